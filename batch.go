@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+var (
+	// batchVarRgx matches a variable definition at runbook
+	batchVarRgx = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	// batchTimeoutRgx matches a command w/ specific timeout
+	batchTimeoutRgx = regexp.MustCompile(`^timeout\s+(\S+)\s+(.+)$`)
+	// batchGrace is the time that a timed out command has to clean up
+	batchGrace = 2 * time.Second
+	// errBatchAbort means a timed out command is still running
+	errBatchAbort = errors.New("the command didn't stop after the timeout, runbook aborted")
+)
+
+// isBatch returns true if the commands should be read from
+// a runbook file (-f) or piped through stdin
+func isBatch() bool {
+	if len(eArgs) > 2 && eArgs[1] == "-f" {
+		return true
+	}
+	if len(eArgs) == 1 {
+		fi, err := os.Stdin.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice == 0
+	}
+	return false
+}
+
+// runBatch runs the runbook commands one by one and returns
+// the exit code, it's not zero if any command failed
+func runBatch() int {
+	var (
+		r      io.Reader = os.Stdin
+		name             = "stdin"
+		vars             = make(map[string]string)
+		failed int
+	)
+
+	if len(eArgs) > 2 && eArgs[1] == "-f" {
+		f, err := os.Open(eArgs[2])
+		if err != nil {
+			println(err.Error())
+			return 2
+		}
+		defer f.Close()
+		r, name = f, eArgs[2]
+
+		for _, kv := range eArgs[3:] {
+			if m := batchVarRgx.FindStringSubmatch(kv); len(m) == 3 {
+				vars[m[1]] = m[2]
+			} else {
+				fmt.Fprintf(os.Stderr, "invalid variable: %s\n", kv)
+				return 2
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = expandVars(line, vars)
+
+		if m := batchVarRgx.FindStringSubmatch(line); len(m) == 3 {
+			vars[m[1]] = m[2]
+			continue
+		}
+
+		if line == "exit" || line == "quit" {
+			break
+		}
+
+		timeout, cmd, err := batchTimeout(line, vars)
+		if err == nil {
+			fmt.Printf("%s> %s\n", c.GetPrompt(), cmd)
+			err = runCmd(cmd, timeout)
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s:%d: %s: %s\n", name, n, line, err)
+		}
+		// the next command can't share the globals w/ the running one
+		if err == errBatchAbort {
+			return 1
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return 2
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runCmd runs a command through the command functions w/ timeout
+func runCmd(line string, timeout time.Duration) error {
+	var (
+		done   = make(chan struct{})
+		ctx    = context.Background()
+		cancel context.CancelFunc
+	)
+
+	subReq := cli.CMDRgx().FindStringSubmatch(line)
+	if len(subReq) == 0 {
+		return errors.New("syntax error")
+	}
+
+	f, ok := cmdFunc[strings.TrimSpace(subReq[1])]
+	if !ok {
+		return errors.New("invalid command")
+	}
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	prompt = c.GetPrompt()
+	args = strings.TrimSpace(subReq[2])
+	cmdCtx = ctx
+	cmdErr = nil
+
+	go func() {
+		f()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return cmdErr
+	case <-ctx.Done():
+		// the command might stop by itself once the context canceled
+		select {
+		case <-done:
+			return fmt.Errorf("timeout after %s", timeout)
+		case <-time.After(batchGrace):
+			return errBatchAbort
+		}
+	}
+}
+
+// batchTimeout returns the command and its timeout, the default
+// timeout can be set by TIMEOUT variable
+func batchTimeout(line string, vars map[string]string) (time.Duration, string, error) {
+	var d = vars["TIMEOUT"]
+
+	if m := batchTimeoutRgx.FindStringSubmatch(line); len(m) == 3 {
+		d, line = m[1], m[2]
+	}
+
+	if d == "" {
+		return 0, line, nil
+	}
+
+	timeout, err := time.ParseDuration(d)
+	if err != nil {
+		return 0, line, fmt.Errorf("invalid timeout %s", d)
+	}
+
+	return timeout, line, nil
+}
+
+// expandVars replaces $name and ${name} w/ the runbook variables
+// or the environment variables
+func expandVars(line string, vars map[string]string) string {
+	return os.Expand(line, func(k string) string {
+		if v, ok := vars[k]; ok {
+			return v
+		}
+		return os.Getenv(k)
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+func TestExpandVars(t *testing.T) {
+	os.Setenv("MYLG_TEST_ENV", "env")
+	defer os.Unsetenv("MYLG_TEST_ENV")
+
+	vars := map[string]string{"host": "8.8.8.8", "MYLG_TEST_ENV": "var"}
+	for line, expected := range map[string]string{
+		"ping $host -c 1":     "ping 8.8.8.8 -c 1",
+		"ping ${host}:53":     "ping 8.8.8.8:53",
+		"echo $MYLG_TEST_ENV": "echo var",
+		"ping $unknown":       "ping ",
+		"ping 8.8.8.8":        "ping 8.8.8.8",
+	} {
+		if r := expandVars(line, vars); r != expected {
+			t.Errorf("%q expected %q but got %q", line, expected, r)
+		}
+	}
+
+	delete(vars, "MYLG_TEST_ENV")
+	if r := expandVars("$MYLG_TEST_ENV", vars); r != "env" {
+		t.Error("expected the environment variable but got", r)
+	}
+}
+
+func TestBatchTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		line    string
+		vars    map[string]string
+		timeout time.Duration
+		cmd     string
+		err     bool
+	}{
+		{"ping 8.8.8.8", nil, 0, "ping 8.8.8.8", false},
+		{"timeout 5s ping 8.8.8.8", nil, 5 * time.Second, "ping 8.8.8.8", false},
+		{"ping 8.8.8.8", map[string]string{"TIMEOUT": "1m"}, time.Minute, "ping 8.8.8.8", false},
+		{"timeout 2s dig mylg.io", map[string]string{"TIMEOUT": "1m"}, 2 * time.Second, "dig mylg.io", false},
+		{"timeout 5x ping 8.8.8.8", nil, 0, "ping 8.8.8.8", true},
+		{"ping 8.8.8.8", map[string]string{"TIMEOUT": "soon"}, 0, "ping 8.8.8.8", true},
+	} {
+		timeout, cmd, err := batchTimeout(tc.line, tc.vars)
+		if timeout != tc.timeout || cmd != tc.cmd || (err != nil) != tc.err {
+			t.Errorf("%q unexpected %s %q %v", tc.line, timeout, cmd, err)
+		}
+	}
+}
+
+func TestBatchVar(t *testing.T) {
+	for line, expected := range map[string][]string{
+		"host=8.8.8.8":       {"host", "8.8.8.8"},
+		"TIMEOUT = 10s":      {"TIMEOUT", "10s"},
+		"_dst=a=b":           {"_dst", "a=b"},
+		"cmd=ping -c 1 host": {"cmd", "ping -c 1 host"},
+		"ping 8.8.8.8":       nil,
+		"1host=8.8.8.8":      nil,
+		"hping http://a?b=c": nil,
+	} {
+		m := batchVarRgx.FindStringSubmatch(line)
+		if expected == nil {
+			if m != nil {
+				t.Errorf("%q unexpected variable %q", line, m)
+			}
+			continue
+		}
+		if len(m) != 3 || m[1] != expected[0] || m[2] != expected[1] {
+			t.Errorf("%q expected %q but got %q", line, expected, m)
+		}
+	}
+}
+
+func TestIsBatch(t *testing.T) {
+	defer func(args []string, stdin *os.File) {
+		eArgs, os.Stdin = args, stdin
+	}(eArgs, os.Stdin)
+
+	for _, tc := range []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"mylg", "-f", "runbook.mylg"}, true},
+		{[]string{"mylg", "-f", "runbook.mylg", "host=8.8.8.8"}, true},
+		{[]string{"mylg", "-f"}, false},
+		{[]string{"mylg", "ping", "8.8.8.8"}, false},
+	} {
+		eArgs = tc.args
+		if isBatch() != tc.expected {
+			t.Errorf("%q expected %t", strings.Join(tc.args, " "), tc.expected)
+		}
+	}
+
+	// piped commands
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	eArgs, os.Stdin = []string{"mylg"}, r
+	if !isBatch() {
+		t.Error("expected batch w/ the piped stdin")
+	}
+}
+
+func TestRunCmd(t *testing.T) {
+	// the commands should be at the cli commands
+	defer func(grace time.Duration, fs map[string]func()) {
+		batchGrace = grace
+		for name, f := range fs {
+			cmdFunc[name] = f
+		}
+	}(batchGrace, map[string]func(){"dig": cmdFunc["dig"], "whois": cmdFunc["whois"], "peering": cmdFunc["peering"]})
+
+	c = &cli.Readline{}
+	batchGrace = 50 * time.Millisecond
+	hang := make(chan struct{})
+	defer close(hang)
+
+	cmdFunc["dig"] = func() { cmdErr = errors.New("failed") }
+	cmdFunc["whois"] = func() { <-cmdCtx.Done() }
+	cmdFunc["peering"] = func() { <-hang }
+
+	for _, tc := range []struct {
+		cmd      string
+		expected string
+	}{
+		{"dig mylg.io", "failed"},
+		{"whois 8.8.8.8", "timeout after 10ms"},
+		{"peering 577", errBatchAbort.Error()},
+		{"unknown", "syntax error"},
+	} {
+		err := runCmd(tc.cmd, 10*time.Millisecond)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("%s expected %q but got %v", tc.cmd, tc.expected, err)
+		}
+	}
+}
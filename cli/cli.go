@@ -24,11 +24,14 @@ const usage = `Usage:
 	lg                          change mode to external looking glass
 	ns                          change mode to name server looking up
 	ping                        ping ip address or domain name
+	ping <host> -all            ping through all the looking glasses nodes concurrently (-w workers -t timeout -p provider)
+	bgp                         BGP lookup at current looking glass or all of them w/ -compare
 	trace                       trace ip address or domain name (real-time w/ -r option)
 	dig                         nameserver look up
 	nms                         quick NMS - monitor device/server ports real-time
 	whois                       resolve AS number/IP/CIDR to holder (provided by ripe ncc)
 	hping                       ping through HTTP/HTTPS w/ GET/POST/HEAD methods
+	tping                       ping a TCP port (connect or SYN) or a UDP port (-u)
 	scan                        scan tcp ports (you can provide range >scan host minport maxport)
 	dump                        prints out a description of the contents of packets on a network interface
 	disc                        discover all the devices on a LAN
@@ -52,6 +55,7 @@ var (
 		"trace",
 		"bgp",
 		"hping",
+		"tping",
 		"connect",
 		"node",
 		"local",
@@ -99,6 +103,14 @@ func Init(version string) *Readline {
 	return &r
 }
 
+// InitBatch set readline items w/o terminal for the batch mode,
+// it keeps the prompt and completer states only
+func InitBatch() *Readline {
+	return &Readline{
+		completer: readline.NewPrefixCompleter(pcItems()...),
+	}
+}
+
 // RemoveItemCompleter removes subitem(s) from a specific main item
 func (r *Readline) RemoveItemCompleter(pcItem string) {
 	child := []readline.PrefixCompleterInterface{}
@@ -151,7 +163,9 @@ func (r *Readline) UpdateCompleter(pcItem string, pcSubItems []string) {
 func (r *Readline) SetPrompt(p string) {
 	p = strings.ToLower(p)
 	r.prompt = p
-	r.instance.SetPrompt(p + "> ")
+	if r.instance != nil {
+		r.instance.SetPrompt(p + "> ")
+	}
 }
 
 // UpdatePromptN appends readline prompt
@@ -165,7 +179,9 @@ func (r *Readline) UpdatePromptN(p string, n int) {
 	} else {
 		r.prompt += "/" + p
 	}
-	r.instance.SetPrompt(r.prompt + "> ")
+	if r.instance != nil {
+		r.instance.SetPrompt(r.prompt + "> ")
+	}
 }
 
 // GetPrompt returns the current prompt string
@@ -175,11 +191,16 @@ func (r *Readline) GetPrompt() string {
 
 // Refresh prompt
 func (r *Readline) Refresh() {
-	r.instance.Refresh()
+	if r.instance != nil {
+		r.instance.Refresh()
+	}
 }
 
 // SetVim set mode to vim
 func (r *Readline) SetVim() {
+	if r.instance == nil {
+		return
+	}
 	if !r.instance.IsVimMode() {
 		r.instance.SetVimMode(true)
 		println("mode changed to vim")
@@ -190,6 +211,9 @@ func (r *Readline) SetVim() {
 
 // SetEmacs set mode to emacs
 func (r *Readline) SetEmacs() {
+	if r.instance == nil {
+		return
+	}
 	if r.instance.IsVimMode() {
 		r.instance.SetVimMode(false)
 		println("mode changed to emacs")
@@ -200,7 +224,9 @@ func (r *Readline) SetEmacs() {
 
 // Next trigers to read next line
 func (r *Readline) Next() {
-	r.next <- struct{}{}
+	if r.next != nil {
+		r.next <- struct{}{}
+	}
 }
 
 // Run the main loop
@@ -230,7 +256,9 @@ LOOP:
 
 // Close the readline instance
 func (r *Readline) Close(next chan struct{}) {
-	r.instance.Close()
+	if r.instance != nil {
+		r.instance.Close()
+	}
 }
 
 // Help print out the main help
@@ -277,6 +305,12 @@ func pcItems() []readline.PrefixCompleterInterface {
 					readline.PcItem("wait"),
 					readline.PcItem("theme"),
 				),
+				readline.PcItem("whois",
+					readline.PcItem("source"),
+				),
+				readline.PcItem("geo",
+					readline.PcItem("source"),
+				),
 			},
 		}
 	)
@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"reflect"
@@ -34,6 +35,12 @@ var defaultConfig = `{
 		"wait"  : "2s",
 		"theme" : "dark"
 	},
+	"whois" : {
+		"source" : "ripe"
+	},
+	"geo" : {
+		"source" : "ripe"
+	},
 	"snmp" : {
 		"community"     : "public",
 		"timeout"       : "1s",
@@ -55,7 +62,13 @@ type Config struct {
 	Web   Web   `json:"web"`
 	Scan  Scan  `json:"scan"`
 	Trace Trace `json:"trace"`
+	Whois Whois `json:"whois"`
+	Geo   Geo   `json:"geo"`
 	Snmp  SNMP  `json:"snmp"`
+
+	// looking glass definitions, they're added to
+	// or replace the built-in looking glasses
+	LG []LGProvider `json:"lg,omitempty"`
 }
 
 // Ping represents ping command options
@@ -91,6 +104,18 @@ type Trace struct {
 	Theme string `json:"theme" tag:"lower"`
 }
 
+// Whois represents the IP to ASN / prefix source, it's ripe
+// (RIPEstat) or an ip2asn, pfx2as or MRT RIB dump file
+type Whois struct {
+	Source string `json:"source"`
+}
+
+// Geo represents the IP geolocation source, it's ripe (RIPEstat)
+// or a MaxMind DB (mmdb) file w/ RIPEstat as fallback
+type Geo struct {
+	Source string `json:"source"`
+}
+
 // SNMP represents nms command options
 type SNMP struct {
 	Community     string `json:"community"`
@@ -105,6 +130,52 @@ type SNMP struct {
 	Privacyproto  string `json:"privacyproto" tag:"lower"`
 }
 
+// LGProvider represents a looking glass definition, the URLs and
+// form values are templates w/ ${host}, ${addr}, ${len}, ${ipv},
+// ${af}, ${node} and ${name} variables. The type is http (default)
+// or a route server daemon: bird, frr and gobgp. The format is the
+// BGP output format: junos, ios or bird (detected if it's empty)
+type LGProvider struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type,omitempty"`
+	Address string    `json:"address,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	Timeout string    `json:"timeout,omitempty"`
+	Default string    `json:"default"`
+	Notice  string    `json:"notice,omitempty"`
+	Nodes   LGNodes   `json:"nodes"`
+	Ping    LGCommand `json:"ping"`
+	Trace   LGCommand `json:"trace"`
+	BGP     LGCommand `json:"bgp"`
+}
+
+// LGNodes represents the source of a looking glass nodes
+type LGNodes struct {
+	URL    string            `json:"url,omitempty"`
+	Match  string            `json:"match,omitempty"`
+	Name   int               `json:"name,omitempty"`
+	Value  int               `json:"value,omitempty"`
+	After  string            `json:"after,omitempty"`
+	Before string            `json:"before,omitempty"`
+	Static map[string]string `json:"static,omitempty"`
+}
+
+// LGCommand represents a looking glass command request and
+// how to extract the result from the response
+type LGCommand struct {
+	URL      string            `json:"url,omitempty"`
+	Method   string            `json:"method,omitempty"`
+	Form     map[string]string `json:"form,omitempty"`
+	Exec     string            `json:"exec,omitempty"`
+	Match    string            `json:"match,omitempty"`
+	Start    string            `json:"start,omitempty"`
+	Sanitize bool              `json:"sanitize,omitempty"`
+	Dedup    bool              `json:"dedup,omitempty"`
+	ASN      bool              `json:"asn,omitempty"`
+	Nodes    *LGNodes          `json:"nodes,omitempty"`
+}
+
 // WriteConfig write config to disk
 func WriteConfig(cfg Config) error {
 	f, err := cfgFile()
@@ -143,6 +214,9 @@ func GetOptions(s interface{}, key string) ([]string, []interface{}) {
 		if t.Field(i).Name == key {
 			f := v.Field(i)
 			ft := f.Type()
+			if f.Kind() != reflect.Struct {
+				break
+			}
 			for j := 0; j < f.NumField(); j++ {
 				vals = append(vals, f.Field(j))
 				opts = append(opts, ft.Field(j).Name)
@@ -173,18 +247,15 @@ func UpgradeConfig(cfg *Config) error {
 		cConf Config
 	)
 
-	b := make([]byte, 2048)
 	f, err := cfgFile()
 	if err != nil {
 		return err
 	}
 
-	h, err := os.Open(f)
+	b, err := ioutil.ReadFile(f)
 	if err != nil {
 		return err
 	}
-	n, _ := h.Read(b)
-	b = b[:n]
 	// load saved/old config to conf
 	json.Unmarshal(b, &conf)
 	// load default config to cConf
@@ -235,7 +306,7 @@ func InitConfig(f string) ([]byte, error) {
 // or default configuration
 func ReadConfig() Config {
 	var (
-		b    = make([]byte, 2048)
+		b    []byte
 		conf Config
 		err  error
 	)
@@ -260,8 +331,8 @@ func ReadConfig() Config {
 			b = []byte(defaultConfig)
 		}
 	} else {
-		n, _ := h.Read(b)
-		b = b[:n]
+		b, _ = ioutil.ReadAll(h)
+		h.Close()
 	}
 
 	err = json.Unmarshal(b, &conf)
@@ -420,6 +491,9 @@ func ShowConfig(s *Config) {
 
 		vv := v.Field(i).Addr()
 		vv = reflect.Indirect(vv)
+		if vv.Kind() != reflect.Struct {
+			continue
+		}
 
 		for j := 0; j < vv.NumField(); j++ {
 			subCmd := vv.Type().Field(j).Name
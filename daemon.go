@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mehrdadrad/mylg/services/httpd"
+	"github.com/mehrdadrad/mylg/services/monitor"
+)
+
+// isDaemon returns true if the scheduled probes should run
+// in daemon mode (mylg daemon -c probes.yaml)
+func isDaemon() bool {
+	return len(eArgs) > 1 && eArgs[1] == "daemon"
+}
+
+// runDaemon runs the probes and exposes their statistics through
+// the web server until it's terminated, it returns the exit code
+func runDaemon() int {
+	var (
+		sigCh = make(chan os.Signal, 1)
+		errCh = make(chan error, 1)
+		done  = make(chan struct{})
+	)
+
+	if len(eArgs) != 4 || eArgs[2] != "-c" {
+		println("usage: mylg daemon -c probes.yaml")
+		return 2
+	}
+
+	mc, err := monitor.ReadConfig(eArgs[3])
+	if err != nil {
+		println(err.Error())
+		return 2
+	}
+	m, err := monitor.New(mc, cfg)
+	if err != nil {
+		println(err.Error())
+		return 2
+	}
+	m.Logger = log.New(os.Stderr, "", log.LstdFlags)
+
+	if mc.Web.Address != "" {
+		cfg.Web.Address = mc.Web.Address
+	}
+	if mc.Web.Port != 0 {
+		cfg.Web.Port = mc.Web.Port
+	}
+
+	httpd.SetMonitor(m)
+	addr := fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port)
+	go func() {
+		errCh <- http.ListenAndServe(addr, httpd.Handler(cfg))
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	m.Logger.Printf("%d probes running, statistics at http://%s/api/probes", len(mc.Probes), addr)
+
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-sigCh:
+		cancel()
+		<-done
+		return 0
+	case err := <-errCh:
+		println(err.Error())
+		cancel()
+		<-done
+		return 1
+	}
+}
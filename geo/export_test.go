@@ -0,0 +1,33 @@
+package geo
+
+import (
+	"net"
+	"time"
+)
+
+// BackendFunc is a function backend for testing
+type BackendFunc func(net.IP) (Location, error)
+
+// Lookup calls the function
+func (f BackendFunc) Lookup(ip net.IP) (Location, error) { return f(ip) }
+
+// SetFallback replaces the background fallback and returns the restore
+func SetFallback(b Backend) func() {
+	old := fallback
+	fallback = b
+	return func() { fallback = old }
+}
+
+// SetMyIPAddr replaces the public IP address lookup and returns the restore
+func SetMyIPAddr(f func() (string, error)) func() {
+	old := myIPAddr
+	myIPAddr = f
+	return func() { myIPAddr = old }
+}
+
+// SetMissTTL replaces the failed lookup expiry and returns the restore
+func SetMissTTL(d time.Duration) func() {
+	old := missTTL
+	missTTL = d
+	return func() { missTTL = old }
+}
@@ -0,0 +1,247 @@
+// Package geo provides the IP addresses geolocation through
+// a MaxMind DB (mmdb) database or RIPEstat
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// Location represents an IP address's geolocation
+type Location struct {
+	City      string  `json:"City"`
+	Country   string  `json:"Country"`
+	Latitude  float64 `json:"Latitude"`
+	Longitude float64 `json:"Longitude"`
+}
+
+// Backend represents an IP address to geolocation source
+type Backend interface {
+	Lookup(ip net.IP) (Location, error)
+}
+
+// RIPE is the RIPEstat geoloc backend
+type RIPE struct{}
+
+// miss represents a failed lookup which is retried once it's expired
+type miss struct {
+	err     error
+	expires time.Time
+}
+
+var (
+	source  = "ripe"
+	backend Backend
+	loadErr error
+	cache   = make(map[string]Location)
+	misses  = make(map[string]miss)
+	pending = make(map[string]bool)
+	myIP    string
+	myIPErr error
+	mu      sync.Mutex
+
+	// missTTL is how long a failed lookup is cached
+	missTTL = time.Minute
+	// fallback looks up the offline database's misses in background
+	fallback Backend = RIPE{}
+	// myIPAddr returns the station's public IP address
+	myIPAddr = ripe.MyIPAddr
+
+	privateNets = parseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+		"100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "fc00::/7", "fe80::/10", "::1/128")
+)
+
+// Lookup returns the first location that has a city from RIPEstat
+func (RIPE) Lookup(ip net.IP) (Location, error) {
+	p := new(ripe.Prefix)
+	p.Set(ip.String())
+	if err := p.GetGeoData(); err != nil {
+		return Location{}, err
+	}
+	for _, g := range p.GeoData.Data.Locations {
+		if g.City != "" {
+			return Location{City: g.City, Country: g.Country, Latitude: g.Latitude, Longitude: g.Longitude}, nil
+		}
+	}
+	return Location{}, fmt.Errorf("%s location not available", ip)
+}
+
+// Init sets the geo source, it's ripe (RIPEstat) or a MaxMind DB
+// file w/ RIPEstat as background fallback, the file loads once
+// it's needed
+func Init(cfg cli.Config) {
+	mu.Lock()
+	source = cfg.Geo.Source
+	backend, loadErr = nil, nil
+	reset()
+	mu.Unlock()
+}
+
+// SetBackend replaces the backend
+func SetBackend(b Backend) {
+	mu.Lock()
+	backend, loadErr = b, nil
+	reset()
+	mu.Unlock()
+}
+
+// reset clears the cached locations and lookups
+func reset() {
+	cache = make(map[string]Location)
+	misses = make(map[string]miss)
+	pending = make(map[string]bool)
+	myIP, myIPErr = "", nil
+}
+
+// Offline returns true if the source is a local database
+func Offline() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return offline()
+}
+
+func offline() bool {
+	return source != "" && source != "ripe"
+}
+
+// IPLocation returns the IP address's location, the private
+// addresses don't have location
+func IPLocation(ip string) (Location, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Location{}, fmt.Errorf("invalid IP address: %s", ip)
+	}
+	for _, n := range privateNets {
+		if n.Contains(addr) {
+			return Location{}, fmt.Errorf("%s is a private address", ip)
+		}
+	}
+
+	key := addr.String()
+
+	mu.Lock()
+	loc, ok := cache[key]
+	m, missed := misses[key]
+	mu.Unlock()
+	if ok {
+		return loc, nil
+	}
+	if missed && time.Now().Before(m.expires) {
+		return Location{}, m.err
+	}
+
+	b, err := current()
+	if err != nil {
+		return Location{}, err
+	}
+	loc, err = b.Lookup(addr)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err != nil {
+		misses[key] = miss{err, time.Now().Add(missTTL)}
+		// the offline database's miss doesn't wait for RIPEstat
+		if offline() && !pending[key] {
+			pending[key] = true
+			go lookupFallback(addr, cache, misses, pending)
+		}
+		return loc, err
+	}
+	cache[key] = loc
+
+	return loc, nil
+}
+
+// lookupFallback looks up the IP address through the fallback
+// and caches the location for the next lookups, the failed one
+// is retried after the miss expiry
+func lookupFallback(addr net.IP, cache map[string]Location, misses map[string]miss, pending map[string]bool) {
+	loc, err := fallback.Lookup(addr)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(pending, addr.String())
+	if err != nil {
+		return
+	}
+	cache[addr.String()] = loc
+	delete(misses, addr.String())
+}
+
+// MyLocation returns the station's public IP address and location,
+// the failed lookup isn't retried and w/ the offline source the
+// public IP address is looked up in background
+func MyLocation() (string, Location, error) {
+	mu.Lock()
+	ip, err := myIP, myIPErr
+	if ip == "" && err == nil {
+		if offline() {
+			myIPErr = errors.New("public IP address is not available yet")
+			go lookupMyIP()
+		} else {
+			myIP, myIPErr = myIPAddr()
+		}
+		ip, err = myIP, myIPErr
+	}
+	mu.Unlock()
+
+	if err != nil {
+		return "", Location{}, err
+	}
+
+	loc, err := IPLocation(ip)
+	return ip, loc, err
+}
+
+// lookupMyIP looks up the public IP address in background
+func lookupMyIP() {
+	ip, err := myIPAddr()
+	mu.Lock()
+	if err == nil {
+		myIP, myIPErr = ip, nil
+	} else {
+		myIPErr = err
+	}
+	mu.Unlock()
+}
+
+// current returns the backend, it loads the database at first
+func current() (Backend, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if backend != nil || loadErr != nil {
+		return backend, loadErr
+	}
+
+	if source == "" || source == "ripe" {
+		backend = RIPE{}
+		return backend, nil
+	}
+
+	db, err := OpenMMDB(source)
+	if err != nil {
+		loadErr = err
+		return nil, err
+	}
+	backend = db
+
+	return backend, nil
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		_, n, _ := net.ParseCIDR(c)
+		nets = append(nets, n)
+	}
+	return nets
+}
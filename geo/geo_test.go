@@ -0,0 +1,109 @@
+package geo_test
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/geo"
+)
+
+func TestOfflineMiss(t *testing.T) {
+	var lookups, fallbacks, myIPs int32
+
+	found := make(chan struct{})
+	defer geo.SetFallback(geo.BackendFunc(func(ip net.IP) (geo.Location, error) {
+		atomic.AddInt32(&fallbacks, 1)
+		<-found
+		return geo.Location{City: "Paris", Country: "FR"}, nil
+	}))()
+	defer geo.SetMyIPAddr(func() (string, error) {
+		atomic.AddInt32(&myIPs, 1)
+		return "", errors.New("no route")
+	})()
+	defer geo.Init(cli.Config{})
+
+	geo.Init(cli.Config{Geo: cli.Geo{Source: "geo.mmdb"}})
+	geo.SetBackend(geo.BackendFunc(func(ip net.IP) (geo.Location, error) {
+		atomic.AddInt32(&lookups, 1)
+		return geo.Location{}, errors.New("not found")
+	}))
+
+	// the miss doesn't wait for the fallback and it's cached
+	for n := 0; n < 3; n++ {
+		if _, err := geo.IPLocation("192.0.2.1"); err == nil {
+			t.Fatal("expected not found error")
+		}
+	}
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Error("expected one lookup but got", n)
+	}
+
+	close(found)
+	for n := 0; ; n++ {
+		if loc, err := geo.IPLocation("192.0.2.1"); err == nil {
+			if loc.City != "Paris" {
+				t.Error("unexpected location", loc)
+			}
+			break
+		}
+		if n > 100 {
+			t.Fatal("expected the fallback location")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&fallbacks); n != 1 {
+		t.Error("expected one fallback lookup but got", n)
+	}
+
+	// the public IP address lookup doesn't block and the failure is cached
+	if _, _, err := geo.MyLocation(); err == nil {
+		t.Error("expected not available error")
+	}
+	for n := 0; atomic.LoadInt32(&myIPs) == 0; n++ {
+		if n > 100 {
+			t.Fatal("expected the public IP address lookup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for n := 0; n < 3; n++ {
+		if _, _, err := geo.MyLocation(); err == nil {
+			t.Error("expected error")
+		}
+	}
+	if n := atomic.LoadInt32(&myIPs); n != 1 {
+		t.Error("expected one public IP address lookup but got", n)
+	}
+}
+
+func TestMissExpiry(t *testing.T) {
+	var lookups int32
+
+	defer geo.SetMissTTL(50 * time.Millisecond)()
+	defer geo.Init(cli.Config{})
+
+	geo.SetBackend(geo.BackendFunc(func(ip net.IP) (geo.Location, error) {
+		if atomic.AddInt32(&lookups, 1) == 1 {
+			return geo.Location{}, errors.New("timeout")
+		}
+		return geo.Location{City: "Paris", Country: "FR"}, nil
+	}))
+
+	// the failure is cached until it's expired
+	for n := 0; n < 3; n++ {
+		if _, err := geo.IPLocation("192.0.2.1"); err == nil {
+			t.Fatal("expected timeout error")
+		}
+	}
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Error("expected one lookup but got", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if loc, err := geo.IPLocation("192.0.2.1"); err != nil || loc.City != "Paris" {
+		t.Error("expected the retried location", loc, err)
+	}
+}
@@ -0,0 +1,334 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+)
+
+// mmdbMarker is the metadata section start marker
+var mmdbMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// mmdb data section types
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEnd
+	mmdbBool
+	mmdbFloat
+)
+
+// MMDB is the MaxMind DB format (GeoIP2 / GeoLite2 City
+// and Country) geolocation database
+type MMDB struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+}
+
+// OpenMMDB reads the MaxMind DB file
+func OpenMMDB(file string) (*MMDB, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewMMDB(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return db, nil
+}
+
+// NewMMDB creates the database from the MaxMind DB format bytes
+func NewMMDB(b []byte) (*MMDB, error) {
+	idx := bytes.LastIndex(b, mmdbMarker)
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid MaxMind DB, metadata not found")
+	}
+
+	meta, _, err := decode(b[idx+len(mmdbMarker):], 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid MaxMind DB metadata")
+	}
+
+	db := &MMDB{
+		buf:        b,
+		nodeCount:  toUint(m["node_count"]),
+		recordSize: toUint(m["record_size"]),
+		ipVersion:  toUint(m["ip_version"]),
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+16 > uint(idx) {
+		return nil, fmt.Errorf("invalid MaxMind DB search tree")
+	}
+	db.data = b[treeSize+16 : idx]
+
+	// the IPv4 addresses are at ::/96 of the IPv6 tree
+	if db.ipVersion == 6 {
+		for i := 0; i < 96 && db.ipv4Start < db.nodeCount; i++ {
+			db.ipv4Start = db.record(db.ipv4Start, 0)
+		}
+	}
+
+	return db, nil
+}
+
+// Lookup returns the IP address's city, country and coordinates
+func (db *MMDB) Lookup(ip net.IP) (Location, error) {
+	var loc Location
+
+	r, err := db.Record(ip)
+	if err != nil {
+		return loc, err
+	}
+
+	loc.City, _ = path(r, "city", "names", "en").(string)
+	loc.Country, _ = path(r, "country", "iso_code").(string)
+	if loc.Country == "" {
+		loc.Country, _ = path(r, "registered_country", "iso_code").(string)
+	}
+	loc.Latitude, _ = path(r, "location", "latitude").(float64)
+	loc.Longitude, _ = path(r, "location", "longitude").(float64)
+
+	return loc, nil
+}
+
+// Record returns the IP address's decoded data record
+func (db *MMDB) Record(ip net.IP) (map[string]interface{}, error) {
+	var (
+		key  = ip.To16()
+		node uint
+		bits = 128
+	)
+
+	if ip4 := ip.To4(); ip4 != nil {
+		key, node, bits = ip4, db.ipv4Start, 32
+	} else if db.ipVersion == 4 {
+		return nil, fmt.Errorf("%s not found, IPv4 only database", ip)
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := uint(key[i>>3]>>(7-uint(i&7))) & 1
+		node = db.record(node, bit)
+	}
+
+	if node <= db.nodeCount {
+		return nil, fmt.Errorf("%s not found", ip)
+	}
+
+	offset := node - db.nodeCount - 16
+	if offset >= uint(len(db.data)) {
+		return nil, fmt.Errorf("invalid MaxMind DB record")
+	}
+	v, _, err := decode(db.data, offset)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid MaxMind DB record")
+	}
+
+	return r, nil
+}
+
+// record returns the node's left (0) or right (1) record
+func (db *MMDB) record(node, bit uint) uint {
+	var (
+		size = db.recordSize / 4
+		b    = db.buf[node*size : node*size+size]
+	)
+
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decode decodes the data section's field at the offset and
+// returns the value and the next field's offset
+func decode(d []byte, offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(d)) {
+		return nil, 0, fmt.Errorf("invalid MaxMind DB data offset")
+	}
+
+	ctrl := d[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == mmdbPointer {
+		ptr, next, err := pointer(d, ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := decode(d, ptr)
+		return v, next, err
+	}
+
+	if typ == mmdbExtended {
+		if offset >= uint(len(d)) {
+			return nil, 0, fmt.Errorf("invalid MaxMind DB data")
+		}
+		typ = 7 + uint(d[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d)) {
+			return nil, 0, fmt.Errorf("invalid MaxMind DB data")
+		}
+		v := uint(0)
+		for _, c := range d[offset : offset+n] {
+			v = v<<8 | uint(c)
+		}
+		offset += n
+		size = []uint{29, 285, 65821}[n-1] + v
+	}
+
+	switch typ {
+	case mmdbMap:
+		m := make(map[string]interface{}, size)
+		for n := uint(0); n < size; n++ {
+			k, next, err := decode(d, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, next, err := decode(d, next)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, _ := k.(string)
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]interface{}, 0, size)
+		for n := uint(0); n < size; n++ {
+			v, next, err := decode(d, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbContainer, mmdbEnd:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(d)) {
+		return nil, 0, fmt.Errorf("invalid MaxMind DB data")
+	}
+	b := d[offset : offset+size]
+	offset += size
+
+	switch typ {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid MaxMind DB double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid MaxMind DB float")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbInt32:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		if typ == mmdbInt32 {
+			return int64(int32(v)), offset, nil
+		}
+		return v, offset, nil
+	default:
+		// bytes and uint128
+		return b, offset, nil
+	}
+}
+
+// pointer returns the pointer's data section offset
+func pointer(d []byte, ctrl byte, offset uint) (uint, uint, error) {
+	var (
+		ss   = uint(ctrl>>3) & 0x03
+		vvv  = uint(ctrl & 0x07)
+		n    = ss + 1
+		base = []uint{0, 2048, 526336, 0}[ss]
+		p    uint
+	)
+
+	if offset+n > uint(len(d)) {
+		return 0, 0, fmt.Errorf("invalid MaxMind DB pointer")
+	}
+	if ss != 3 {
+		p = vvv
+	}
+	for _, c := range d[offset : offset+n] {
+		p = p<<8 | uint(c)
+	}
+
+	return p + base, offset + n, nil
+}
+
+// path returns the nested maps' value
+func path(v interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func toUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case int64:
+		return uint(n)
+	}
+	return 0
+}
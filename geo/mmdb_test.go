@@ -0,0 +1,146 @@
+package geo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/geo"
+)
+
+// mmdb encodes the data section fields
+type mmdb struct {
+	bytes.Buffer
+}
+
+func (m *mmdb) ctrl(typ, size int) {
+	if typ > 7 {
+		m.WriteByte(byte(size))
+		m.WriteByte(byte(typ - 7))
+		return
+	}
+	m.WriteByte(byte(typ<<5 | size))
+}
+
+func (m *mmdb) str(s string) {
+	m.ctrl(2, len(s))
+	m.WriteString(s)
+}
+
+func (m *mmdb) double(f float64) {
+	m.ctrl(3, 8)
+	binary.Write(m, binary.BigEndian, math.Float64bits(f))
+}
+
+func (m *mmdb) uint(typ int, v uint32) {
+	m.ctrl(typ, 4)
+	binary.Write(m, binary.BigEndian, v)
+}
+
+func (m *mmdb) mapHdr(size int) {
+	m.ctrl(7, size)
+}
+
+// newMMDB returns an IPv4 database w/ 24 bits records that
+// has the prefix's record only
+func newMMDB(prefix *net.IPNet) []byte {
+	var (
+		buf   bytes.Buffer
+		data  mmdb
+		meta  mmdb
+		bits  = 24
+		nodes = uint32(bits)
+	)
+
+	// {"city":{"names":{"en":"Los Angeles"}},"country":{"iso_code":"US"},
+	// "location":{"latitude":34.05,"longitude":-118.24}}
+	data.mapHdr(3)
+	data.str("city")
+	data.mapHdr(1)
+	data.str("names")
+	data.mapHdr(1)
+	data.str("en")
+	data.str("Los Angeles")
+	data.str("country")
+	data.mapHdr(1)
+	data.str("iso_code")
+	// a pointer to the "US" after the record
+	ptr := data.Len()
+	data.Write([]byte{1 << 5, 0})
+	data.str("location")
+	data.mapHdr(2)
+	data.str("latitude")
+	data.double(34.05)
+	data.str("longitude")
+	data.double(-118.24)
+	us := data.Len()
+	data.str("US")
+	data.Bytes()[ptr] |= byte(us >> 8)
+	data.Bytes()[ptr+1] = byte(us)
+
+	for n := 0; n < bits; n++ {
+		var (
+			bit   = prefix.IP.To4()[n/8] >> (7 - uint(n%8)) & 1
+			rec   [2]uint32
+			child = uint32(n + 1)
+		)
+		if n == bits-1 {
+			// data section offset 0
+			child = nodes + 16
+		}
+		rec[bit], rec[1-bit] = child, nodes
+		for _, r := range rec {
+			buf.Write([]byte{byte(r >> 16), byte(r >> 8), byte(r)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data.Bytes())
+
+	meta.mapHdr(3)
+	meta.str("node_count")
+	meta.uint(6, nodes)
+	meta.str("record_size")
+	meta.uint(5, 24)
+	meta.str("ip_version")
+	meta.uint(5, 4)
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	buf.Write(meta.Bytes())
+
+	return buf.Bytes()
+}
+
+func TestMMDB(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("192.0.2.0/24")
+	db, err := geo.NewMMDB(newMMDB(prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := db.Lookup(net.ParseIP("192.0.2.10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.City != "Los Angeles" || loc.Country != "US" || loc.Latitude != 34.05 || loc.Longitude != -118.24 {
+		t.Error("unexpected location", loc)
+	}
+
+	if _, err := db.Lookup(net.ParseIP("192.0.3.10")); err == nil {
+		t.Error("expected not found error")
+	}
+	if _, err := db.Lookup(net.ParseIP("2001:db8::1")); err == nil {
+		t.Error("expected IPv4 only database error")
+	}
+	if _, err := geo.NewMMDB([]byte("mylg")); err == nil {
+		t.Error("expected invalid database error")
+	}
+
+	geo.SetBackend(db)
+	if loc, err := geo.IPLocation("192.0.2.1"); err != nil || loc.City != "Los Angeles" {
+		t.Error("unexpected location", loc, err)
+	}
+	if _, err := geo.IPLocation("10.1.1.1"); err == nil {
+		t.Error("expected private address error")
+	}
+}
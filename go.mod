@@ -18,5 +18,7 @@ require (
 	github.com/nsf/termbox-go v0.0.0-20200204031403-4d2b513ad8be // indirect
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/rakyll/statik v0.1.6
+	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
 	golang.org/x/net v0.0.0-20200219183655-46282727080f
+	gopkg.in/yaml.v2 v2.4.0
 )
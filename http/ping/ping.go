@@ -14,12 +14,12 @@ import (
 	"net/http/httptrace"
 	"net/url"
 	"os"
-	"os/signal"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/stats"
 )
 
 var stdout *os.File
@@ -192,20 +192,17 @@ func Normalize(URL string) string {
 	return URL
 }
 
-// Run tries to ping w/ pretty print
-func (p *Ping) Run() {
+// Run tries to ping w/ pretty print until the count
+// reached or the context canceled
+func (p *Ping) Run(ctx context.Context) {
 	if p.method != "GET" && p.method != "POST" && p.method != "HEAD" {
 		fmt.Printf("Error: Method '%s' not recognized.\n", p.method)
 		return
 	}
 	var (
-		sigCh = make(chan os.Signal, 1)
-		c     = make(map[int]float64, 10)
-		s     []float64
+		c = make(map[int]float64, 10)
+		s stats.Stats
 	)
-	// capture interrupt w/ s channel
-	signal.Notify(sigCh, os.Interrupt)
-	defer signal.Stop(sigCh)
 
 	fmt.Printf("HPING %s (%s), Method: %s, DNSLookup: %.4f ms\n", p.host, p.rAddr, p.method, p.nsTime.Seconds()*1e3)
 
@@ -214,39 +211,36 @@ LOOP:
 		if r, err := p.Ping(); err == nil {
 			r.PrintPingResult(p, i, err)
 			c[r.StatusCode]++
-			s = append(s, r.TotalTime*1e3)
+			s.Add(r.TotalTime * 1e3)
 		} else {
 			c[-1]++
+			s.AddLoss()
 			r.PrintPingResult(p, i, err)
 		}
 		select {
-		case <-sigCh:
+		case <-ctx.Done():
 			break LOOP
-		default:
+		case <-time.After(p.interval):
 		}
-		time.Sleep(p.interval)
 	}
 
 	// print statistics
 	if p.fmtJSON {
 		unMuteStdout()
-		p.printStatsJSON(c, s)
+		p.printStatsJSON(c, s.Summary())
 	} else {
-		p.printStats(c, s)
+		p.printStats(c, s.Summary())
 	}
 }
 
 // printStats prints out the footer
-func (p *Ping) printStats(c map[int]float64, s []float64) {
-
-	r := calcStats(c, s)
-
-	totalReq := r["sum"] + c[-1]
-	failPct := 100 - (100*r["sum"])/totalReq
+func (p *Ping) printStats(c map[int]float64, r stats.Summary) {
+	totalReq := float64(r.Sent)
 
 	fmt.Printf("\n--- %s HTTP ping statistics --- \n", p.host)
-	fmt.Printf("%.0f requests transmitted, %.0f replies received, %.0f%% requests failed\n", totalReq, r["sum"], failPct)
-	fmt.Printf("HTTP Round-trip min/avg/max = %.2f/%.2f/%.2f ms\n", r["min"], r["avg"], r["max"])
+	fmt.Printf("%d requests transmitted, %d replies received, %.0f%% requests failed\n", r.Sent, r.Recv, r.Loss)
+	fmt.Printf("HTTP Round-trip min/avg/max/stddev = %.2f/%.2f/%.2f/%.2f ms\n", r.Min, r.Mean, r.Max, r.StdDev)
+	fmt.Printf("HTTP Round-trip jitter %.2f ms, p50/p95/p99 = %.2f/%.2f/%.2f ms\n", r.Jitter, r.P50, r.P95, r.P99)
 	for k, v := range c {
 		if k < 0 {
 			continue
@@ -257,13 +251,10 @@ func (p *Ping) printStats(c map[int]float64, s []float64) {
 }
 
 // printStats prints out in json format
-func (p *Ping) printStatsJSON(c map[int]float64, s []float64) {
+func (p *Ping) printStatsJSON(c map[int]float64, r stats.Summary) {
 	var statusCode = make(map[int]float64, 10)
 
-	r := calcStats(c, s)
-
-	totalReq := r["sum"] + c[-1]
-	failPct := 100 - (100*r["sum"])/totalReq
+	totalReq := float64(r.Sent)
 
 	for k, v := range c {
 		if k < 0 {
@@ -277,9 +268,7 @@ func (p *Ping) printStatsJSON(c map[int]float64, s []float64) {
 		DNSLookup float64 `json:"dnslookup"`
 		Count     int     `json:"count"`
 
-		Min float64 `json:"min"`
-		Avg float64 `json:"avg"`
-		Max float64 `json:"max"`
+		stats.Summary
 
 		Failure     float64         `json:"failure"`
 		StatusCodes map[int]float64 `json:"statuscodes"`
@@ -288,11 +277,9 @@ func (p *Ping) printStatsJSON(c map[int]float64, s []float64) {
 		p.nsTime.Seconds() * 1e3,
 		p.count,
 
-		r["min"],
-		r["avg"],
-		r["max"],
+		r,
 
-		failPct,
+		r.Loss,
 		statusCode,
 	}
 
@@ -402,36 +389,6 @@ func tracer(r *Result) *httptrace.ClientTrace {
 	}
 }
 
-func calcStats(c map[int]float64, s []float64) map[string]float64 {
-	var r = make(map[string]float64, 5)
-
-	// total replied requests
-	for k, v := range c {
-		if k < 0 {
-			continue
-		}
-		r["sum"] += v
-	}
-
-	for _, v := range s {
-		// maximum
-		if r["max"] < v {
-			r["max"] = v
-		}
-		// minimum
-		if r["min"] > v || r["min"] == 0 {
-			r["min"] = v
-		}
-		// average
-		if r["avg"] == 0 {
-			r["avg"] = v
-		} else {
-			r["avg"] = (r["avg"] + v) / 2
-		}
-	}
-	return r
-}
-
 func muteStdout() {
 	stdout = os.Stdout
 	_, w, _ := os.Pipe()
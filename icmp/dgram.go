@@ -0,0 +1,85 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"net"
+	"os"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// listenPacket listens to the ICMP socket
+var listenPacket = icmp.ListenPacket
+
+// listenICMP listens to the raw ICMP socket (ip4:icmp or ip6:ipv6-icmp),
+// it falls back to the unprivileged datagram ICMP socket (udp4 or udp6)
+// once the raw socket isn't permitted and the OS supports it, on Linux
+// the group should be at the net.ipv4.ping_group_range. the datagram
+// socket doesn't deliver the ICMP errors w/o IP_RECVERR and the error
+// queue, the frag needed, packet too big and unreachable time out
+func listenICMP(network, address string) (*icmp.PacketConn, error) {
+	c, err := listenPacket(network, address)
+	if err == nil || !dgramICMP || !isPermission(err) {
+		return c, err
+	}
+
+	dgram := map[string]string{"ip4:icmp": "udp4", "ip6:ipv6-icmp": "udp6"}[network]
+	if dgram == "" {
+		return c, err
+	}
+	if dc, derr := listenPacket(dgram, address); derr == nil {
+		return dc, nil
+	}
+
+	return c, err
+}
+
+// isDgram returns true if the connection is a datagram ICMP socket,
+// the kernel rewrites the echo ID to the socket's ID and it delivers
+// only the echo replies that belong to the socket
+func isDgram(c net.PacketConn) bool {
+	_, ok := c.LocalAddr().(*net.UDPAddr)
+	return ok
+}
+
+// icmpAddr returns the destination address type that the connection expects
+func icmpAddr(c net.PacketConn, addr *net.IPAddr) net.Addr {
+	if isDgram(c) {
+		return &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}
+	}
+	return addr
+}
+
+// addrIP returns the IP address of the raw or datagram socket's address
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// isEchoReply returns true if the message is the echo reply to the id,
+// the datagram socket's replies match any id as the kernel rewrites it
+func isEchoReply(m *icmp.Message, id int, dgram bool) bool {
+	echo, ok := m.Body.(*icmp.Echo)
+	if !ok || (m.Type != ipv4.ICMPTypeEchoReply && m.Type != ipv6.ICMPTypeEchoReply) {
+		return false
+	}
+	return echo.ID == id || dgram
+}
+
+// isPermission returns true if the error is because of the privileges
+func isPermission(err error) bool {
+	if oe, ok := err.(*net.OpError); ok {
+		err = oe.Err
+	}
+	return os.IsPermission(err)
+}
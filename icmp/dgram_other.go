@@ -0,0 +1,11 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package icmp
+
+// dgramICMP enables the unprivileged datagram ICMP sockets fallback
+const dgramICMP = false
@@ -0,0 +1,147 @@
+package icmp_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/icmp"
+	xicmp "golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+func TestListenICMP(t *testing.T) {
+	if !icmp.DgramICMP {
+		t.Skip("the datagram ICMP sockets fallback isn't supported")
+	}
+
+	var (
+		eperm = &net.OpError{Op: "listen", Err: os.NewSyscallError("socket", syscall.EPERM)}
+		raw   = new(xicmp.PacketConn)
+		dgram = new(xicmp.PacketConn)
+	)
+
+	for _, tc := range []struct {
+		network string
+		rawErr  error
+		dgErr   error
+		conn    *xicmp.PacketConn
+		calls   []string
+	}{
+		{"ip4:icmp", nil, nil, raw, []string{"ip4:icmp"}},
+		{"ip4:icmp", eperm, nil, dgram, []string{"ip4:icmp", "udp4"}},
+		{"ip6:ipv6-icmp", eperm, nil, dgram, []string{"ip6:ipv6-icmp", "udp6"}},
+		{"ip4:icmp", eperm, eperm, nil, []string{"ip4:icmp", "udp4"}},
+		{"ip4:icmp", errors.New("address in use"), nil, nil, []string{"ip4:icmp"}},
+		{"udp4", eperm, nil, nil, []string{"udp4"}},
+	} {
+		var calls []string
+		restore := icmp.SetListenPacket(func(network, address string) (*xicmp.PacketConn, error) {
+			calls = append(calls, network)
+			if network == tc.network {
+				if tc.rawErr != nil {
+					return nil, tc.rawErr
+				}
+				return raw, nil
+			}
+			if tc.dgErr != nil {
+				return nil, tc.dgErr
+			}
+			return dgram, nil
+		})
+		c, err := icmp.ListenICMP(tc.network, "0.0.0.0")
+		restore()
+
+		if c != tc.conn || (c == nil) != (err != nil) {
+			t.Errorf("%s %v unexpected connection %p %v", tc.network, tc.rawErr, c, err)
+		}
+		if c == nil && err != tc.rawErr {
+			t.Errorf("%s expected the raw socket error but got %v", tc.network, err)
+		}
+		if strings.Join(calls, " ") != strings.Join(tc.calls, " ") {
+			t.Errorf("%s %v expected %q but got %q", tc.network, tc.rawErr, tc.calls, calls)
+		}
+	}
+}
+
+func TestIsPermission(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected bool
+	}{
+		{&net.OpError{Op: "listen", Err: os.NewSyscallError("socket", syscall.EPERM)}, true},
+		{&net.OpError{Op: "listen", Err: os.NewSyscallError("socket", syscall.EACCES)}, true},
+		{syscall.EPERM, true},
+		{&net.OpError{Op: "listen", Err: os.NewSyscallError("socket", syscall.EADDRINUSE)}, false},
+		{errors.New("permission denied"), false},
+	} {
+		if icmp.IsPermission(tc.err) != tc.expected {
+			t.Errorf("%v expected %t", tc.err, tc.expected)
+		}
+	}
+}
+
+// rawConn is a connection w/ the raw socket's local address
+type rawConn struct {
+	net.PacketConn
+}
+
+func (rawConn) LocalAddr() net.Addr { return &net.IPAddr{IP: net.IPv4zero} }
+
+func TestICMPAddr(t *testing.T) {
+	c, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	addr := &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+
+	// the datagram socket expects the UDP address
+	ua, ok := icmp.ICMPAddr(c, addr).(*net.UDPAddr)
+	if !ok || !ua.IP.Equal(addr.IP) || ua.Zone != "eth0" || ua.Port != 0 {
+		t.Errorf("expected the UDP address but got %#v", icmp.ICMPAddr(c, addr))
+	}
+	if a := icmp.ICMPAddr(rawConn{c}, addr); a != addr {
+		t.Errorf("expected the IP address but got %#v", a)
+	}
+
+	for _, a := range []net.Addr{addr, ua} {
+		if ip := icmp.AddrIP(a); !ip.Equal(addr.IP) {
+			t.Errorf("%s expected %s but got %s", a, addr.IP, ip)
+		}
+	}
+	if ip := icmp.AddrIP(&net.TCPAddr{IP: addr.IP}); ip != nil {
+		t.Error("expected nil but got", ip)
+	}
+}
+
+func TestIsEchoReply(t *testing.T) {
+	echo := func(typ xicmp.Type, id int) *xicmp.Message {
+		return &xicmp.Message{Type: typ, Body: &xicmp.Echo{ID: id, Seq: 1}}
+	}
+
+	for _, tc := range []struct {
+		msg      *xicmp.Message
+		dgram    bool
+		expected bool
+	}{
+		{echo(ipv4.ICMPTypeEchoReply, 100), false, true},
+		{echo(ipv6.ICMPTypeEchoReply, 100), false, true},
+		{echo(ipv4.ICMPTypeEchoReply, 200), false, false},
+		// the kernel rewrote the datagram socket's echo id
+		{echo(ipv4.ICMPTypeEchoReply, 200), true, true},
+		{echo(ipv6.ICMPTypeEchoReply, 200), true, true},
+		{echo(ipv4.ICMPTypeEcho, 100), false, false},
+		{echo(ipv4.ICMPTypeEcho, 200), true, false},
+		{&xicmp.Message{Type: ipv4.ICMPTypeTimeExceeded, Body: &xicmp.TimeExceeded{}}, true, false},
+	} {
+		if icmp.IsEchoReply(tc.msg, 100, tc.dgram) != tc.expected {
+			t.Errorf("%v %#v dgram=%t expected %t", tc.msg.Type, tc.msg.Body, tc.dgram, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var (
+	errTimeout = errors.New("Request timeout")
+	errNoReply = errors.New("100% packet loss")
+)
+
+// engine sends the echo requests and matches the replies through
+// one long-lived socket per address family and an in-flight table
+// keyed by the echo id / seq, the requests stay at the table twice
+// the timeout to detect the duplicate and late replies, a request
+// is open until its reply, error or timeout response is emitted
+type engine struct {
+	id      int
+	seq     uint16
+	sent    int
+	conn4   *icmp.PacketConn
+	conn6   *icmp.PacketConn
+	timeout time.Duration
+
+	flight map[uint16]*echo
+	queue  []*echo
+	next   int
+	last   map[string]int
+	open   int
+
+	out     chan Response
+	notify  chan struct{}
+	settled chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// echo represents an in-flight echo request
+type echo struct {
+	n       int // send order
+	seq     int // caller's sequence
+	wire    uint16
+	addr    string
+	ts      time.Time
+	replied bool
+	expired bool
+}
+
+// newEngine opens the sockets of the address families and starts
+// to receive the replies
+func newEngine(source string, v4, v6 bool, timeout time.Duration) (*engine, error) {
+	var err error
+
+	e := &engine{
+		id:      rand.Intn(0xffff),
+		timeout: timeout,
+		flight:  make(map[uint16]*echo),
+		last:    make(map[string]int),
+		out:     make(chan Response, 1000),
+		notify:  make(chan struct{}, 1),
+		settled: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	if v4 {
+		if e.conn4, err = listenICMP("ip4:icmp", source); err != nil {
+			return nil, err
+		}
+	}
+	if v6 {
+		if e.conn6, err = listenICMP("ip6:ipv6-icmp", source); err != nil {
+			if e.conn4 != nil {
+				e.conn4.Close()
+			}
+			return nil, err
+		}
+	}
+
+	for _, c := range []*icmp.PacketConn{e.conn4, e.conn6} {
+		if c != nil {
+			e.wg.Add(1)
+			go e.recv(c, c == e.conn6)
+		}
+	}
+	e.wg.Add(1)
+	go e.expire()
+
+	return e, nil
+}
+
+// send sends an echo request, the caller's sequence comes back
+// w/ the response
+func (e *engine) send(addr *net.IPAddr, seq int, data []byte) {
+	var (
+		conn           = e.conn4
+		typ  icmp.Type = ipv4.ICMPTypeEcho
+	)
+	if !IsIPv4(addr.IP) {
+		conn, typ = e.conn6, ipv6.ICMPTypeEchoRequest
+	}
+
+	// drop the previous reply's notification
+	select {
+	case <-e.notify:
+	default:
+	}
+
+	e.mu.Lock()
+	e.seq++
+	e.sent++
+	e.open++
+	ec := &echo{n: e.sent, seq: seq, wire: e.seq, addr: addr.IP.String()}
+	e.mu.Unlock()
+
+	b, err := (&icmp.Message{
+		Type: typ, Code: 0,
+		Body: &icmp.Echo{
+			ID:   e.id,
+			Seq:  int(ec.wire),
+			Data: data,
+		},
+	}).Marshal(nil)
+
+	if err == nil {
+		e.mu.Lock()
+		ec.ts = time.Now()
+		e.flight[ec.wire] = ec
+		e.queue = append(e.queue, ec)
+		e.mu.Unlock()
+
+		for {
+			_, err = conn.WriteTo(b, icmpAddr(conn, addr))
+			if neterr, ok := err.(*net.OpError); ok && neterr.Err == syscall.ENOBUFS {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			break
+		}
+	}
+
+	if err != nil {
+		e.mu.Lock()
+		first := !ec.replied && !ec.expired
+		ec.replied = true
+		e.mu.Unlock()
+		if first {
+			e.emit(Response{Error: err, Sequence: seq, Addr: ec.addr})
+			e.settle(1)
+		}
+	}
+}
+
+// recv reads the replies and the ICMP errors until the socket closed
+func (e *engine) recv(conn *icmp.PacketConn, v6 bool) {
+	var (
+		buf   = make([]byte, 0xffff)
+		proto = ProtocolIPv4ICMP
+		dgram = isDgram(conn)
+	)
+	defer e.wg.Done()
+
+	if v6 {
+		proto = ProtocolIPv6ICMP
+	}
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ts := time.Now()
+
+		m, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch b := m.Body.(type) {
+		case *icmp.Echo:
+			if isEchoReply(m, e.id, dgram) {
+				e.reply(uint16(b.Seq), addrIP(addr), n, ts)
+			}
+		case *icmp.TimeExceeded:
+			e.icmpError(b.Data, "time exceeded", 0)
+		case *icmp.PacketTooBig:
+			msg := fmt.Sprintf("packet too big from %s (mtu = %d)", addrIP(addr), b.MTU)
+			e.icmpError(b.Data, msg, b.MTU)
+		case *icmp.DstUnreach:
+			// the next-hop MTU (RFC 1191) is at the unused field
+			if !v6 && m.Code == IPv4ICMPCodeFragmentationNeeded && n >= 8 {
+				mtu := int(buf[6])<<8 | int(buf[7])
+				msg := fmt.Sprintf("frag needed and DF set from %s (mtu = %d)", addrIP(addr), mtu)
+				e.icmpError(b.Data, msg, mtu)
+				continue
+			}
+			e.icmpError(b.Data, "destination unreachable", 0)
+		}
+	}
+}
+
+// reply matches the echo reply w/ the in-flight request
+func (e *engine) reply(wire uint16, ip net.IP, size int, ts time.Time) {
+	e.mu.Lock()
+	ec, ok := e.flight[wire]
+	if !ok || ec.addr != ip.String() {
+		e.mu.Unlock()
+		return
+	}
+
+	r := Response{
+		Size:     size,
+		Addr:     ec.addr,
+		Sequence: ec.seq,
+		RTT:      float64(ts.Sub(ec.ts).Nanoseconds()) / 1e6,
+	}
+
+	switch {
+	case ec.replied:
+		r.Dup = true
+	case ec.expired:
+		r.Late = true
+	default:
+		r.OutOfOrder = ec.n < e.last[ec.addr]
+		if !r.OutOfOrder {
+			e.last[ec.addr] = ec.n
+		}
+	}
+	ec.replied = true
+	e.mu.Unlock()
+
+	if r.Dup || r.Late {
+		e.emit(r)
+		return
+	}
+
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+
+	e.emit(r)
+	e.settle(1)
+}
+
+// icmpError matches the echo request that the ICMP error quotes,
+// the mtu is the advertised next-hop MTU if there is any
+func (e *engine) icmpError(quote []byte, msg string, mtu int) {
+	var echoType byte = 8
+	if len(quote) < 1 {
+		return
+	}
+
+	switch quote[0] >> 4 {
+	case 4:
+		hl := int(quote[0]&0x0f) << 2
+		if hl < 20 || len(quote) < hl+8 || quote[9] != ProtocolIPv4ICMP {
+			return
+		}
+		quote = quote[hl:]
+	case 6:
+		if len(quote) < 48 || quote[6] != ProtocolIPv6ICMP {
+			return
+		}
+		quote, echoType = quote[40:], 128
+	default:
+		return
+	}
+
+	if quote[0] != echoType || int(quote[4])<<8|int(quote[5]) != e.id {
+		return
+	}
+
+	e.mu.Lock()
+	ec, ok := e.flight[uint16(quote[6])<<8|uint16(quote[7])]
+	if !ok || ec.replied || ec.expired {
+		e.mu.Unlock()
+		return
+	}
+	ec.replied = true
+	e.mu.Unlock()
+
+	e.emit(Response{Error: errors.New(msg), MTU: mtu, Sequence: ec.seq, Addr: ec.addr})
+	e.settle(1)
+}
+
+// expire reports the timed out requests and removes the old ones
+func (e *engine) expire() {
+	var ticker = time.NewTicker(10 * time.Millisecond)
+
+	defer e.wg.Done()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		}
+
+		var (
+			rs  []Response
+			now = time.Now()
+		)
+
+		e.mu.Lock()
+		for ; e.next < len(e.queue) && now.Sub(e.queue[e.next].ts) > e.timeout; e.next++ {
+			ec := e.queue[e.next]
+			if !ec.replied {
+				ec.expired = true
+				rs = append(rs, Response{Error: errTimeout, Timeout: true, Sequence: ec.seq, Addr: ec.addr})
+			}
+		}
+		for len(e.queue) > 0 && now.Sub(e.queue[0].ts) > 2*e.timeout {
+			if ec := e.queue[0]; e.flight[ec.wire] == ec {
+				delete(e.flight, ec.wire)
+			}
+			e.queue = e.queue[1:]
+			e.next--
+		}
+		e.mu.Unlock()
+
+		for _, r := range rs {
+			e.emit(r)
+		}
+		if len(rs) > 0 {
+			e.settle(len(rs))
+		}
+	}
+}
+
+// settle closes the requests once their responses emitted
+func (e *engine) settle(n int) {
+	e.mu.Lock()
+	e.open -= n
+	e.mu.Unlock()
+
+	select {
+	case e.settled <- struct{}{}:
+	default:
+	}
+}
+
+// wait waits for all the requests' replies, errors or timeouts
+// to be emitted
+func (e *engine) wait(done <-chan struct{}) {
+	for {
+		e.mu.Lock()
+		open := e.open
+		e.mu.Unlock()
+		if open < 1 {
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		case <-e.settled:
+		}
+	}
+}
+
+// emit sends out the response unless the engine closed
+func (e *engine) emit(r Response) {
+	select {
+	case e.out <- r:
+	case <-e.done:
+	}
+}
+
+// close closes the sockets and the responses channel
+func (e *engine) close() {
+	close(e.done)
+	for _, c := range []*icmp.PacketConn{e.conn4, e.conn6} {
+		if c != nil {
+			c.Close()
+		}
+	}
+	e.wg.Wait()
+	close(e.out)
+}
@@ -0,0 +1,115 @@
+package icmp
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// ParseMPLS exports parseMPLS for testing
+var ParseMPLS = parseMPLS
+
+// BoxCompare exports boxCompare for testing
+var BoxCompare = boxCompare
+
+// LowerMTU exports lowerMTU for testing
+var LowerMTU = lowerMTU
+
+// Calc exports calc for testing
+func (tp *Topology) Calc() { tp.calc() }
+
+// DSCPRemarks returns the remarks of the hops' received DSCPs
+func DSCPRemarks(tos int, dscps []int) []string {
+	var (
+		i = &Trace{tos: tos, qos: true}
+		r []string
+	)
+	for n, d := range dscps {
+		h := HopResp{}
+		i.dscpRemark(n+1, d, &h)
+		r = append(r, h.Remark)
+	}
+	return r
+}
+
+// EngineReplies feeds the engine w/ the sent requests and the replies
+// of the wire seqs, a zero wire seq waits one and a half timeout
+func EngineReplies(timeout time.Duration, sent int, wires []int) []Response {
+	var (
+		e, _ = newEngine("", false, false, timeout)
+		ip   = net.ParseIP("192.0.2.1")
+		rs   []Response
+	)
+	for n := 1; n <= sent; n++ {
+		ec := &echo{n: n, seq: n - 1, wire: uint16(n), addr: ip.String(), ts: time.Now()}
+		e.flight[ec.wire] = ec
+		e.queue = append(e.queue, ec)
+		e.open++
+	}
+	go func() {
+		for _, w := range wires {
+			if w == 0 {
+				time.Sleep(timeout * 3 / 2)
+				continue
+			}
+			e.reply(uint16(w), ip, 64, time.Now())
+		}
+		e.wait(nil)
+		e.close()
+	}()
+	for r := range e.out {
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// SearchSize exports searchSize for testing
+var SearchSize = searchSize
+
+// Count returns the trace rounds, -1 means until interrupted
+func (i *Trace) Count() int { return i.count }
+
+// SetMyIPAddr replaces the public IP address lookup
+func SetMyIPAddr(ip string) func() {
+	f := myIPAddr
+	myIPAddr = func() (string, error) { return ip, nil }
+	return func() { myIPAddr = f }
+}
+
+// ListenICMP exports listenICMP for testing
+var ListenICMP = listenICMP
+
+// DgramICMP is true if the OS has the datagram ICMP sockets fallback
+const DgramICMP = dgramICMP
+
+// ICMPAddr exports icmpAddr for testing
+var ICMPAddr = icmpAddr
+
+// AddrIP exports addrIP for testing
+var AddrIP = addrIP
+
+// IsPermission exports isPermission for testing
+var IsPermission = isPermission
+
+// IsEchoReply exports isEchoReply for testing
+var IsEchoReply = isEchoReply
+
+// SetListenPacket replaces the ICMP socket listener
+func SetListenPacket(f func(network, address string) (*icmp.PacketConn, error)) func() {
+	l := listenPacket
+	listenPacket = f
+	return func() { listenPacket = l }
+}
+
+// ICMPFlowMessage exports icmpFlowMessage for testing
+var ICMPFlowMessage = icmpFlowMessage
+
+// MDAFlows exports mdaFlows for testing
+var MDAFlows = mdaFlows
+
+// PathLinks exports pathLinks for testing
+var PathLinks = pathLinks
+
+// FmtFlows exports fmtFlows for testing
+var FmtFlows = fmtFlows
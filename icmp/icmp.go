@@ -16,6 +16,8 @@ import (
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
+
+	"github.com/mehrdadrad/mylg/stats"
 )
 
 const (
@@ -32,6 +34,8 @@ const (
 	IPv4ICMPTypeDestinationUnreachable = 3
 	// IPv4ICMPTypeTimeExceeded is ICMPv4 Time Exceeded
 	IPv4ICMPTypeTimeExceeded = 11
+	// IPv4ICMPCodeFragmentationNeeded is ICMPv4 Destination Unreachable, Fragmentation Needed
+	IPv4ICMPCodeFragmentationNeeded = 4
 
 	// IPv6ICMPTypeEchoReply is ICMPv6 Echo Reply
 	IPv6ICMPTypeEchoReply = 129
@@ -39,6 +43,8 @@ const (
 	IPv6ICMPTypeDestinationUnreachable = 1
 	//IPv6ICMPTypeTimeExceeded is ICMPv6 Time Exceeded
 	IPv6ICMPTypeTimeExceeded = 3
+	// IPv6ICMPTypePacketTooBig is ICMPv6 Packet Too Big
+	IPv6ICMPTypePacketTooBig = 2
 )
 
 // Trace represents trace properties
@@ -62,16 +68,33 @@ type Trace struct {
 	pSize    int
 	maxTTL   int
 
+	multipath bool
+	flow      int
+	maxFlows  int
+
+	tracebox bool
+	sent     []byte
+
+	pmtu    bool
+	maxSize int
+
+	tos      int
+	qos      bool
+	dscpHop  int
+	dscpLast int
+
 	uiTheme string
 	report  bool
+	save    bool
+	geo     bool
 	km      bool
 	count   int
+	fmtJSON bool
+	fmtCSV  bool
 }
 
 // Ping represents ping request
 type Ping struct {
-	m         icmp.Message
-	id        int
 	seq       int
 	pSize     int
 	count     int
@@ -85,49 +108,82 @@ type Ping struct {
 	forceV6   bool
 	network   string
 	source    string
+	tos       int
+	qos       bool
+	flood     bool
+	fmtJSON   bool
+	df        bool
+	sweep     [2]int
 	timeout   time.Duration
 	interval  time.Duration
 	MaxRTT    time.Duration
 }
 
-// HopResp represents hop's response
+// HopResp represents hop's response, the JSON field names
+// are the same as the web dashboard expects
 type HopResp struct {
-	num     int
-	hop     string
-	ip      string
-	elapsed float64
-	last    bool
-	err     error
-	whois   Whois
+	// Num is the hop number (TTL)
+	Num int `json:"Id"`
+	// Name is the reverse DNS name of the hop
+	Name string `json:"Hop"`
+	// IP is the hop's IP address, empty means timeout
+	IP string `json:"IP"`
+	// Elapsed is the probe's round trip time in milliseconds
+	Elapsed float64 `json:"Elapsed"`
+	// Type is the replied ICMP type
+	Type int `json:"Type"`
+	// Code is the replied ICMP code
+	Code int `json:"Code"`
+	// Last is true once the hop is the target
+	Last bool `json:"Last"`
+	// MPLS is the label stack if the hop is inside an LSP
+	MPLS []MPLSLabel `json:"MPLS,omitempty"`
+	// Mods are the probe's header fields that modified (tracebox)
+	Mods []Mod `json:"Mods,omitempty"`
+	// City and Country are the hop's geolocation (-geo)
+	City    string `json:"City,omitempty"`
+	Country string `json:"Country,omitempty"`
+	// DSCP is the probe's DSCP at the hop and Remark is the change
+	// from the previous hop (-dscp / -tos)
+	DSCP   string `json:"DSCP,omitempty"`
+	Remark string `json:"Remark,omitempty"`
+	// Err holds the probe's error if it failed to send
+	Err error `json:"-"`
+
+	Whois
 }
 
 // ICMPResp represents ICMP response msg
 type ICMPResp struct {
-	typ  int
-	code int
-	id   int
-	seq  int
-	src  net.IP
-	udp  struct{ dstPort int }
-	ip   struct {
+	typ   int
+	code  int
+	id    int
+	seq   int
+	src   net.IP
+	udp   struct{ dstPort int }
+	mpls  []MPLSLabel
+	quote []byte
+	mtu   int
+	ip    struct {
 		dst net.IP
 		id  int
+		tos int
 	}
 }
 
 // Whois represents prefix info from RIPE
 type Whois struct {
-	holder string
-	asn    float64
+	Holder    string  `json:"Holder"`
+	ASN       float64 `json:"ASN"`
+	Prefix    string  `json:"Prefix"`
+	PrefixLen int     `json:"PrefixLen"`
 }
 
 // Stats represents statistic's fields
 type Stats struct {
-	count int64   // sent packet
-	min   float64 // minimum/best rtt
-	avg   float64 // average rtt
-	max   float64 // maximum/worst rtt
-	pkl   int64   // packet loss
+	count int64       // sent packet
+	pkl   int64       // packet loss
+	rtt   stats.Stats // round trip times
 }
 
 func bytesToIPv6(b []byte) net.IP {
@@ -152,7 +208,16 @@ func icmpV4RespParser(b []byte) ICMPResp {
 		if err == nil {
 			resp.ip.id = h.ID
 			resp.ip.dst = h.Dst
+			resp.ip.tos = h.TOS
+		}
+		// next-hop MTU (RFC 1191) and the quoted probe
+		if resp.code == IPv4ICMPCodeFragmentationNeeded && len(b) >= 56 {
+			resp.mtu = int(b[26])<<8 | int(b[27])
+			resp.id = int(b[52])<<8 | int(b[53])
+			resp.seq = int(b[54])<<8 | int(b[55])
 		}
+		resp.mpls = parseMPLS(b[20:], 5, 4)
+		resp.quote = quotedPacket(b[20:], resp.mpls)
 	case IPv4ICMPTypeTimeExceeded:
 		h, err := ipv4.ParseHeader(b[28:48])
 		resp.id = int(b[52])<<8 | int(b[53])
@@ -160,12 +225,27 @@ func icmpV4RespParser(b []byte) ICMPResp {
 		if err == nil {
 			resp.ip.id = h.ID
 			resp.ip.dst = h.Dst
+			resp.ip.tos = h.TOS
 		}
+		resp.mpls = parseMPLS(b[20:], 5, 4)
+		resp.quote = quotedPacket(b[20:], resp.mpls)
 	}
 
 	return resp
 }
 
+// quotedPacket returns the original datagram of the ICMPv4 error
+// message w/o the extension structure if there is any
+func quotedPacket(m []byte, mpls []MPLSLabel) []byte {
+	var q = m[8:]
+	if m[5] != 0 && len(q) > int(m[5])*4 {
+		q = q[:int(m[5])*4]
+	} else if len(mpls) > 0 && len(q) > icmpExtOrigLen {
+		q = q[:icmpExtOrigLen]
+	}
+	return q
+}
+
 func icmpV6RespParser(b []byte) ICMPResp {
 	var resp ICMPResp
 
@@ -176,6 +256,9 @@ func icmpV6RespParser(b []byte) ICMPResp {
 	if len(b) < 44 && resp.typ == IPv6ICMPTypeTimeExceeded {
 		return resp
 	}
+	if len(b) < 56 && resp.typ == IPv6ICMPTypePacketTooBig {
+		return resp
+	}
 
 	switch resp.typ {
 	case IPv6ICMPTypeEchoReply:
@@ -183,10 +266,20 @@ func icmpV6RespParser(b []byte) ICMPResp {
 		resp.seq = int(b[6])<<8 | int(b[7])
 	case IPv6ICMPTypeDestinationUnreachable:
 		resp.ip.dst = bytesToIPv6(b[32:48])
+		resp.ip.tos = ipv6TrafficClass(b[8:])
+		resp.mpls = parseMPLS(b, 4, 8)
+	case IPv6ICMPTypePacketTooBig:
+		resp.mtu = int(b[4])<<24 | int(b[5])<<16 | int(b[6])<<8 | int(b[7])
+		resp.id = int(b[52])<<8 | int(b[53])
+		resp.seq = int(b[54])<<8 | int(b[55])
+		resp.ip.dst = bytesToIPv6(b[32:48])
+		resp.ip.tos = ipv6TrafficClass(b[8:])
 	case IPv6ICMPTypeTimeExceeded:
 		resp.id = int(b[52])<<8 | int(b[53])
 		resp.seq = int(b[54])<<8 | int(b[55])
 		resp.ip.dst = bytesToIPv6(b[32:48])
+		resp.ip.tos = ipv6TrafficClass(b[8:])
+		resp.mpls = parseMPLS(b, 4, 8)
 	}
 
 	return resp
@@ -250,6 +343,19 @@ func setIPv4TOS(fd int, v int) error {
 	return nil
 }
 
+func setIPv6TrafficClass(fd int, v int) error {
+	err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, v)
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+// ipv6TrafficClass returns the IPv6 header's traffic class
+func ipv6TrafficClass(h []byte) int {
+	return int(h[0]&0x0f)<<4 | int(h[1]>>4)
+}
+
 func setIPv4TTL(fd int, v int) error {
 	err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IP, syscall.IP_TTL, v)
 	if err != nil {
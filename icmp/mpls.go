@@ -0,0 +1,98 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// icmpExtVersion is the ICMP extension structure version (RFC 4884)
+	icmpExtVersion = 2
+	// icmpExtOrigLen is the original datagram length that the non-compliant
+	// routers include before the extension structure w/o length attribute
+	icmpExtOrigLen = 128
+	// icmpExtClassMPLS is the MPLS label stack object class (RFC 4950)
+	icmpExtClassMPLS = 1
+	// icmpExtCTypeIncoming is the incoming MPLS label stack c-type
+	icmpExtCTypeIncoming = 1
+)
+
+// MPLSLabel represents an MPLS label stack entry (RFC 4950)
+type MPLSLabel struct {
+	Label int  `json:"Label"`
+	TC    int  `json:"TC"`
+	S     bool `json:"S"`
+	TTL   int  `json:"TTL"`
+}
+
+// String returns the label stack entry in L=label,TC=tc,S=s,TTL=ttl format
+func (l MPLSLabel) String() string {
+	var s int
+	if l.S {
+		s = 1
+	}
+	return fmt.Sprintf("L=%d,TC=%d,S=%d,TTL=%d", l.Label, l.TC, s, l.TTL)
+}
+
+// parseMPLS returns the MPLS label stack from the ICMP extension objects,
+// m is the ICMP message, lenIdx is the original datagram length attribute
+// index and unit is its unit in bytes (ICMPv4: 5/4, ICMPv6: 4/8)
+func parseMPLS(m []byte, lenIdx, unit int) []MPLSLabel {
+	var labels []MPLSLabel
+
+	if len(m) < 8 {
+		return nil
+	}
+
+	offset := 8 + int(m[lenIdx])*unit
+	if m[lenIdx] == 0 {
+		offset = 8 + icmpExtOrigLen
+	}
+	if len(m) < offset+4 || m[offset]>>4 != icmpExtVersion {
+		return nil
+	}
+
+	ext := m[offset:]
+	// zero checksum means it's not computed
+	if (ext[2] != 0 || ext[3] != 0) && onesSum(ext) != 0xffff {
+		return nil
+	}
+
+	for obj := ext[4:]; len(obj) >= 4; {
+		oLen := int(obj[0])<<8 | int(obj[1])
+		if oLen < 4 || oLen > len(obj) {
+			break
+		}
+		if obj[2] == icmpExtClassMPLS && obj[3] == icmpExtCTypeIncoming {
+			for e := obj[4:oLen]; len(e) >= 4; e = e[4:] {
+				labels = append(labels, MPLSLabel{
+					Label: int(e[0])<<12 | int(e[1])<<4 | int(e[2])>>4,
+					TC:    int(e[2]>>1) & 0x07,
+					S:     e[2]&0x01 == 1,
+					TTL:   int(e[3]),
+				})
+			}
+		}
+		obj = obj[oLen:]
+	}
+
+	return labels
+}
+
+// fmtMPLS returns the label stack in MPLS: L=label,TC=tc,S=s,TTL=ttl ... format
+func fmtMPLS(labels []MPLSLabel) string {
+	return "MPLS: " + mplsLabels(labels)
+}
+
+// mplsLabels returns the space separated label stack entries
+func mplsLabels(labels []MPLSLabel) string {
+	var s []string
+	for _, l := range labels {
+		s = append(s, l.String())
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,318 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+
+	"golang.org/x/net/icmp"
+)
+
+// mdaProbes holds the number of flows that should be probed at a hop
+// to rule out one more next hop with 95% confidence, once n next hops
+// have been seen (multipath detection algorithm, Veitch et al.)
+var mdaProbes = []int{1, 6, 11, 16, 21, 27, 33, 38, 44, 51, 57, 63, 70, 76, 83, 90}
+
+// PathGraph represents the load balanced paths toward the target
+type PathGraph struct {
+	Host  string
+	IP    string
+	Flows int
+	Hops  []PathHop
+	Links []PathLink
+}
+
+// PathHop represents all interfaces that replied at a TTL
+type PathHop struct {
+	TTL   int
+	Nodes []PathNode
+}
+
+// PathNode represents an interface and the flows which went through it
+type PathNode struct {
+	IP     string
+	Name   string
+	Holder string
+	ASN    float64
+	RTT    float64
+	Last   bool
+	Flows  []int
+}
+
+// PathLink represents an edge between two interfaces at TTL and TTL+1
+type PathLink struct {
+	TTL   int
+	From  string
+	To    string
+	Flows []int
+}
+
+// MultiPath probes every hop with several flows, the flow identifier
+// stays the same for each flow across the hops (Paris traceroute)
+// and it returns the load balanced paths as a graph
+func (i *Trace) MultiPath(ctx context.Context) (*PathGraph, error) {
+	var (
+		flows = 0
+		prev  = make(map[int]string)
+		g     = &PathGraph{Host: i.host, IP: i.ip.String()}
+	)
+
+	if err := i.Bind(); err != nil {
+		return nil, err
+	}
+	defer syscall.Close(i.fd)
+
+	for h := 1; h <= i.maxTTL; h++ {
+		var (
+			curr  = make(map[int]string)
+			nodes = make(map[string]*PathNode)
+			order []string
+		)
+
+		for f := 0; f < i.maxFlows; f++ {
+			if f >= mdaFlows(len(nodes)) && f >= flows {
+				break
+			}
+			if ctx.Err() != nil {
+				return g, ctx.Err()
+			}
+			i.flow = f
+			hop := i.NextHop(h)
+			if hop.Err != nil {
+				return g, hop.Err
+			}
+			if hop.IP == "" {
+				continue
+			}
+			n, ok := nodes[hop.IP]
+			if !ok {
+				n = &PathNode{IP: hop.IP, Name: hop.Name, Last: hop.Last}
+				nodes[hop.IP] = n
+				order = append(order, hop.IP)
+			}
+			n.Flows = append(n.Flows, f)
+			n.RTT = min(n.RTT, hop.Elapsed)
+			curr[f] = hop.IP
+		}
+
+		if f := mdaFlows(len(nodes)); f > flows {
+			flows = f
+		}
+
+		hop := PathHop{TTL: h}
+		for _, ip := range order {
+			hop.Nodes = append(hop.Nodes, *nodes[ip])
+		}
+		if i.ripe {
+			i.addPathWhois(hop.Nodes)
+		}
+		g.Hops = append(g.Hops, hop)
+		g.Links = append(g.Links, pathLinks(h-1, prev, curr)...)
+
+		if reached(hop.Nodes) {
+			break
+		}
+		prev = curr
+	}
+
+	if flows > i.maxFlows {
+		flows = i.maxFlows
+	}
+	g.Flows = flows
+
+	return g, nil
+}
+
+// PrintMultiPath prints out the load balanced paths per hop
+func (i *Trace) PrintMultiPath(ctx context.Context) {
+	fmt.Printf("trace route to %s (%s), %d hops max, multipath up to %d flows\n", i.host, i.ip, i.maxTTL, i.maxFlows)
+
+	g, err := i.MultiPath(ctx)
+	if err != nil {
+		println(err.Error())
+	}
+	if g == nil {
+		return
+	}
+
+	for _, h := range g.Hops {
+		if len(h.Nodes) == 0 {
+			fmt.Printf("%-2d *\n", h.TTL)
+			continue
+		}
+		for n, node := range h.Nodes {
+			var branch = "  "
+			if len(h.Nodes) > 1 {
+				branch = "├ "
+				if n == len(h.Nodes)-1 {
+					branch = "└ "
+				}
+			}
+			if n == 0 {
+				fmt.Printf("%-2d %s%s\n", h.TTL, branch, fmtPathNode(node))
+			} else {
+				fmt.Printf("   %s%s\n", branch, fmtPathNode(node))
+			}
+		}
+	}
+}
+
+// fmtPathNode formats a multipath node
+func fmtPathNode(n PathNode) string {
+	var msg string
+	if n.Name != "" {
+		msg = fmt.Sprintf("%s (%s) ", n.Name, n.IP)
+	} else {
+		msg = fmt.Sprintf("%s ", n.IP)
+	}
+	if n.ASN != 0 {
+		msg += fmtASN(n.ASN, n.Holder) + " "
+	}
+	return msg + fmt.Sprintf("%.3f ms flows: %s", n.RTT, fmtFlows(n.Flows))
+}
+
+// fmtFlows formats flows like 0-3,7
+func fmtFlows(flows []int) string {
+	var r []string
+	for n := 0; n < len(flows); n++ {
+		b := n
+		for n+1 < len(flows) && flows[n+1] == flows[n]+1 {
+			n++
+		}
+		if b == n {
+			r = append(r, fmt.Sprintf("%d", flows[b]))
+		} else {
+			r = append(r, fmt.Sprintf("%d-%d", flows[b], flows[n]))
+		}
+	}
+	return strings.Join(r, ",")
+}
+
+// addPathWhois adds whois info to the multipath nodes
+func (i *Trace) addPathWhois(nodes []PathNode) {
+	for n := range nodes {
+		if w, err := ipWhois(nodes[n].IP); err == nil {
+			nodes[n].Holder = w.Holder
+			nodes[n].ASN = w.ASN
+		}
+	}
+}
+
+// pathLinks returns the links between two hops based on the flows
+func pathLinks(ttl int, prev, curr map[int]string) []PathLink {
+	var (
+		links = make(map[string]*PathLink)
+		keys  []string
+	)
+
+	if ttl < 1 {
+		return nil
+	}
+
+	for f, to := range curr {
+		from, ok := prev[f]
+		if !ok {
+			continue
+		}
+		key := from + " " + to
+		if _, ok := links[key]; !ok {
+			links[key] = &PathLink{TTL: ttl, From: from, To: to}
+			keys = append(keys, key)
+		}
+		links[key].Flows = append(links[key].Flows, f)
+	}
+
+	sort.Strings(keys)
+	r := make([]PathLink, 0, len(keys))
+	for _, k := range keys {
+		sort.Ints(links[k].Flows)
+		r = append(r, *links[k])
+	}
+	return r
+}
+
+// reached returns true if all replied interfaces are the target
+func reached(nodes []PathNode) bool {
+	for _, n := range nodes {
+		if !n.Last {
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+// mdaFlows returns the number of flows needed once n interfaces found
+func mdaFlows(n int) int {
+	if n < len(mdaProbes) {
+		return mdaProbes[n]
+	}
+	return mdaProbes[len(mdaProbes)-1]
+}
+
+// flowPort returns the UDP/TCP source port for a flow
+func flowPort(flow int) int {
+	return 33000 + flow
+}
+
+// icmpFlowMessage returns an echo request that its checksum stays the same
+// for a flow whatever the sequence is; the first two bytes of the payload
+// compensate the sequence changes
+func icmpFlowMessage(typ icmp.Type, id, seq, flow, pSize int, isIPv4 bool) ([]byte, error) {
+	data := icmpPayload(absInt(pSize), isIPv4)
+	if len(data) < 2 {
+		data = make([]byte, 2)
+	}
+
+	m, err := (&icmp.Message{
+		Type: typ, Code: 0,
+		Body: &icmp.Echo{
+			ID: id, Seq: seq,
+			Data: data,
+		},
+	}).Marshal(nil)
+	if err != nil {
+		return m, err
+	}
+
+	want := uint16(0xfffe - flow)
+	m[2], m[3] = 0, 0
+	comp := onesAdd(want, ^onesSum(m))
+	binary.BigEndian.PutUint16(m[8:10], comp)
+
+	// the kernel calculates the ICMPv6 checksum including pseudo header
+	// which is the same for all of the probes
+	if isIPv4 {
+		binary.BigEndian.PutUint16(m[2:4], ^want)
+	}
+
+	return m, nil
+}
+
+// onesSum returns the 16 bits one's complement sum
+func onesSum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)&1 != 0 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for (sum >> 16) > 0 {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return uint16(sum)
+}
+
+// onesAdd adds two numbers in one's complement
+func onesAdd(a, b uint16) uint16 {
+	sum := uint32(a) + uint32(b)
+	sum = (sum >> 16) + (sum & 0xffff)
+	return uint16(sum)
+}
@@ -0,0 +1,110 @@
+package icmp_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// sum16 returns the 16 bits one's complement sum
+func sum16(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)&1 != 0 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 > 0 {
+		sum = sum>>16 + sum&0xffff
+	}
+	return uint16(sum)
+}
+
+func TestICMPFlowMessage(t *testing.T) {
+	checksums := make(map[uint16]int)
+	for flow := 0; flow < 16; flow++ {
+		var checksum uint16
+		for seq := 1; seq < 200; seq += 17 {
+			m, err := icmp.ICMPFlowMessage(ipv4.ICMPTypeEcho, 1234, seq, flow, 64, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := sum16(m); s != 0xffff {
+				t.Fatalf("flow %d seq %d invalid checksum %#x", flow, seq, s)
+			}
+			if seq == 1 {
+				checksum = binary.BigEndian.Uint16(m[2:4])
+				continue
+			}
+			if c := binary.BigEndian.Uint16(m[2:4]); c != checksum {
+				t.Errorf("flow %d seq %d checksum %#x expected %#x", flow, seq, c, checksum)
+			}
+		}
+		if f, ok := checksums[checksum]; ok {
+			t.Errorf("flow %d and %d have the same checksum %#x", f, flow, checksum)
+		}
+		checksums[checksum] = flow
+	}
+
+	// the kernel calculates the ICMPv6 checksum, the message sum
+	// should stay the same for the flow
+	a, _ := icmp.ICMPFlowMessage(ipv6.ICMPTypeEchoRequest, 1234, 1, 3, 64, false)
+	b, _ := icmp.ICMPFlowMessage(ipv6.ICMPTypeEchoRequest, 1234, 99, 3, 64, false)
+	c, _ := icmp.ICMPFlowMessage(ipv6.ICMPTypeEchoRequest, 1234, 1, 4, 64, false)
+	if a[2] != 0 || a[3] != 0 {
+		t.Error("expected the empty ICMPv6 checksum")
+	}
+	if sum16(a) != sum16(b) || sum16(a) == sum16(c) {
+		t.Errorf("unexpected ICMPv6 sums %#x %#x %#x", sum16(a), sum16(b), sum16(c))
+	}
+}
+
+func TestMDAFlows(t *testing.T) {
+	for n, expected := range map[int]int{0: 1, 1: 6, 2: 11, 15: 90, 16: 90, 100: 90} {
+		if r := icmp.MDAFlows(n); r != expected {
+			t.Errorf("%d interfaces expected %d flows but got %d", n, expected, r)
+		}
+	}
+}
+
+func TestPathLinks(t *testing.T) {
+	prev := map[int]string{0: "10.0.0.1", 1: "10.0.0.1", 2: "10.0.0.2", 3: "10.0.0.2"}
+	curr := map[int]string{0: "10.0.1.1", 1: "10.0.1.2", 2: "10.0.1.1", 3: "10.0.1.1", 4: "10.0.1.2"}
+	expected := []icmp.PathLink{
+		{TTL: 2, From: "10.0.0.1", To: "10.0.1.1", Flows: []int{0}},
+		{TTL: 2, From: "10.0.0.1", To: "10.0.1.2", Flows: []int{1}},
+		{TTL: 2, From: "10.0.0.2", To: "10.0.1.1", Flows: []int{2, 3}},
+	}
+
+	if r := icmp.PathLinks(2, prev, curr); !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %v but got %v", expected, r)
+	}
+	if r := icmp.PathLinks(0, prev, curr); r != nil {
+		t.Error("expected no link at TTL 0 but got", r)
+	}
+	if r := icmp.PathLinks(2, nil, curr); len(r) != 0 {
+		t.Error("expected no link w/o the previous hop but got", r)
+	}
+}
+
+func TestFmtFlows(t *testing.T) {
+	for _, tc := range []struct {
+		flows    []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{5}, "5"},
+		{[]int{0, 1, 2, 3, 7}, "0-3,7"},
+		{[]int{0, 2, 4, 5}, "0,2,4-5"},
+		{[]int{1, 2, 4, 5, 6, 9}, "1-2,4-6,9"},
+	} {
+		if r := icmp.FmtFlows(tc.flows); r != tc.expected {
+			t.Errorf("%v expected %q but got %q", tc.flows, tc.expected, r)
+		}
+	}
+}
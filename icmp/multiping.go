@@ -0,0 +1,398 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+// MultiPing represents fping style ping of the multiple hosts
+// w/ one socket per address family
+type MultiPing struct {
+	targets  []*PingTarget
+	count    int
+	pSize    int
+	tos      int
+	qos      bool
+	forceV4  bool
+	forceV6  bool
+	alive    bool
+	unreach  bool
+	timeout  time.Duration
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+// PingTarget represents a target's statistics, the RTTs are
+// in milliseconds
+type PingTarget struct {
+	Host   string  `json:"host"`
+	Addr   string  `json:"addr"`
+	Sent   int     `json:"sent"`
+	Recv   int     `json:"recv"`
+	Loss   float64 `json:"loss"`
+	Last   float64 `json:"last"`
+	Min    float64 `json:"min"`
+	Avg    float64 `json:"avg"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"stddev"`
+	Jitter float64 `json:"jitter"`
+	Dup    int     `json:"dup"`
+	Late   int     `json:"late"`
+	Err    string  `json:"err,omitempty"`
+
+	ip  net.IP
+	rtt stats.Stats
+}
+
+// IsMultiPing returns true if the ping arguments have
+// a hosts file or more than one host
+func IsMultiPing(args string) bool {
+	target, flag := cli.Flag(args)
+	_, ok := flag["f"]
+	return ok || len(strings.Fields(target)) > 1
+}
+
+// NewMultiPing creates a multi hosts ping from the hosts
+// file (-f) or the hosts list
+func NewMultiPing(args string, cfg cli.Config) (*MultiPing, error) {
+	var err error
+
+	target, flag := cli.Flag(args)
+	if file, ok := flag["f"].(string); ok {
+		target = file
+	}
+	if _, ok := flag["help"]; ok || target == "" {
+		help(cfg)
+		return nil, nil
+	}
+
+	m := &MultiPing{
+		count:   cli.SetFlag(flag, "c", cfg.Ping.Count).(int),
+		pSize:   64,
+		forceV4: cli.SetFlag(flag, "4", false).(bool),
+		forceV6: cli.SetFlag(flag, "6", false).(bool),
+		alive:   cli.SetFlag(flag, "a", false).(bool),
+		unreach: cli.SetFlag(flag, "u", false).(bool),
+	}
+
+	if m.tos, m.qos, err = qosFlag(flag); err != nil {
+		return nil, err
+	}
+	timeout := NormalizeDuration(cli.SetFlag(flag, "t", cfg.Ping.Timeout).(string))
+	if m.timeout, err = time.ParseDuration(timeout); err != nil {
+		return nil, fmt.Errorf("timeout options is not valid")
+	}
+	interval := NormalizeDuration(cli.SetFlag(flag, "i", cfg.Ping.Interval).(string))
+	if m.interval, err = time.ParseDuration(interval); err != nil {
+		return nil, fmt.Errorf("interval options is not valid")
+	}
+
+	hosts, err := ParseTargets(target)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("there isn't any target")
+	}
+	for _, h := range hosts {
+		m.targets = append(m.targets, &PingTarget{Host: h})
+	}
+	m.resolve()
+
+	return m, nil
+}
+
+// resolve looks up the targets concurrently
+func (m *MultiPing) resolve() {
+	var (
+		wg sync.WaitGroup
+		c  = make(chan *PingTarget)
+	)
+	for n := 0; n < 32; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range c {
+				ips, err := net.LookupIP(t.Host)
+				if err != nil {
+					t.Err = "unresolved"
+					continue
+				}
+				for _, ip := range ips {
+					if (IsIPv4(ip) && !m.forceV6) || (!IsIPv4(ip) && !m.forceV4) {
+						t.ip = ip
+						break
+					}
+				}
+				if t.ip == nil {
+					t.Err = "there is not A or AAAA record"
+					continue
+				}
+				t.Addr = t.ip.String()
+			}
+		}()
+	}
+	for _, t := range m.targets {
+		c <- t
+	}
+	close(c)
+	wg.Wait()
+}
+
+// Run pings the targets for the count rounds or until the
+// context canceled, each round pings all the targets through
+// the engine's socket of the address family
+func (m *MultiPing) Run(ctx context.Context) error {
+	var (
+		v4, v6 bool
+		wg     sync.WaitGroup
+		gap    time.Duration
+		n      = len(m.targets)
+	)
+
+	for _, t := range m.targets {
+		if t.ip != nil {
+			v4, v6 = v4 || IsIPv4(t.ip), v6 || !IsIPv4(t.ip)
+		}
+	}
+
+	e, err := m.engine(v4, v6)
+	if err != nil {
+		return err
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for r := range e.out {
+			m.response(r)
+		}
+	}()
+
+	// spreads the requests over the interval
+	if gap = m.interval / time.Duration(n); gap > 10*time.Millisecond {
+		gap = 10 * time.Millisecond
+	}
+
+LOOP:
+	for round := 0; m.count < 1 || round < m.count; round++ {
+		if round > 0 {
+			select {
+			case <-ctx.Done():
+				break LOOP
+			case <-time.After(m.interval):
+			}
+		}
+		for i, t := range m.targets {
+			if t.ip == nil {
+				continue
+			}
+			m.mu.Lock()
+			t.Sent++
+			m.mu.Unlock()
+			// the target is the sequence's index
+			e.send(&net.IPAddr{IP: t.ip}, round*n+i, make([]byte, m.pSize-8))
+			select {
+			case <-ctx.Done():
+				break LOOP
+			case <-time.After(gap):
+			}
+		}
+	}
+
+	// waits for the last replies
+	e.wait(ctx.Done())
+	e.close()
+	wg.Wait()
+
+	return nil
+}
+
+// engine opens the ping engine of the address families
+func (m *MultiPing) engine(v4, v6 bool) (*engine, error) {
+	e, err := newEngine("", v4, v6, m.timeout)
+	if err != nil || !m.qos {
+		return e, err
+	}
+	if e.conn4 != nil {
+		err = e.conn4.IPv4PacketConn().SetTOS(m.tos)
+	}
+	if err == nil && e.conn6 != nil {
+		err = e.conn6.IPv6PacketConn().SetTrafficClass(m.tos)
+	}
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// response updates the target's statistics w/ the response, the
+// late and duplicate replies don't count as received
+func (m *MultiPing) response(r Response) {
+	t := m.targets[r.Sequence%len(m.targets)]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case r.Dup:
+		t.Dup++
+	case r.Late:
+		t.Late++
+	case r.Timeout:
+	case r.Error != nil:
+		t.Err = r.Error.Error()
+	default:
+		t.add(r.RTT)
+	}
+}
+
+// add adds the RTT to the target's statistics
+func (t *PingTarget) add(rtt float64) {
+	t.rtt.Add(rtt)
+	t.Recv++
+	t.Last = rtt
+	t.Min = t.rtt.Min()
+	t.Avg = t.rtt.Mean()
+	t.Max = t.rtt.Max()
+	t.StdDev = t.rtt.StdDev()
+	t.Jitter = t.rtt.Jitter()
+}
+
+// Targets returns the targets' statistics
+func (m *MultiPing) Targets() []PingTarget {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var r []PingTarget
+	for _, t := range m.targets {
+		c := *t
+		if c.Sent > 0 {
+			c.Loss = float64(c.Sent-c.Recv) * 100 / float64(c.Sent)
+		}
+		r = append(r, c)
+	}
+	return r
+}
+
+// PrintPretty pings the targets and prints out a table that
+// updates live on a terminal, -a / -u print out the alive /
+// unreachable targets only for scripting, it returns the run error
+func (m *MultiPing) PrintPretty(ctx context.Context) error {
+	var (
+		done  = make(chan error, 1)
+		fd    = int(os.Stdout.Fd())
+		live  = terminal.IsTerminal(fd) && !m.alive && !m.unreach
+		lines int
+		buf   bytes.Buffer
+	)
+
+	go func() { done <- m.Run(ctx) }()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+LOOP:
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+			break LOOP
+		case <-ticker.C:
+			if !live {
+				continue
+			}
+			buf.Reset()
+			WriteMultiPing(&buf, m.Targets())
+			if _, h, err := terminal.GetSize(fd); err == nil && h > 0 && bytes.Count(buf.Bytes(), []byte("\n")) >= h {
+				fmt.Printf("\r%d targets, %d alive", len(m.targets), countAlive(m.Targets()))
+				continue
+			}
+			if lines > 0 {
+				fmt.Printf("\033[%dA\033[J", lines)
+			}
+			os.Stdout.Write(buf.Bytes())
+			lines = bytes.Count(buf.Bytes(), []byte("\n"))
+		}
+	}
+
+	targets := m.Targets()
+	switch {
+	case m.alive || m.unreach:
+		for _, t := range targets {
+			if (m.alive && t.Recv > 0) || (m.unreach && t.Recv == 0) {
+				fmt.Println(t.Host)
+			}
+		}
+		return nil
+	case lines > 0:
+		fmt.Printf("\033[%dA\033[J", lines)
+	default:
+		fmt.Println()
+	}
+	WriteMultiPing(os.Stdout, targets)
+
+	alive := countAlive(targets)
+	fmt.Printf("%d targets, %d alive, %d unreachable\n", len(targets), alive, len(targets)-alive)
+
+	return nil
+}
+
+// WriteMultiPing writes the targets' statistics table
+func WriteMultiPing(w io.Writer, targets []PingTarget) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Target", "Address", "Sent", "Recv", "Loss%", "Min", "Avg", "Max", "StdDev", "Jitter", "Dup", "Late"})
+	table.SetAutoWrapText(false)
+
+	for _, t := range targets {
+		if t.Err != "" && t.Sent == 0 {
+			table.Append([]string{t.Host, t.Err, "", "", "", "", "", "", "", "", "", ""})
+			continue
+		}
+		table.Append([]string{
+			t.Host,
+			t.Addr,
+			fmt.Sprintf("%d", t.Sent),
+			fmt.Sprintf("%d", t.Recv),
+			fmt.Sprintf("%.1f", t.Loss),
+			fmt.Sprintf("%.3f", t.Min),
+			fmt.Sprintf("%.3f", t.Avg),
+			fmt.Sprintf("%.3f", t.Max),
+			fmt.Sprintf("%.3f", t.StdDev),
+			fmt.Sprintf("%.3f", t.Jitter),
+			fmt.Sprintf("%d", t.Dup),
+			fmt.Sprintf("%d", t.Late),
+		})
+	}
+	table.Render()
+}
+
+func countAlive(targets []PingTarget) int {
+	var n int
+	for _, t := range targets {
+		if t.Recv > 0 {
+			n++
+		}
+	}
+	return n
+}
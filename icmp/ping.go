@@ -2,39 +2,34 @@
 package icmp
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
 	"fmt"
 	"golang.org/x/net/icmp"
-	"golang.org/x/net/ipv4"
-	"golang.org/x/net/ipv6"
-	"math/rand"
 	"net"
-	"os"
-	"os/signal"
 	"regexp"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/stats"
 )
 
-// packet represents ping packet
-type packet struct {
-	bytes []byte
-	addr  net.Addr
-	err   error
-}
-
-// Response represent ping response
+// Response represent ping response, the duplicate and late
+// replies come after the request's first reply / timeout, the
+// MTU is the next-hop MTU of the fragmentation needed / too big
 type Response struct {
-	RTT      float64
-	Size     int
-	Sequence int
-	Addr     string
-	Timeout  bool
-	Error    error
+	RTT        float64
+	Size       int
+	Sequence   int
+	Addr       string
+	Timeout    bool
+	Dup        bool
+	Late       bool
+	OutOfOrder bool
+	MTU        int
+	Error      error
 }
 
 // NewPing creates a new ping object
@@ -51,7 +46,6 @@ func NewPing(args string, cfg cli.Config) (*Ping, error) {
 	}
 
 	p := Ping{
-		id:        rand.Intn(0xffff),
 		seq:       -1,
 		pSize:     64,
 		target:    target,
@@ -61,11 +55,34 @@ func NewPing(args string, cfg cli.Config) (*Ping, error) {
 		count:     cli.SetFlag(flag, "c", cfg.Ping.Count).(int),
 		forceV4:   cli.SetFlag(flag, "4", false).(bool),
 		forceV6:   cli.SetFlag(flag, "6", false).(bool),
+		flood:     cli.SetFlag(flag, "flood", false).(bool),
+		fmtJSON:   cli.SetFlag(flag, "json", false).(bool),
+		df:        cli.SetFlag(flag, "df", false).(bool),
 		network:   "ip",
 		source:    "",
 		MaxRTT:    time.Second,
 	}
 
+	if p.tos, p.qos, err = qosFlag(flag); err != nil {
+		return nil, err
+	}
+
+	// set the data size, the ICMP header is 8 bytes
+	size := cli.SetFlag(flag, "s", p.pSize-8).(int)
+	if size < 0 || size > maxDataSize {
+		return nil, fmt.Errorf("size should be between 0 and %d", maxDataSize)
+	}
+	p.pSize = size + 8
+
+	if sweep, ok := flag["sweep"]; ok {
+		if p.sweep, err = sweepRange(sweep); err != nil {
+			return nil, err
+		}
+		if p.isCIDR {
+			return nil, fmt.Errorf("the size sweep doesn't support CIDR")
+		}
+	}
+
 	if !p.isCIDR {
 		// resolve host
 		ips, err := net.LookupIP(target)
@@ -84,8 +101,12 @@ func NewPing(args string, cfg cli.Config) (*Ping, error) {
 	if p.timeout, err = time.ParseDuration(timeoutStr); err != nil {
 		return nil, fmt.Errorf("timeout options is not valid")
 	}
-	// set interval
-	intervalStr := cli.SetFlag(flag, "i", cfg.Ping.Interval).(string)
+	// set interval, the flood w/o interval paces by the replies
+	interval := cfg.Ping.Interval
+	if p.flood {
+		interval = "0"
+	}
+	intervalStr := cli.SetFlag(flag, "i", interval).(string)
 	intervalStr = NormalizeDuration(intervalStr)
 	if p.interval, err = time.ParseDuration(intervalStr); err != nil {
 		return nil, fmt.Errorf("interval options is not valid")
@@ -94,25 +115,35 @@ func NewPing(args string, cfg cli.Config) (*Ping, error) {
 	return &p, nil
 }
 
-// Run loops the ping and print it out
-func (p *Ping) Run() chan Response {
-	var r = make(chan Response, 1)
+// Run loops the ping until count reached or the context canceled,
+// all the requests go through one socket
+func (p *Ping) Run(ctx context.Context) chan Response {
+	e, err := p.engine()
+	if err != nil {
+		return errResponse(Response{Error: err, Addr: p.addr.String()})
+	}
+
 	go func() {
+		defer e.close()
 		for n := 0; n < p.count; n++ {
-			p.Ping(r)
-			if n != p.count-1 {
-				time.Sleep(p.interval)
+			p.seq++
+			e.send(p.addr, p.seq, p.payload())
+			if n == p.count-1 {
+				break
+			}
+			if !p.pace(ctx, e) {
+				return
 			}
 		}
-		close(r)
+		e.wait(ctx.Done())
 	}()
-	return r
+	return e.out
 }
 
-func (p *Ping) MRun() chan Response {
+// MRun pings all the hosts of the CIDR until the context canceled,
+// the requests go out back to back through one socket
+func (p *Ping) MRun(ctx context.Context) chan Response {
 	var (
-		r           = make(chan Response, 1000)
-		sigCh       = make(chan os.Signal, 1)
 		_, ipNet, _ = net.ParseCIDR(p.target)
 		ifs, _      = net.Interfaces()
 		skipIPs     = map[string]struct{}{}
@@ -129,34 +160,87 @@ func (p *Ping) MRun() chan Response {
 		}
 	}
 
-	if len(ipNet.IP) == 4 {
-		go func() {
-			// capture interrupt w/ s channel
-			signal.Notify(sigCh, os.Interrupt)
-			defer signal.Stop(sigCh)
-		LOOP:
-			for ip := range walkIPv4(p.target) {
-				// skip local network/broadcast ip addrs
-				if _, ok := skipIPs[ip]; ok {
-					continue
-				}
-				pp := p
-				pp.isV4Avail = true
-				pp.addr = &net.IPAddr{IP: net.ParseIP(ip)}
-				pp.Ping(r)
-				select {
-				case <-sigCh:
-					break LOOP
-				default:
-					continue
-				}
-			}
-			close(r)
-		}()
-	} else {
+	if len(ipNet.IP) != 4 {
 		println("IPv6 doesn't support")
-		close(r)
+		return errResponse()
+	}
+
+	p.isV4Avail, p.isV6Avail = true, false
+	e, err := p.engine()
+	if err != nil {
+		return errResponse(Response{Error: err, Addr: p.target})
+	}
+
+	go func() {
+		defer e.close()
+		for ip := range walkIPv4(ctx, p.target) {
+			// skip local network/broadcast ip addrs
+			if _, ok := skipIPs[ip]; ok {
+				continue
+			}
+			p.seq++
+			e.send(&net.IPAddr{IP: net.ParseIP(ip)}, p.seq, p.payload())
+		}
+		e.wait(ctx.Done())
+	}()
+	return e.out
+}
+
+// engine opens the ping engine w/ the target's address family
+func (p *Ping) engine() (*engine, error) {
+	e, err := newEngine(p.source, p.isV4Avail, p.isV6Avail, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if p.qos {
+		conn := e.conn4
+		if p.isV6Avail {
+			conn = e.conn6
+		}
+		if err := p.setQoS(conn); err != nil {
+			e.close()
+			return nil, err
+		}
+	}
+	if p.df {
+		if err := p.setDontFrag(e); err != nil {
+			e.close()
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// pace waits the interval before the next request, the flood
+// w/o the interval sends the next request once the reply comes
+// back or after 10 ms, it returns false if the context canceled
+func (p *Ping) pace(ctx context.Context, e *engine) bool {
+	var (
+		reply <-chan struct{}
+		wait  = p.interval
+	)
+	if p.flood && p.interval == 0 {
+		reply, wait = e.notify, 10*time.Millisecond
+	}
+	if wait == 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-reply:
+	case <-time.After(wait):
+	}
+	return true
+}
+
+// errResponse returns a closed channel w/ the responses
+func errResponse(rs ...Response) chan Response {
+	r := make(chan Response, len(rs))
+	for _, resp := range rs {
+		r <- resp
 	}
+	close(r)
 	return r
 }
 
@@ -185,20 +269,6 @@ func IsIPv6(ip net.IP) bool {
 	return false
 }
 
-func (p *Ping) parseMessage(m *packet) (*ipv4.Header, *icmp.Message, error) {
-	var proto = ProtocolIPv4ICMP
-	if p.isV6Avail {
-		proto = ProtocolIPv6ICMP
-	}
-	msg, err := icmp.ParseMessage(proto, m.bytes)
-	if err != nil {
-		return nil, nil, err
-	}
-	bytes, _ := msg.Body.Marshal(msg.Type.Protocol())
-	h, err := icmp.ParseIPv4Header(bytes)
-	return h, msg, err
-}
-
 // SetIP set ip address
 func (p *Ping) SetIP(ips []net.IP) error {
 	for _, ip := range ips {
@@ -225,269 +295,174 @@ func (p *Ping) PacketSize(s int) {
 	p.pSize = s
 }
 
-// listen starts to listen incoming icmp
-func (p *Ping) listen(network string) (*icmp.PacketConn, error) {
-	c, err := icmp.ListenPacket(network, p.source)
-	if err != nil {
-		return c, err
-	}
-	return c, nil
-}
-
-// recv reads icmp message
-func (p *Ping) recv(conn *icmp.PacketConn, rcvdChan chan<- *packet) {
-	var (
-		err  error
-		dest net.Addr
-		ts   = time.Now()
-		n    int
-	)
-
-	bytes := make([]byte, 1500)
-	conn.SetReadDeadline(time.Now().Add(p.timeout))
-
-	for {
-		n, dest, err = conn.ReadFrom(bytes)
-		if err != nil {
-			if neterr, ok := err.(*net.OpError); ok {
-				if neterr.Timeout() {
-					err = errors.New("Request timeout")
-				}
-			}
-		}
-
-		bytes = bytes[:n]
-
-		if n > 0 {
-			respID := int(bytes[4])<<8 | int(bytes[5])
-			respSq := int(bytes[6])<<8 | int(bytes[7])
-			if respID == p.id && respSq == p.seq {
-				rcvdChan <- &packet{bytes: bytes, addr: dest, err: err}
-				break
-			} else if time.Since(ts) < p.timeout {
-				continue
-			}
-		}
-
-		if time.Since(ts) < p.timeout {
-			continue
-		}
-
-		err = errors.New("Request timeout")
-		rcvdChan <- &packet{bytes: []byte{}, addr: dest, err: err}
-		break
-	}
-}
-
 func (p *Ping) payload() []byte {
 	timeBytes := make([]byte, 8)
 	ts := time.Now().UnixNano()
 	for i := uint8(0); i < 8; i++ {
 		timeBytes[i] = byte((ts >> (i * 8)) & 0xff)
 	}
+	if p.pSize < 16 {
+		return timeBytes[:p.pSize-8]
+	}
 	payload := make([]byte, p.pSize-16)
 	payload = append(payload, timeBytes...)
 	return payload
 }
-func (p *Ping) send(conn *icmp.PacketConn) {
-	var (
-		wg sync.WaitGroup
-	)
-	var icmpType icmp.Type
-	if IsIPv4(p.addr.IP) {
-		icmpType = ipv4.ICMPTypeEcho
-	} else if IsIPv6(p.addr.IP) {
-		icmpType = ipv6.ICMPTypeEchoRequest
-	}
-
-	p.seq++
-	bytes, err := (&icmp.Message{
-		Type: icmpType, Code: 0,
-		Body: &icmp.Echo{
-			ID:   p.id,
-			Seq:  p.seq,
-			Data: p.payload(),
-		},
-	}).Marshal(nil)
-	if err != nil {
-		println(err.Error())
-	}
 
-	wg.Add(1)
-	go func(conn *icmp.PacketConn, dest net.Addr, b []byte) {
-		defer wg.Done()
-		for {
-			if _, err := conn.WriteTo(bytes, dest); err != nil {
-				println(err.Error())
-				if neterr, ok := err.(*net.OpError); ok {
-					if neterr.Err == syscall.ENOBUFS {
-						continue
-					}
-				}
-			}
-			break
-		}
-	}(conn, p.addr, bytes)
-
-	wg.Wait()
+// setQoS sets the IPv4 TOS or the IPv6 traffic class
+func (p *Ping) setQoS(conn *icmp.PacketConn) error {
+	if p.isV6Avail {
+		return conn.IPv6PacketConn().SetTrafficClass(p.tos)
+	}
+	return conn.IPv4PacketConn().SetTOS(p.tos)
 }
 
-// Ping tries to send and receive a packet
-func (p *Ping) Ping(out chan Response) {
+// setDontFrag sets the DF bit of the IPv4 requests or disables the
+// IPv6 fragmentation, the cached path MTU doesn't shrink the requests
+func (p *Ping) setDontFrag(e *engine) error {
 	var (
-		conn     *icmp.PacketConn
-		err      error
-		addr     string       = p.addr.String()
-		rcvdChan chan *packet = make(chan *packet, 1)
+		pc   net.PacketConn
+		serr error
 	)
-
-	if p.isV4Avail {
-		if conn, err = p.listen("ip4:icmp"); err != nil {
-			out <- Response{Error: err, Addr: addr}
-			return
-		}
-		defer conn.Close()
-	}
-
 	if p.isV6Avail {
-		if conn, err = p.listen("ip6:ipv6-icmp"); err != nil {
-			out <- Response{Error: err, Addr: addr}
-			return
-		}
-		defer conn.Close()
+		pc = e.conn6.IPv6PacketConn().PacketConn
+	} else {
+		pc = e.conn4.IPv4PacketConn().PacketConn
 	}
 
-	p.send(conn)
-	p.recv(conn, rcvdChan)
-	rm := <-rcvdChan
-
-	if rm.err != nil {
-		out <- Response{Error: rm.err, Sequence: p.seq, Addr: addr}
-		return
+	sc, ok := pc.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("the DF bit is not supported")
 	}
-	_, m, err := p.parseMessage(rm)
+	rc, err := sc.SyscallConn()
 	if err != nil {
-		out <- Response{Error: err, Sequence: p.seq, Addr: addr}
-		return
+		return err
 	}
-
-	switch m.Body.(type) {
-	case *icmp.TimeExceeded:
-		out <- Response{Error: fmt.Errorf("time exceeded"), Sequence: p.seq, Addr: addr}
-	case *icmp.PacketTooBig:
-		out <- Response{Error: fmt.Errorf("packet too big"), Sequence: p.seq, Addr: addr}
-	case *icmp.DstUnreach:
-		out <- Response{Error: fmt.Errorf("destination unreachable"), Sequence: p.seq, Addr: addr}
-	case *icmp.Echo:
-		rtt := float64(time.Now().UnixNano()-getTimeStamp(rm.bytes)) / 1000000
-		out <- Response{
-			Size:     len(rm.bytes),
-			Addr:     rm.addr.String(),
-			RTT:      rtt,
-			Sequence: p.seq,
-			Error:    nil,
+	err = rc.Control(func(fd uintptr) {
+		if p.isV6Avail {
+			serr = setIPv6DontFrag(int(fd))
+		} else {
+			serr = setIPv4DontFrag(int(fd))
 		}
-	default:
-		out <- Response{Error: fmt.Errorf("ICMP error"), Sequence: p.seq, Addr: addr}
+	})
+	if err != nil {
+		return err
 	}
+	return serr
 }
 
-// PrintPretty prints out the result pretty format
-func (p *Ping) PrintPretty(resp chan Response) {
+// PrintPretty prints out the result pretty format, the flood
+// prints out a dot per lost packet instead of the replies, it
+// returns error if there isn't any reply
+func (p *Ping) PrintPretty(resp chan Response) error {
 	var (
-		loop          = true
-		sigCh         = make(chan os.Signal, 1)
-		pFmt          = "%d bytes from %s icmp_seq=%d time=%.3f ms"
-		eFmt          = "%s icmp_seq=%d"
-		sFmt          = "%d packets transmitted,  %d packets received, %d%% packet loss\n"
-		msg           string
-		min, max, avg float64
-		c             = map[string]int{"tx": 0, "err": 0, "pl": 0}
+		pFmt = "%d bytes from %s icmp_seq=%d time=%.3f ms%s"
+		eFmt = "%s icmp_seq=%d"
+		sFmt = "%d packets transmitted,  %d packets received,%s %.0f%% packet loss\n"
+		msg  string
+		st   stats.Stats
+		c    = map[string]int{"dup": 0, "ooo": 0, "late": 0}
 	)
 
-	// capture interrupt w/ s channel
-	signal.Notify(sigCh, os.Interrupt)
-	defer signal.Stop(sigCh)
-
-	fmt.Printf("PING %s (%s): %d data bytes\n", p.target, p.addr, p.pSize-8)
-	for loop {
-		select {
-		case r, ok := <-resp:
-			if !ok {
-				loop = false
-				break
-			}
-			c["tx"]++
-			if r.Error != nil {
-				c["err"]++
-				msg = fmt.Sprintf(eFmt, r.Error.Error(), r.Sequence)
-				println(msg)
-				continue
+	if !p.fmtJSON {
+		qos := ""
+		if p.qos {
+			qos = ", " + fmtQoS(p.tos)
+		}
+		if p.df {
+			qos += ", DF"
+		}
+		fmt.Printf("PING %s (%s): %d data bytes%s\n", p.target, p.addr, p.pSize-8, qos)
+	}
+	for r := range resp {
+		switch {
+		case r.Dup:
+			c["dup"]++
+			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT, " (DUP!)")
+		case r.Late:
+			c["late"]++
+			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT, " (late)")
+		case r.Error != nil:
+			st.AddLoss()
+			msg = fmt.Sprintf(eFmt, r.Error.Error(), r.Sequence)
+			if p.flood && !p.fmtJSON {
+				fmt.Print(".")
 			}
-
-			min = Min(r.RTT, min)
-			max = Max(r.RTT, max)
-			avg = Avg(r.RTT, avg)
-
-			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT)
+		case r.OutOfOrder:
+			c["ooo"]++
+			st.Add(r.RTT)
+			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT, " (out of order)")
+		default:
+			st.Add(r.RTT)
+			msg = fmt.Sprintf(pFmt, r.Size, r.Addr, r.Sequence, r.RTT, "")
+		}
+		if !p.fmtJSON && !p.flood {
 			println(msg)
-		case <-sigCh:
-			loop = false
 		}
 	}
 
-	if c["tx"] == 0 {
-		return
+	if st.Sent() == 0 {
+		return nil
 	}
-	// packet loss
-	c["pl"] = c["err"] * 100 / c["tx"]
 
-	fmt.Printf("\n--- %s ping statistics ---\n", p.target)
-	fmt.Printf(sFmt, c["tx"], c["tx"]-c["err"], c["pl"])
+	sum := st.Summary()
 
-	if c["pl"] == 100 {
-		return
+	if p.fmtJSON {
+		p.printJSON(sum, c)
+		return noReply(sum)
 	}
 
-	fmt.Printf("round-trip min/avg/max = %.3f/%.3f/%.3f ms\n", min, avg, max)
-}
+	var extra string
+	for _, e := range []struct {
+		key, format string
+	}{{"dup", " +%d duplicates,"}, {"ooo", " %d out of order,"}, {"late", " %d late,"}} {
+		if c[e.key] > 0 {
+			extra += fmt.Sprintf(e.format, c[e.key])
+		}
+	}
 
-// IsCIDR returns true if target is CIDR
-func (p *Ping) IsCIDR() bool {
-	return p.isCIDR
-}
+	if p.flood {
+		fmt.Println()
+	}
+	fmt.Printf("\n--- %s ping statistics ---\n", p.target)
+	fmt.Printf(sFmt, sum.Sent, sum.Recv, extra, sum.Loss)
 
-// Max handles maximum delay
-func Max(x, y float64) float64 {
-	if x > y {
-		return x
+	if sum.Recv == 0 {
+		return errNoReply
 	}
-	return y
+
+	fmt.Println(sum)
+	return nil
 }
 
-// Min handles minimum delay
-func Min(x, y float64) float64 {
-	if x < y || y == 0 {
-		return x
+// noReply returns error if all the requests lost
+func noReply(sum stats.Summary) error {
+	if sum.Recv == 0 {
+		return errNoReply
 	}
-	return y
+	return nil
 }
 
-// Avg handles average delay
-func Avg(x, y float64) float64 {
-	if y == 0 {
-		return x
+// printJSON prints out the statistics in json format
+func (p *Ping) printJSON(sum stats.Summary, c map[string]int) {
+	b, err := json.Marshal(struct {
+		Host string `json:"host"`
+		Addr string `json:"addr"`
+		stats.Summary
+		Dup        int `json:"dup"`
+		OutOfOrder int `json:"outoforder"`
+		Late       int `json:"late"`
+	}{p.target, p.addr.String(), sum, c["dup"], c["ooo"], c["late"]})
+	if err != nil {
+		println(err.Error())
+		return
 	}
-	return (x + y) / 2
+	fmt.Println(string(b))
 }
 
-// getTimeStamp
-func getTimeStamp(m []byte) int64 {
-	var ts int64
-	for i := uint(0); i < 8; i++ {
-		ts += int64(m[uint(len(m))-8+i]) << (i * 8)
-	}
-	return ts
+// IsCIDR returns true if target is CIDR
+func (p *Ping) IsCIDR() bool {
+	return p.isCIDR
 }
 
 // isCIDR
@@ -506,7 +481,7 @@ func NormalizeDuration(d string) string {
 	return d
 }
 
-func walkIPv4(cidr string) chan string {
+func walkIPv4(ctx context.Context, cidr string) chan string {
 	c := make(chan string, 2048)
 	go func() {
 		defer close(c)
@@ -518,8 +493,8 @@ func walkIPv4(cidr string) chan string {
 		for ip := ip.Mask(ipnet.Mask); ipnet.Contains(ip); nextIP(ip) {
 			select {
 			case c <- ip.String():
-			default:
-				break
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -549,18 +524,43 @@ func help(cfg cli.Config) {
 	fmt.Printf(`
     usage:
           ping IP address / domain name / CIDR [options]
+          ping host1 host2 ... / -f hosts file [options]
     options:
           -c count       Send 'count' requests (default: %d)
           -t timeout     Specify a timeout in format "ms", "s", "m" (default: %s)
           -i interval    Wait interval between sending each packet (default: %s)
           -4             Forces the ping command to use IPv4 (target should be hostname)
           -6             Forces the ping command to use IPv6 (target should be hostname)
+          -tos           Set the IPv4 TOS / IPv6 traffic class byte (0-255)
+          -dscp          Set the DSCP by name (EF, AF41, CS5 ...) or number (0-63)
+          -f file        Ping the hosts of the file (one host per line) concurrently
+          -a             Prints out the alive hosts only (w/ multiple hosts)
+          -u             Prints out the unreachable hosts only (w/ multiple hosts)
+          -json          Prints out the statistics in json format
+          -flood         Sends the next request once the reply comes back or after 10 ms
+                         (or every interval w/ -i) and prints out a dot per lost packet
+          -s size        Specify the number of data bytes to be sent (default: 56)
+          -df            Set the don't fragment (DF) bit, the fragmentation needed
+                         and packet too big print out the next-hop MTU
+          -sweep min-max Find the largest data size that gets through w/ the DF bit
+                         between min and max bytes by binary search
+    Note:
+          w/o the raw socket privileges the ping falls back to the datagram ICMP socket
+          on Linux, it doesn't receive the ICMP errors (fragmentation needed, packet too
+          big and unreachable) so they time out, -df / -sweep need the raw socket
     Example:
           ping 8.8.8.8
           ping 31.13.74.0/24
           ping 8.8.8.8 -c 10
           ping google.com -6
           ping mylg.io -i 5s
+          ping 8.8.8.8 -dscp EF
+          ping 8.8.8.8 -c 20 -json
+          ping 192.168.1.1 -c 10000 -flood
+          ping 10.0.0.1 -s 1472 -df
+          ping 10.0.0.1 -sweep 1400-8972
+          ping 8.8.8.8 1.1.1.1 google.com
+          ping -f /home/user/hosts.txt -c 10 -u
 	`,
 		cfg.Ping.Count,
 		cfg.Ping.Timeout,
@@ -1,10 +1,17 @@
 package icmp_test
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/mehrdadrad/mylg/cli"
 	"github.com/mehrdadrad/mylg/icmp"
+	"io/ioutil"
 	"net"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSetIP(t *testing.T) {
@@ -21,3 +28,128 @@ func TestIsIPvx(t *testing.T) {
 		t.Error("IsIPv4 is false but expected true")
 	}
 }
+
+func TestParseDSCP(t *testing.T) {
+	for s, d := range map[string]int{"EF": 46, "af41": 34, "CS5": 40, "be": 0, "46": 46, "0x2e": 46} {
+		if v, err := icmp.ParseDSCP(s); err != nil || v != d {
+			t.Error("unexpected DSCP", s, v, err)
+		}
+	}
+	for _, s := range []string{"64", "AF44", ""} {
+		if _, err := icmp.ParseDSCP(s); err == nil {
+			t.Error("expected invalid DSCP error", s)
+		}
+	}
+	if icmp.DSCPName(46) != "EF" || icmp.DSCPName(0) != "BE" || icmp.DSCPName(5) != "5" {
+		t.Error("unexpected DSCP name")
+	}
+
+	r := icmp.DSCPRemarks(46<<2, []int{46, 34, 34, 0})
+	if !reflect.DeepEqual(r, []string{"", "DSCP EF re-marked to AF41", "", "DSCP AF41 bleached"}) {
+		t.Error("unexpected remarks", r)
+	}
+
+	cfg, _ := cli.ReadDefaultConfig()
+	if _, err := icmp.NewPing("8.8.8.8 -dscp XX", cfg); err == nil {
+		t.Error("expected invalid DSCP error")
+	}
+}
+
+func TestMultiPingTargets(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# anycast\n192.0.2.1\n\n2001:db8::1  pop2\n192.0.2.1\n")
+	f.Close()
+
+	targets, err := icmp.ParseTargets(f.Name())
+	if err != nil || !reflect.DeepEqual(targets, []string{"192.0.2.1", "2001:db8::1"}) {
+		t.Error("unexpected targets", targets, err)
+	}
+
+	if !icmp.IsMultiPing("-f "+f.Name()) || !icmp.IsMultiPing("192.0.2.1 192.0.2.2 -c 5") || icmp.IsMultiPing("192.0.2.1 -c 5") {
+		t.Error("unexpected multi ping detection")
+	}
+
+	var buf bytes.Buffer
+	icmp.WriteMultiPing(&buf, []icmp.PingTarget{
+		{Host: "a.example", Addr: "192.0.2.1", Sent: 4, Recv: 3, Loss: 25, Min: 1, Avg: 2, Max: 3, StdDev: 0.816, Jitter: 0.125},
+		{Host: "b.example", Err: "unresolved"},
+	})
+	if !strings.Contains(buf.String(), "| a.example | 192.0.2.1  |    4 |    3 |  25.0 | 1.000 | 2.000 | 3.000 |  0.816 |  0.125 |") {
+		t.Error("unexpected table", buf.String())
+	}
+}
+
+func TestEngineReplies(t *testing.T) {
+	rs := icmp.EngineReplies(100*time.Millisecond, 4, []int{1, 3, 2, 2, 0, 4})
+
+	var r []string
+	for _, resp := range rs {
+		switch {
+		case resp.Dup:
+			r = append(r, fmt.Sprintf("%d dup", resp.Sequence))
+		case resp.Late:
+			r = append(r, fmt.Sprintf("%d late", resp.Sequence))
+		case resp.Timeout:
+			r = append(r, fmt.Sprintf("%d timeout", resp.Sequence))
+		case resp.OutOfOrder:
+			r = append(r, fmt.Sprintf("%d ooo", resp.Sequence))
+		default:
+			r = append(r, fmt.Sprintf("%d ok", resp.Sequence))
+		}
+	}
+
+	expected := []string{"0 ok", "2 ok", "1 ooo", "1 dup", "3 timeout", "3 late"}
+	if !reflect.DeepEqual(r, expected) {
+		t.Error("unexpected responses", r)
+	}
+}
+
+func TestSearchSize(t *testing.T) {
+	// the path MTU is 1500 and the router advertises it
+	var sizes []int
+	probe := func(size int) icmp.SizeProbe {
+		sizes = append(sizes, size)
+		if size+28 > 1500 {
+			return icmp.SizeProbe{Size: size, MTU: 1500, Error: "frag needed"}
+		}
+		return icmp.SizeProbe{Size: size}
+	}
+
+	if size, ok := icmp.SearchSize(64, 9000, 28, probe); !ok || size != 1472 {
+		t.Error("expected 1472 but got", size, ok)
+	}
+	if !reflect.DeepEqual(sizes, []int{64, 9000, 1472}) {
+		t.Error("unexpected probes", sizes)
+	}
+
+	// w/o the next-hop MTU
+	probe = func(size int) icmp.SizeProbe {
+		if size > 8972 {
+			return icmp.SizeProbe{Size: size, Error: "timeout"}
+		}
+		return icmp.SizeProbe{Size: size}
+	}
+	if size, ok := icmp.SearchSize(64, 9000, 28, probe); !ok || size != 8972 {
+		t.Error("expected 8972 but got", size, ok)
+	}
+	if _, ok := icmp.SearchSize(9000, 9100, 28, probe); ok {
+		t.Error("expected no reply w/ the minimum size")
+	}
+}
+
+func TestSweepFlags(t *testing.T) {
+	cfg, _ := cli.ReadDefaultConfig()
+	p, err := icmp.NewPing("127.0.0.1 -sweep 64-9000", cfg)
+	if err != nil || !p.IsSweep() {
+		t.Error("expected the size sweep", err)
+	}
+	for _, args := range []string{"127.0.0.1 -sweep 9000-64", "127.0.0.1 -sweep", "127.0.0.1 -s 70000"} {
+		if _, err := icmp.NewPing(args, cfg); err == nil {
+			t.Error("expected error", args)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// mtuPlateaus are the common MTUs (RFC 1191 and overlays) to shrink the
+// probe size once the router doesn't provide the next-hop MTU
+var mtuPlateaus = []int{65535, 32000, 17914, 9000, 8166, 4352, 2002, 1500, 1492,
+	1480, 1476, 1450, 1438, 1400, 1280, 1006, 576, 552, 508, 296, 68}
+
+// PathMTU represents the path MTU discovery result
+type PathMTU struct {
+	Host  string   `json:"host"`
+	IP    string   `json:"ip"`
+	Start int      `json:"start"`
+	MTU   int      `json:"mtu"`
+	Hops  []MTUHop `json:"hops"`
+}
+
+// MTUHop represents a hop's response at path MTU discovery
+type MTUHop struct {
+	HopResp
+	// MTU is the largest probe size that reached the hop
+	MTU int `json:"MTU"`
+	// Reporter is the router that replied fragmentation needed or packet too big
+	Reporter string `json:"Reporter,omitempty"`
+	// BlackHole is true if the oversized DF probes discarded w/o any ICMP
+	BlackHole bool `json:"BlackHole"`
+}
+
+// PathMTU sends the DF probes hop by hop and shrinks the size once
+// a router replies fragmentation needed (packet too big) or the
+// oversized probes discarded silently, it stops at the target
+func (i *Trace) PathMTU(ctx context.Context) (*PathMTU, error) {
+	var (
+		minSize = 68
+		size    = i.maxSize
+	)
+
+	if !IsIPv4(i.ip) {
+		minSize = 1280
+	}
+	if size <= 0 {
+		size = localMTU(i.src)
+	}
+	if size > 65535 {
+		size = 65535
+	}
+	if size < minSize {
+		size = minSize
+	}
+
+	pm := &PathMTU{Host: i.host, IP: i.ip.String(), Start: size}
+
+	if err := i.Bind(); err != nil {
+		return nil, err
+	}
+	defer syscall.Close(i.fd)
+
+	for h := 1; h <= i.maxTTL && ctx.Err() == nil; h++ {
+		hop := MTUHop{HopResp: HopResp{Num: h}}
+		size = i.hopMTU(ctx, &hop, size, minSize)
+		if hop.Err != nil {
+			return pm, hop.Err
+		}
+		if hop.IP != "" {
+			hop.MTU = size
+			if i.ripe {
+				r := []HopResp{hop.HopResp}
+				i.addWhois(r)
+				hop.HopResp = r[0]
+			}
+		}
+		pm.Hops = append(pm.Hops, hop)
+		if hop.Last {
+			break
+		}
+	}
+	pm.MTU = size
+
+	return pm, ctx.Err()
+}
+
+// hopMTU probes the hop until it replies and returns the
+// largest size that reached the hop
+func (i *Trace) hopMTU(ctx context.Context, hop *MTUHop, size, minSize int) int {
+	for n := 0; n < 32 && ctx.Err() == nil; n++ {
+		i.SetTTL(hop.Num)
+		i.pSize = size
+		begin := time.Now()
+
+		id, seq, err := i.Send(33434)
+		if isMsgSize(err) && size > minSize {
+			// the local interface MTU is smaller
+			size = lowerMTU(size, minSize)
+			continue
+		}
+		if err != nil {
+			hop.Err = err
+			return size
+		}
+
+		resp, err := i.Recv(id, seq)
+		elapsed := time.Since(begin)
+
+		if err != nil {
+			// the hop doesn't reply at all or it discards the oversized probes
+			if size == minSize || !i.replies(hop.Num, minSize) {
+				return size
+			}
+			hop.BlackHole = true
+			size = i.searchMTU(ctx, hop.Num, minSize, size)
+			continue
+		}
+
+		if i.tooBig(resp) {
+			hop.Reporter = resp.src.String()
+			if resp.mtu >= minSize && resp.mtu < size {
+				size = resp.mtu
+			} else {
+				size = lowerMTU(size, minSize)
+			}
+			continue
+		}
+
+		hop.HopResp = i.hopResp(hop.Num, resp, elapsed)
+		return size
+	}
+
+	return size
+}
+
+// searchMTU finds the largest size that reaches the hop, the ok
+// size reaches the hop and the bad size is discarded
+func (i *Trace) searchMTU(ctx context.Context, ttl, ok, bad int) int {
+	for bad-ok > 1 && ctx.Err() == nil {
+		mid := (ok + bad) / 2
+		if i.replies(ttl, mid) {
+			ok = mid
+		} else {
+			bad = mid
+		}
+	}
+	return ok
+}
+
+// replies returns true if the hop replies the probe w/ the size
+func (i *Trace) replies(ttl, size int) bool {
+	i.SetTTL(ttl)
+	i.pSize = size
+
+	id, seq, err := i.Send(33434)
+	if err != nil {
+		return false
+	}
+	resp, err := i.Recv(id, seq)
+	return err == nil && !i.tooBig(resp)
+}
+
+// tooBig returns true if the response is ICMP fragmentation
+// needed or ICMPv6 packet too big
+func (i *Trace) tooBig(resp ICMPResp) bool {
+	if IsIPv4(i.ip) {
+		return resp.typ == IPv4ICMPTypeDestinationUnreachable && resp.code == IPv4ICMPCodeFragmentationNeeded
+	}
+	return resp.typ == IPv6ICMPTypePacketTooBig
+}
+
+// PrintPathMTU prints out the hops' MTU and where the MTU dropped
+func (i *Trace) PrintPathMTU(ctx context.Context) {
+	pm, err := i.PathMTU(ctx)
+	if pm == nil {
+		println(err.Error())
+		return
+	}
+
+	fmt.Printf("trace route to %s (%s), %d hops max, path MTU discovery from %d bytes\n", i.host, i.ip, i.maxTTL, pm.Start)
+
+	mtu := pm.Start
+	for _, h := range pm.Hops {
+		if h.IP == "" {
+			fmt.Printf("%-2d *\n", h.Num)
+			continue
+		}
+
+		msg := fmt.Sprintf("%-2d %s mtu %d", h.Num, fmtHops([]HopResp{h.HopResp}, 0), h.MTU)
+		if h.MTU < mtu {
+			msg += fmt.Sprintf(" <- mtu drop %d -> %d", mtu, h.MTU)
+			mtu = h.MTU
+		}
+		if h.Reporter != "" {
+			msg += fmt.Sprintf(", fragmentation needed from %s", h.Reporter)
+		}
+		if h.BlackHole {
+			msg += ", black hole: the oversized DF probes discarded w/o ICMP"
+		}
+		fmt.Println(msg)
+	}
+
+	if err != nil && err != context.Canceled {
+		println(err.Error())
+		return
+	}
+	fmt.Printf("path MTU %d bytes\n", pm.MTU)
+}
+
+// lowerMTU returns the next common MTU less than the size
+func lowerMTU(size, minSize int) int {
+	for _, p := range mtuPlateaus {
+		if p < size && p >= minSize {
+			return p
+		}
+	}
+	return minSize
+}
+
+// localMTU returns the MTU of the interface that has the source address
+func localMTU(src net.IP) int {
+	ifs, _ := net.Interfaces()
+	for _, ifc := range ifs {
+		addrs, _ := ifc.Addrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(src) {
+				return ifc.MTU
+			}
+		}
+	}
+	return 1500
+}
+
+// isMsgSize returns true if the packet is larger than the interface MTU
+func isMsgSize(err error) bool {
+	if oe, ok := err.(*net.OpError); ok {
+		err = oe.Err
+	}
+	if se, ok := err.(*os.SyscallError); ok {
+		err = se.Err
+	}
+	return err == syscall.EMSGSIZE
+}
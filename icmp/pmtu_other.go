@@ -0,0 +1,30 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package icmp
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const ipv6DontFrag = 0x3e
+
+// setIPv6DontFrag disables the fragmentation for the probes
+func setIPv6DontFrag(fd int) error {
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, ipv6DontFrag, 1); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+// setIPv4DontFrag isn't supported, the raw socket w/ the IPv4
+// header sets the DF bit of the trace probes
+func setIPv4DontFrag(fd int) error {
+	return errors.New("the IPv4 DF bit is not supported on this OS")
+}
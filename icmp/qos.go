@@ -0,0 +1,84 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dscpNames are the standard per hop behaviors code points
+var dscpNames = map[string]int{
+	"CS0": 0, "CS1": 8, "CS2": 16, "CS3": 24, "CS4": 32, "CS5": 40, "CS6": 48, "CS7": 56,
+	"AF11": 10, "AF12": 12, "AF13": 14, "AF21": 18, "AF22": 20, "AF23": 22,
+	"AF31": 26, "AF32": 28, "AF33": 30, "AF41": 34, "AF42": 36, "AF43": 38,
+	"EF": 46, "VA": 44, "LE": 1,
+}
+
+// ParseDSCP returns the DSCP value of the name (EF, AF41, CS5 ...)
+// or the number (decimal or hex) between 0 and 63
+func ParseDSCP(s string) (int, error) {
+	s = strings.ToUpper(s)
+	switch s {
+	case "BE", "DF", "DEFAULT":
+		return 0, nil
+	}
+	if d, ok := dscpNames[s]; ok {
+		return d, nil
+	}
+	d, err := strconv.ParseInt(s, 0, 0)
+	if err != nil || d < 0 || d > 63 {
+		return 0, fmt.Errorf("invalid DSCP %s, it should be a name like EF, AF41, CS5 or 0-63", s)
+	}
+	return int(d), nil
+}
+
+// DSCPName returns the DSCP's name if it's a standard code point
+// otherwise the number
+func DSCPName(d int) string {
+	if d == 0 {
+		return "BE"
+	}
+	for name, v := range dscpNames {
+		if v == d {
+			return name
+		}
+	}
+	return strconv.Itoa(d)
+}
+
+// qosFlag returns the IPv4 TOS / IPv6 traffic class byte from
+// the -tos (0-255) or -dscp (name or 0-63) flags if any
+func qosFlag(flag map[string]interface{}) (int, bool, error) {
+	if v, ok := flag["tos"]; ok {
+		tos, err := strconv.ParseInt(fmt.Sprint(v), 0, 0)
+		if err != nil || tos < 0 || tos > 255 {
+			return 0, false, fmt.Errorf("invalid TOS %v, it should be 0-255", v)
+		}
+		return int(tos), true, nil
+	}
+	if v, ok := flag["dscp"]; ok {
+		d, err := ParseDSCP(fmt.Sprint(v))
+		if err != nil {
+			return 0, false, err
+		}
+		return d << 2, true, nil
+	}
+	return 0, false, nil
+}
+
+// fmtQoS formats the TOS byte's DSCP
+func fmtQoS(tos int) string {
+	return fmt.Sprintf("DSCP %s (tos 0x%02x)", DSCPName(tos>>2), tos)
+}
+
+// fmtRemark formats the DSCP change between two hops
+func fmtRemark(from, to int) string {
+	if to == 0 {
+		return fmt.Sprintf("DSCP %s bleached", DSCPName(from))
+	}
+	return fmt.Sprintf("DSCP %s re-marked to %s", DSCPName(from), DSCPName(to))
+}
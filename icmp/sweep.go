@@ -0,0 +1,236 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxDataSize is the largest ICMP data size of an IPv4 packet
+	maxDataSize = 65535 - 20 - 8
+	// sweepTries is the number of the requests per size that timed out
+	sweepTries = 2
+)
+
+// SizeSweep represents the DF size sweep result, the sizes are the
+// ICMP data bytes (as the -s) and the MTU is the IP packet size
+type SizeSweep struct {
+	Host string `json:"host"`
+	Addr string `json:"addr"`
+	Min  int    `json:"min"`
+	Max  int    `json:"max"`
+	// Size is the largest data size that got through
+	Size int `json:"size"`
+	MTU  int `json:"mtu"`
+	// NextHopMTU is the lowest advertised next-hop MTU if there is any
+	NextHopMTU int         `json:"nexthopmtu"`
+	Probes     []SizeProbe `json:"probes"`
+}
+
+// SizeProbe represents a size's response at the sweep
+type SizeProbe struct {
+	Size  int     `json:"size"`
+	RTT   float64 `json:"rtt"`
+	MTU   int     `json:"mtu,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// IsSweep returns true if the ping is the size sweep
+func (p *Ping) IsSweep() bool {
+	return p.sweep[1] > 0
+}
+
+// Sweep finds the largest size between the sweep range that gets
+// through w/ the DF bit by binary search, the advertised next-hop
+// MTU shortens the search, fn calls back per size if it's not nil
+func (p *Ping) Sweep(ctx context.Context, fn func(SizeProbe)) (*SizeSweep, error) {
+	p.df = true
+	e, err := p.engine()
+	if err != nil {
+		return nil, err
+	}
+	defer e.close()
+
+	sw := &SizeSweep{
+		Host: p.target,
+		Addr: p.addr.String(),
+		Min:  p.sweep[0],
+		Max:  p.sweep[1],
+	}
+
+	size, ok := searchSize(sw.Min, sw.Max, p.headerLen(), func(size int) SizeProbe {
+		if ctx.Err() != nil {
+			return SizeProbe{Size: size, Error: ctx.Err().Error()}
+		}
+		r := p.sizeProbe(ctx, e, size)
+		if r.MTU > 0 && (sw.NextHopMTU == 0 || r.MTU < sw.NextHopMTU) {
+			sw.NextHopMTU = r.MTU
+		}
+		sw.Probes = append(sw.Probes, r)
+		if fn != nil {
+			fn(r)
+		}
+		return r
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !ok {
+		return sw, fmt.Errorf("no reply w/ the minimum size %d", sw.Min)
+	}
+
+	sw.Size, sw.MTU = size, size+p.headerLen()
+	return sw, nil
+}
+
+// searchSize returns the largest size that the probe gets through
+// and false if the min size doesn't, hdr is the IP and ICMP headers
+// length to convert the advertised next-hop MTU to the size
+func searchSize(min, max, hdr int, probe func(int) SizeProbe) (int, bool) {
+	if r := probe(min); r.Error != "" {
+		return 0, false
+	}
+
+	ok, bad, next := min, max+1, max
+	for bad-ok > 1 {
+		r := probe(next)
+		if r.Error == "" {
+			ok = next
+		} else {
+			bad = next
+		}
+		next = ok + (bad-ok)/2
+		// the larger sizes than the next-hop MTU don't get through
+		if s := r.MTU - hdr; r.MTU > 0 && s >= ok && s < bad {
+			bad, next = s+1, s
+		}
+	}
+
+	return ok, true
+}
+
+// sizeProbe sends the DF requests w/ the size until any response
+// but the timeout comes back or the tries run out
+func (p *Ping) sizeProbe(ctx context.Context, e *engine, size int) SizeProbe {
+	var r = SizeProbe{Size: size}
+
+	p.pSize = size + 8
+	for n := 0; n < sweepTries; n++ {
+		p.seq++
+		e.send(p.addr, p.seq, p.payload())
+		resp, ok := p.response(ctx, e)
+		if !ok {
+			r.Error = ctx.Err().Error()
+			return r
+		}
+		switch {
+		case resp.Timeout:
+			r.Error = resp.Error.Error()
+			continue
+		case isMsgSize(resp.Error):
+			r.Error = "message too long, larger than the local interface MTU"
+		case resp.Error != nil:
+			r.Error, r.MTU = resp.Error.Error(), resp.MTU
+		default:
+			r.RTT = resp.RTT
+		}
+		return r
+	}
+
+	return r
+}
+
+// response returns the current request's response, it returns
+// false if the context canceled
+func (p *Ping) response(ctx context.Context, e *engine) (Response, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Response{}, false
+		case r := <-e.out:
+			if r.Sequence == p.seq && !r.Dup && !r.Late {
+				return r, true
+			}
+		}
+	}
+}
+
+// headerLen returns the IP and ICMP headers length
+func (p *Ping) headerLen() int {
+	if p.isV6Avail {
+		return 40 + 8
+	}
+	return 20 + 8
+}
+
+// PrintSweep prints out the sizes' responses and the largest
+// size that gets through w/ the DF bit, it returns the error
+// once the sweep failed
+func (p *Ping) PrintSweep(ctx context.Context) error {
+	var fn func(SizeProbe)
+
+	if !p.fmtJSON {
+		fmt.Printf("PING %s (%s): DF size sweep %d-%d data bytes\n", p.target, p.addr, p.sweep[0], p.sweep[1])
+		fn = func(r SizeProbe) {
+			if r.Error != "" {
+				fmt.Printf("%d bytes: %s\n", r.Size, r.Error)
+				return
+			}
+			fmt.Printf("%d bytes: reply time=%.3f ms\n", r.Size, r.RTT)
+		}
+	}
+
+	sw, err := p.Sweep(ctx, fn)
+	if err != nil {
+		println(err.Error())
+		return err
+	}
+
+	if p.fmtJSON {
+		b, err := json.Marshal(sw)
+		if err != nil {
+			println(err.Error())
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Printf("\n--- %s size sweep ---\n", p.target)
+	fmt.Printf("%d sizes probed, largest size w/ DF %d data bytes, path MTU %d bytes\n", len(sw.Probes), sw.Size, sw.MTU)
+	if sw.NextHopMTU > 0 {
+		fmt.Printf("fragmentation needed, lowest advertised next-hop MTU %d bytes\n", sw.NextHopMTU)
+	}
+	return nil
+}
+
+// sweepRange parses the sweep flag's min-max sizes
+func sweepRange(v interface{}) ([2]int, error) {
+	var (
+		r   [2]int
+		err error
+	)
+
+	s, ok := v.(string)
+	parts := strings.Split(s, "-")
+	if !ok || len(parts) != 2 {
+		return r, fmt.Errorf("the sweep range is not valid, e.g. -sweep 64-9000")
+	}
+	for i := range parts {
+		if r[i], err = strconv.Atoi(parts[i]); err != nil {
+			return r, fmt.Errorf("the sweep range is not valid, e.g. -sweep 64-9000")
+		}
+	}
+	if r[0] >= r[1] || r[1] > maxDataSize {
+		return r, fmt.Errorf("the sweep range should be min-max up to %d", maxDataSize)
+	}
+
+	return r, nil
+}
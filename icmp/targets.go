@@ -0,0 +1,92 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// maxTargets limits the targets that a CIDR expands to
+const maxTargets = 1024
+
+// ParseTargets returns the targets from the file (one target per
+// line), the CIDRs or the hosts
+func ParseTargets(args string) ([]string, error) {
+	var (
+		targets []string
+		fields  = strings.Fields(args)
+	)
+
+	if len(fields) == 1 {
+		if fi, err := os.Stat(fields[0]); err == nil && !fi.IsDir() {
+			f, err := os.Open(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+
+			fields = fields[:0]
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				fields = append(fields, strings.Fields(line)[0])
+			}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if !isCIDR(f) {
+			targets = appendUniq(targets, f)
+			continue
+		}
+		ip, ipNet, _ := net.ParseCIDR(f)
+		ones, bits := ipNet.Mask.Size()
+		if bits-ones > 10 {
+			return nil, fmt.Errorf("%s has more than %d addresses", f, maxTargets)
+		}
+		for ip = ip.Mask(ipNet.Mask); ipNet.Contains(ip); nextIP(ip) {
+			// skip the network and broadcast addresses
+			if ip.To4() != nil && bits-ones > 1 && (ip.Equal(ipNet.IP) || isBroadcast(ip, ipNet)) {
+				continue
+			}
+			targets = appendUniq(targets, ip.String())
+		}
+	}
+
+	if len(targets) > maxTargets {
+		return nil, fmt.Errorf("too many targets, the maximum is %d", maxTargets)
+	}
+
+	return targets, nil
+}
+
+func isBroadcast(ip net.IP, ipNet *net.IPNet) bool {
+	ip4 := ip.To4()
+	for n := range ip4 {
+		if ip4[n]|ipNet.Mask[len(ipNet.Mask)-4+n] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUniq(s []string, v string) []string {
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
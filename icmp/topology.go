@@ -0,0 +1,510 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// Topology represents the merged router level graph of the
+// traces to the multiple targets
+type Topology struct {
+	Start   time.Time    `json:"start"`
+	Count   int          `json:"count"`
+	Targets []TopoTarget `json:"targets"`
+	Nodes   []*TopoNode  `json:"nodes"`
+	Edges   []*TopoEdge  `json:"edges"`
+
+	nodes map[string]*TopoNode
+	edges map[[2]string]*TopoEdge
+	mu    sync.Mutex
+}
+
+// TopoTarget represents a traced target
+type TopoTarget struct {
+	Host    string `json:"host"`
+	IP      string `json:"ip"`
+	Src     string `json:"src"`
+	Hops    int    `json:"hops"`
+	Reached bool   `json:"reached"`
+	Err     string `json:"err,omitempty"`
+}
+
+// TopoNode represents a router, source or target at the topology
+type TopoNode struct {
+	IP      string   `json:"ip"`
+	Name    string   `json:"name,omitempty"`
+	ASN     float64  `json:"asn"`
+	Holder  string   `json:"holder,omitempty"`
+	Hop     int      `json:"hop"`
+	Avg     float64  `json:"avg"`
+	Best    float64  `json:"best"`
+	Wrst    float64  `json:"wrst"`
+	Source  bool     `json:"source,omitempty"`
+	Target  bool     `json:"target,omitempty"`
+	Targets []string `json:"targets"`
+
+	rtts []float64
+}
+
+// TopoEdge represents the link between two nodes, the gap is the
+// number of the hops w/o response in between
+type TopoEdge struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Count   int      `json:"count"`
+	Gap     int      `json:"gap,omitempty"`
+	Targets []string `json:"targets"`
+}
+
+// NewTopology creates an empty topology
+func NewTopology() *Topology {
+	return &Topology{
+		Start: time.Now(),
+		nodes: make(map[string]*TopoNode),
+		edges: make(map[[2]string]*TopoEdge),
+	}
+}
+
+// AddPath merges a trace round's hops to the target, the hops
+// should be in order from the source
+func (tp *Topology) AddPath(target, src string, hops []HopResp) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	var (
+		prev = tp.node(src, target, 0)
+		gap  int
+	)
+	prev.Source = true
+
+	for _, h := range hops {
+		if h.IP == "" {
+			gap++
+			continue
+		}
+		n := tp.node(h.IP, target, h.Num)
+		n.rtts = append(n.rtts, h.Elapsed)
+		if n.Name == "" && h.Name != h.IP {
+			n.Name = h.Name
+		}
+		if n.ASN == 0 && h.ASN != 0 {
+			n.ASN, n.Holder = h.ASN, holderName(h.Holder)
+		}
+		if h.Last {
+			n.Target = true
+		}
+		if n != prev {
+			tp.edge(prev.IP, n.IP, target, gap)
+		}
+		prev, gap = n, 0
+		if h.Last {
+			break
+		}
+	}
+}
+
+// node returns the existing node or adds the new one
+func (tp *Topology) node(ip, target string, hop int) *TopoNode {
+	n, ok := tp.nodes[ip]
+	if !ok {
+		n = &TopoNode{IP: ip, Hop: hop}
+		tp.nodes[ip] = n
+		tp.Nodes = append(tp.Nodes, n)
+	}
+	if hop < n.Hop {
+		n.Hop = hop
+	}
+	n.Targets = appendUniq(n.Targets, target)
+	return n
+}
+
+func (tp *Topology) edge(from, to, target string, gap int) {
+	k := [2]string{from, to}
+	e, ok := tp.edges[k]
+	if !ok {
+		e = &TopoEdge{From: from, To: to, Gap: gap}
+		tp.edges[k] = e
+		tp.Edges = append(tp.Edges, e)
+	}
+	if gap < e.Gap {
+		e.Gap = gap
+	}
+	e.Count++
+	e.Targets = appendUniq(e.Targets, target)
+}
+
+// AddTarget adds the target's trace result
+func (tp *Topology) AddTarget(t TopoTarget) {
+	tp.mu.Lock()
+	tp.Targets = append(tp.Targets, t)
+	tp.mu.Unlock()
+}
+
+// calc calculates the nodes' round trip times and sorts the
+// nodes by hop and the targets by host
+func (tp *Topology) calc() {
+	for _, n := range tp.Nodes {
+		var sum float64
+		for _, rtt := range n.rtts {
+			sum += rtt
+			n.Best = min(n.Best, rtt)
+			n.Wrst = max(n.Wrst, rtt)
+		}
+		if len(n.rtts) > 0 {
+			n.Avg = sum / float64(len(n.rtts))
+		}
+		sort.Strings(n.Targets)
+	}
+	for _, e := range tp.Edges {
+		sort.Strings(e.Targets)
+	}
+	sort.SliceStable(tp.Nodes, func(a, b int) bool { return tp.Nodes[a].Hop < tp.Nodes[b].Hop })
+	sort.Slice(tp.Targets, func(a, b int) bool { return tp.Targets[a].Host < tp.Targets[b].Host })
+}
+
+// addWhois looks up the nodes w/o ASN
+func (tp *Topology) addWhois(workers int) {
+	var (
+		wg sync.WaitGroup
+		c  = make(chan *TopoNode)
+	)
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range c {
+				if w, err := ipWhois(node.IP); err == nil {
+					node.ASN, node.Holder = w.ASN, holderName(w.Holder)
+				}
+			}
+		}()
+	}
+	for _, node := range tp.Nodes {
+		if node.ASN == 0 && !node.Source {
+			c <- node
+		}
+	}
+	close(c)
+	wg.Wait()
+}
+
+// label returns the node's DOT and GraphML label
+func (n *TopoNode) label() string {
+	var l []string
+	if n.Source {
+		l = append(l, "source")
+	}
+	if n.Name != "" {
+		l = append(l, n.Name)
+	}
+	l = append(l, n.IP)
+	if n.ASN != 0 {
+		l = append(l, strings.TrimSpace(fmtASN(n.ASN, n.Holder)))
+	}
+	if len(n.rtts) > 0 {
+		l = append(l, fmt.Sprintf("%.1f ms", n.Avg))
+	}
+	return strings.Join(l, "\n")
+}
+
+// DOT writes the topology in Graphviz DOT format, the nodes
+// of each AS are at the same cluster
+func (tp *Topology) DOT(w io.Writer) error {
+	var (
+		b        bytes.Buffer
+		clusters = make(map[float64][]*TopoNode)
+		asns     []float64
+	)
+
+	b.WriteString("digraph mylg {\n  rankdir=LR;\n  node [shape=box, fontsize=10];\n  edge [fontsize=9];\n")
+	for _, n := range tp.Nodes {
+		if n.ASN == 0 {
+			b.WriteString("  " + dotNode(n))
+			continue
+		}
+		if _, ok := clusters[n.ASN]; !ok {
+			asns = append(asns, n.ASN)
+		}
+		clusters[n.ASN] = append(clusters[n.ASN], n)
+	}
+	for _, asn := range asns {
+		fmt.Fprintf(&b, "  subgraph \"cluster_AS%.0f\" {\n    label=%q;\n    style=dashed;\n",
+			asn, strings.TrimSpace(fmtASN(asn, clusters[asn][0].Holder)))
+		for _, n := range clusters[asn] {
+			b.WriteString("    " + dotNode(n))
+		}
+		b.WriteString("  }\n")
+	}
+	for _, e := range tp.Edges {
+		attrs := fmt.Sprintf("label=\"%d\"", len(e.Targets))
+		if e.Gap > 0 {
+			attrs += fmt.Sprintf(", style=dashed, tooltip=\"%d hops w/o response\"", e.Gap)
+		}
+		fmt.Fprintf(&b, "  %q -> %q [%s];\n", e.From, e.To, attrs)
+	}
+	b.WriteString("}\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func dotNode(n *TopoNode) string {
+	var shape string
+	switch {
+	case n.Source:
+		shape = ", shape=ellipse"
+	case n.Target:
+		shape = ", shape=doubleoctagon"
+	}
+	return fmt.Sprintf("%q [label=%q%s];\n", n.IP, n.label(), shape)
+}
+
+// GraphML writes the topology in GraphML format
+func (tp *Topology) GraphML(w io.Writer) error {
+	var b bytes.Buffer
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, k := range [][4]string{
+		{"label", "node", "label", "string"}, {"name", "node", "name", "string"},
+		{"asn", "node", "asn", "long"}, {"holder", "node", "holder", "string"},
+		{"hop", "node", "hop", "int"}, {"avg", "node", "avg", "double"},
+		{"best", "node", "best", "double"}, {"wrst", "node", "wrst", "double"},
+		{"type", "node", "type", "string"}, {"targets", "node", "targets", "string"},
+		{"count", "edge", "count", "int"}, {"gap", "edge", "gap", "int"},
+		{"etargets", "edge", "targets", "string"},
+	} {
+		fmt.Fprintf(&b, `  <key id="%s" for="%s" attr.name="%s" attr.type="%s"/>`+"\n", k[0], k[1], k[2], k[3])
+	}
+	b.WriteString(`  <graph id="mylg" edgedefault="directed">` + "\n")
+	for _, n := range tp.Nodes {
+		typ := "router"
+		if n.Source {
+			typ = "source"
+		} else if n.Target {
+			typ = "target"
+		}
+		fmt.Fprintf(&b, `    <node id="%s">`+"\n", xmlEscape(n.IP))
+		for _, d := range [][2]string{
+			{"label", n.label()}, {"name", n.Name}, {"asn", fmt.Sprintf("%.0f", n.ASN)},
+			{"holder", n.Holder}, {"hop", fmt.Sprint(n.Hop)}, {"avg", fmt.Sprintf("%.3f", n.Avg)},
+			{"best", fmt.Sprintf("%.3f", n.Best)}, {"wrst", fmt.Sprintf("%.3f", n.Wrst)},
+			{"type", typ}, {"targets", strings.Join(n.Targets, " ")},
+		} {
+			fmt.Fprintf(&b, `      <data key="%s">%s</data>`+"\n", d[0], xmlEscape(d[1]))
+		}
+		b.WriteString("    </node>\n")
+	}
+	for _, e := range tp.Edges {
+		fmt.Fprintf(&b, `    <edge source="%s" target="%s">`+"\n", xmlEscape(e.From), xmlEscape(e.To))
+		fmt.Fprintf(&b, `      <data key="count">%d</data>`+"\n", e.Count)
+		fmt.Fprintf(&b, `      <data key="gap">%d</data>`+"\n", e.Gap)
+		fmt.Fprintf(&b, `      <data key="etargets">%s</data>`+"\n", xmlEscape(strings.Join(e.Targets, " ")))
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// JSON writes the topology in JSON format
+func (tp *Topology) JSON(w io.Writer) error {
+	b, err := json.MarshalIndent(tp, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// traceTopo traces the target for the count rounds and merges
+// the rounds to the topology
+func (tp *Topology) traceTopo(ctx context.Context, target string, flag map[string]interface{}, cfg cli.Config) {
+	var (
+		t    = TopoTarget{Host: target}
+		hops []HopResp
+	)
+
+	defer func() { tp.AddTarget(t) }()
+
+	i, err := newTrace(target, flag, cfg)
+	if err != nil {
+		t.Err = err.Error()
+		return
+	}
+	t.IP, t.Src = i.ip.String(), i.src.String()
+
+	resp, err := i.MRun(ctx)
+	if err != nil {
+		t.Err = err.Error()
+		return
+	}
+
+	for r := range resp {
+		if r.Err != nil {
+			t.Err = r.Err.Error()
+			return
+		}
+		// the next round
+		if len(hops) > 0 && r.Num <= hops[len(hops)-1].Num {
+			tp.AddPath(target, t.Src, hops)
+			hops = hops[:0]
+		}
+		hops = append(hops, r)
+		if r.Last {
+			t.Reached = true
+			t.Hops = r.Num
+		}
+	}
+	if len(hops) > 0 {
+		tp.AddPath(target, t.Src, hops)
+	}
+}
+
+// RunTopology traces the targets concurrently w/ the trace flags
+// and returns the merged topology
+func RunTopology(ctx context.Context, targets []string, flag map[string]interface{}, cfg cli.Config, workers int) *Topology {
+	var (
+		tp = NewTopology()
+		wg sync.WaitGroup
+		c  = make(chan string)
+	)
+
+	tp.Count = cli.SetFlag(flag, "c", 3).(int)
+	if tp.Count < 1 {
+		tp.Count = 3
+	}
+
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range c {
+				// each trace sets its own flags
+				f := make(map[string]interface{}, len(flag)+1)
+				for k, v := range flag {
+					f[k] = v
+				}
+				f["c"] = tp.Count
+				tp.traceTopo(ctx, target, f, cfg)
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		select {
+		case c <- target:
+		case <-ctx.Done():
+		}
+	}
+	close(c)
+	wg.Wait()
+
+	if cli.SetFlag(flag, "nr", true).(bool) && ctx.Err() == nil {
+		tp.addWhois(workers)
+	}
+	tp.calc()
+
+	return tp
+}
+
+// PrintTopology traces the targets and writes the merged topology
+// (trace topo file / CIDR / hosts [options])
+func PrintTopology(ctx context.Context, args string, cfg cli.Config) {
+	target, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok || target == "" {
+		helpTraceTopo()
+		return
+	}
+
+	var (
+		workers = cli.SetFlag(flag, "W", 10).(int)
+		output  = cli.SetFlag(flag, "o", "").(string)
+		w       io.Writer
+		err     error
+	)
+
+	targets, err := ParseTargets(target)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	fmt.Printf("trace topo %d targets, %d at a time, please wait ...\n", len(targets), workers)
+	tp := RunTopology(ctx, targets, flag, cfg, workers)
+	for _, t := range tp.Targets {
+		if t.Err != "" {
+			fmt.Printf("%s: %s\n", t.Host, t.Err)
+		}
+	}
+
+	w = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch {
+	case cli.SetFlag(flag, "graphml", false).(bool):
+		err = tp.GraphML(w)
+	case cli.SetFlag(flag, "json", false).(bool):
+		err = tp.JSON(w)
+	default:
+		err = tp.DOT(w)
+	}
+
+	if err != nil {
+		println(err.Error())
+	} else if output != "" {
+		fmt.Printf("%d nodes and %d edges saved to %s\n", len(tp.Nodes), len(tp.Edges), output)
+	}
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func helpTraceTopo() {
+	fmt.Println(`
+    usage:
+          trace topo file / CIDR / hosts [options]
+    options:
+          -c             Set the number of rounds per target (default 3)
+          -W             Set the number of concurrent traces (default 10)
+          -o             Writes the topology to the file instead of stdout
+          -dot           Exports in Graphviz DOT format (default)
+          -graphml       Exports in GraphML format
+          -json          Exports in JSON format
+          -u, -t, -n, -nr, -m, -w, -4, -6 are the same as trace
+    Example:
+          trace topo /home/user/pops.txt -o /tmp/pops.dot
+          trace topo 192.0.2.0/28 -graphml -o /tmp/net.graphml
+          trace topo google.com facebook.com -json
+	`)
+}
@@ -6,12 +6,13 @@ package icmp
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net"
 	"os"
-	"os/signal"
 	"sort"
 	"strings"
 	"sync"
@@ -19,9 +20,11 @@ import (
 	"time"
 
 	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 
 	"github.com/mehrdadrad/mylg/cli"
-	"github.com/mehrdadrad/mylg/ripe"
+	"github.com/mehrdadrad/mylg/geo"
+	"github.com/mehrdadrad/mylg/whois"
 )
 
 // TCPOption represents TCP option
@@ -36,19 +39,24 @@ type MHopResp []HopResp
 
 // NewTrace creates new trace object
 func NewTrace(args string, cfg cli.Config) (*Trace, error) {
+	target, flag := cli.Flag(args)
+	// show help
+	if _, ok := flag["help"]; ok || len(target) < 3 {
+		helpTrace()
+		return nil, nil
+	}
+	return newTrace(target, flag, cfg)
+}
+
+// newTrace creates the trace object based on the parsed flags
+func newTrace(target string, flag map[string]interface{}, cfg cli.Config) (*Trace, error) {
 	var (
 		family    int
 		proto     int
 		ip, lAddr net.IP
 	)
-	target, flag := cli.Flag(args)
 	forceIPv4 := cli.SetFlag(flag, "4", false).(bool)
 	forceIPv6 := cli.SetFlag(flag, "6", false).(bool)
-	// show help
-	if _, ok := flag["help"]; ok || len(target) < 3 {
-		helpTrace()
-		return nil, nil
-	}
 	ips, err := net.LookupIP(target)
 	if err != nil {
 		return nil, err
@@ -102,7 +110,37 @@ func NewTrace(args string, cfg cli.Config) (*Trace, error) {
 		maxTTL:   cli.SetFlag(flag, "m", 30).(int),
 		count:    cli.SetFlag(flag, "c", -1).(int),
 		report:   cli.SetFlag(flag, "R", false).(bool),
+		save:     cli.SetFlag(flag, "save", false).(bool),
+		geo:      cli.SetFlag(flag, "geo", false).(bool),
 		km:       cli.SetFlag(flag, "km", false).(bool),
+
+		fmtJSON: cli.SetFlag(flag, "json", false).(bool),
+		fmtCSV:  cli.SetFlag(flag, "csv", false).(bool),
+
+		multipath: cli.SetFlag(flag, "mp", false).(bool),
+		maxFlows:  cli.SetFlag(flag, "mf", 32).(int),
+
+		tracebox: cli.SetFlag(flag, "tb", false).(bool),
+
+		pmtu:    cli.SetFlag(flag, "pmtu", false).(bool),
+		maxSize: cli.SetFlag(flag, "p", 0).(int),
+	}
+
+	if t.tos, t.qos, err = qosFlag(flag); err != nil {
+		return nil, err
+	}
+
+	if t.pmtu && t.tcp {
+		return nil, fmt.Errorf("path MTU discovery supports ICMP and UDP only")
+	}
+
+	if t.tracebox && !IsIPv4(ip) {
+		return nil, fmt.Errorf("tracebox supports IPv4 only")
+	}
+
+	// the history and the reverse trace keep the reports
+	if _, ok := flag["rev"]; ok || t.save {
+		t.report = true
 	}
 
 	// default report's count
@@ -113,7 +151,7 @@ func NewTrace(args string, cfg cli.Config) (*Trace, error) {
 
 func (h MHopResp) Len() int           { return len(h) }
 func (h MHopResp) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
-func (h MHopResp) Less(i, j int) bool { return len(h[i].ip) > len(h[j].ip) }
+func (h MHopResp) Less(i, j int) bool { return len(h[i].IP) > len(h[j].IP) }
 
 // SetTTL set the IP packat time to live
 func (i *Trace) SetTTL(ttl int) {
@@ -163,12 +201,27 @@ func (i *Trace) Send(port int) (int, int, error) {
 			Addr:   b,
 		}
 
-		m, err := icmpV6Message(os.Getpid()&0xffff, seq, i.pSize)
+		var m []byte
+		if i.multipath {
+			m, err = icmpFlowMessage(ipv6.ICMPTypeEchoRequest, os.Getpid()&0xffff, seq, i.flow, i.pSize, false)
+		} else {
+			m, err = icmpV6Message(os.Getpid()&0xffff, seq, i.pSize)
+		}
 		if err != nil {
 			return id, seq, err
 		}
 
 		setIPv6HopLimit(fd, i.ttl)
+		if i.qos {
+			if err := setIPv6TrafficClass(fd, i.tos); err != nil {
+				return id, seq, err
+			}
+		}
+		if i.pmtu {
+			if err := setIPv6DontFrag(fd); err != nil {
+				return id, seq, err
+			}
+		}
 
 		if err := syscall.Sendto(fd, m, 0, &addr); err != nil {
 			return id, seq, err
@@ -186,16 +239,25 @@ func (i *Trace) Sendv4(id, seq, rport int) error {
 	)
 
 	switch {
+	case i.icmp && i.multipath:
+		proto = 1 // icmp v4
+		b, _ = icmpFlowMessage(ipv4.ICMPTypeEcho, os.Getpid()&0xffff, seq, i.flow, i.pSize, true)
 	case i.icmp:
 		proto = 1 // icmp v4
 		b, _ = icmpV4Message(os.Getpid()&0xffff, seq, i.pSize)
 	case i.udp:
 		proto = 17 // udp
 		lport = 64000 + rand.Intn(3)*100
+		if i.multipath {
+			lport = flowPort(i.flow)
+		}
 		b = udpMessage(lport, rport, i.pSize, true)
 	case i.tcp:
 		proto = 6 // tcp
-		b = tcpMessage(0, 33434, 64, true)
+		if i.multipath {
+			lport = flowPort(i.flow)
+		}
+		b = tcpMessage(uint16(lport), 33434, 64, true)
 		setTCPCheckSum(i.src, i.ip, b)
 	}
 
@@ -216,10 +278,19 @@ func (i *Trace) Sendv4(id, seq, rport int) error {
 		TotalLen: ipv4.HeaderLen + len(b),
 		Protocol: proto,
 		ID:       id,
+		TOS:      i.tos,
 		TTL:      i.ttl,
 		Dst:      i.ip.To4(),
 	}
 
+	if i.pmtu {
+		h.Flags = ipv4.DontFragment
+	}
+
+	if i.tracebox {
+		i.sent = ipv4Packet(h, i.src, b)
+	}
+
 	if err := p.WriteTo(h, b, nil); err != nil {
 		return err
 	}
@@ -296,7 +367,7 @@ func (i *Trace) Bind() error {
 // Recv gets the replied icmp packet
 func (i *Trace) Recv(id, seq int) (ICMPResp, error) {
 	var (
-		b    = make([]byte, 512)
+		buf  = make([]byte, 512)
 		ts   = time.Now()
 		resp ICMPResp
 		wID  bool
@@ -306,7 +377,7 @@ func (i *Trace) Recv(id, seq int) (ICMPResp, error) {
 	)
 
 	for {
-		n, from, err := syscall.Recvfrom(i.fd, b, 0)
+		n, from, err := syscall.Recvfrom(i.fd, buf, 0)
 
 		if err != nil {
 			du, _ := time.ParseDuration(i.wait)
@@ -316,13 +387,16 @@ func (i *Trace) Recv(id, seq int) (ICMPResp, error) {
 			return resp, err
 		}
 
-		b = b[:n]
+		b := buf[:n]
 
 		if len(i.ip.To4()) == net.IPv4len {
 			resp = icmpV4RespParser(b)
 			wID = resp.typ == IPv4ICMPTypeEchoReply && id != resp.id
 			wSeq = seq != resp.seq
 			wDst = resp.ip.dst.String() != i.ip.String()
+			if resp.typ == IPv4ICMPTypeEchoReply {
+				wDst = !resp.src.Equal(i.ip)
+			}
 			wV4 = (id != resp.ip.id && resp.ip.id != 0)
 		} else {
 			resp = icmpV6RespParser(b)
@@ -330,10 +404,14 @@ func (i *Trace) Recv(id, seq int) (ICMPResp, error) {
 			wID = resp.typ == IPv6ICMPTypeEchoReply && id != resp.id
 			wSeq = seq != resp.seq
 			wDst = resp.ip.dst.String() != i.ip.String()
+			if resp.typ == IPv6ICMPTypeEchoReply {
+				wDst = !resp.src.Equal(i.ip)
+			}
 			wV4 = false
 		}
 
-		if (i.icmp && wSeq) || (!i.icmp && (wDst || wID || wV4)) {
+		// the destination check separates the concurrent traces
+		if (i.icmp && (wSeq || wDst)) || (!i.icmp && (wDst || wID || wV4)) {
 			du, _ := time.ParseDuration(i.wait)
 			if time.Since(ts) < du {
 				continue
@@ -350,97 +428,126 @@ func (i *Trace) Recv(id, seq int) (ICMPResp, error) {
 func (i *Trace) NextHop(hop int) HopResp {
 	rand.Seed(time.Now().UTC().UnixNano())
 	var (
-		r        = HopResp{num: hop}
-		dnsCache = make(map[string][]string)
-		port     = 33434
-		name     []string
-		ok       bool
+		r    = HopResp{Num: hop}
+		port = 33434
 	)
 	i.SetTTL(hop)
 	begin := time.Now()
 
 	id, seq, err := i.Send(port)
 	if err != nil {
-		return HopResp{num: hop, err: err}
+		return HopResp{Num: hop, Err: err}
 	}
 
 	resp, err := i.Recv(id, seq)
 	if err != nil {
-		r = HopResp{hop, "", "", 0, false, nil, Whois{}}
 		return r
 	}
 
-	elapsed := time.Since(begin)
+	return i.hopResp(hop, resp, time.Since(begin))
+}
+
+// hopResp makes the hop's response from the replied packet
+func (i *Trace) hopResp(hop int, resp ICMPResp, elapsed time.Duration) HopResp {
+	var name []string
 
 	if i.resolve {
-		if name, ok = dnsCache[resp.src.String()]; !ok {
-			name, _ = lookupAddr(resp.src)
-			dnsCache[resp.src.String()] = name
-		}
+		name, _ = lookupAddr(resp.src)
+	}
+	r := HopResp{
+		Num:     hop,
+		IP:      resp.src.String(),
+		Elapsed: elapsed.Seconds() * 1e3,
+		Type:    resp.typ,
+		Code:    resp.code,
+		MPLS:    resp.mpls,
+	}
+	if i.tracebox && resp.quote != nil {
+		r.Mods = boxCompare(i.sent, resp.quote)
+	}
+	if i.qos && resp.ip.dst != nil {
+		i.dscpRemark(hop, resp.ip.tos>>2, &r)
 	}
 	if len(name) > 0 {
-		r = HopResp{hop, name[0], resp.src.String(), elapsed.Seconds() * 1e3, false, nil, Whois{}}
-	} else {
-		r = HopResp{hop, "", resp.src.String(), elapsed.Seconds() * 1e3, false, nil, Whois{}}
+		r.Name = name[0]
 	}
 	// reached to the target
 	for _, h := range i.ips {
 		if resp.src.String() == h.String() {
-			r.last = true
+			r.Last = true
 			break
 		}
 	}
 	return r
 }
 
-// Run provides trace based on the other methods
-func (i *Trace) Run(retry int) (chan []HopResp, error) {
-	var (
-		c = make(chan []HopResp, 1)
-		r []HopResp
-	)
+// dscpRemark sets the DSCP that the hop received and the change
+// from the previous responded hop, it starts over at a new round
+func (i *Trace) dscpRemark(hop, dscp int, r *HopResp) {
+	if hop < i.dscpHop || i.dscpHop == 0 {
+		i.dscpLast = i.tos >> 2
+	}
+	i.dscpHop = hop
+
+	r.DSCP = DSCPName(dscp)
+	if dscp != i.dscpLast {
+		r.Remark = fmtRemark(i.dscpLast, dscp)
+		i.dscpLast = dscp
+	}
+}
+
+// Run provides trace based on the other methods, it stops
+// once the target reached or the context canceled
+func (i *Trace) Run(ctx context.Context, retry int) (chan []HopResp, error) {
+	var c = make(chan []HopResp, 1)
 
 	if err := i.Bind(); err != nil {
 		return c, err
 	}
 
 	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				syscall.Close(i.fd)
-			}
-		}()
-	LOOP:
+		defer syscall.Close(i.fd)
+		defer close(c)
 		for h := 1; h <= i.maxTTL; h++ {
+			var r []HopResp
 			for n := 0; n < retry; n++ {
+				if ctx.Err() != nil {
+					return
+				}
 				hop := i.NextHop(h)
 				r = append(r, hop)
-				if hop.err != nil {
+				if hop.Err != nil {
 					break
 				}
 			}
 			if i.ripe {
 				i.addWhois(r[:])
 			}
-			c <- r
+			if i.geo {
+				addGeo(r)
+			}
+			select {
+			case c <- r:
+			case <-ctx.Done():
+				return
+			}
 			for _, R := range r {
-				if R.last || R.err != nil {
-					break LOOP
+				if R.Last || R.Err != nil {
+					return
 				}
 			}
-			r = r[:0]
 		}
-		close(c)
-		syscall.Close(i.fd)
 	}()
 	return c, nil
 }
 
-// MRun provides trace all hops in loop
-func (i *Trace) MRun() (chan HopResp, error) {
+// MRun provides trace all hops in loop until the count
+// reached or the context canceled
+func (i *Trace) MRun(ctx context.Context) (chan HopResp, error) {
 	var (
 		c      = make(chan HopResp, 1)
 		ASN    = make(map[string]Whois, 100)
+		GEO    = make(map[string]geo.Location, 100)
 		maxTTL = i.maxTTL
 		MU     sync.Mutex
 		count  int
@@ -451,55 +558,87 @@ func (i *Trace) MRun() (chan HopResp, error) {
 	}
 
 	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				syscall.Close(i.fd)
-			}
-		}()
-	LOOP:
+		defer syscall.Close(i.fd)
+		defer close(c)
 		for {
 			for h := 1; h <= maxTTL; h++ {
+				if ctx.Err() != nil {
+					return
+				}
 				hop := i.NextHop(h)
-				if w, ok := ASN[hop.ip]; ok {
-					hop.whois = w
-				} else if hop.ip != "" {
-					go func(ASN map[string]Whois) {
+				MU.Lock()
+				w, ok := ASN[hop.IP]
+				MU.Unlock()
+				if ok {
+					hop.Whois = w
+				} else if hop.IP != "" && i.ripe && whois.Offline() {
+					hop.Whois, _ = ipWhois(hop.IP)
+					MU.Lock()
+					ASN[hop.IP] = hop.Whois
+					MU.Unlock()
+				} else if hop.IP != "" && i.ripe {
+					go func(ip string) {
+						w, _ := ipWhois(ip)
 						MU.Lock()
-						defer MU.Unlock()
-						w, _ := whois(hop.ip)
-						ASN[hop.ip] = w
-					}(ASN)
+						ASN[ip] = w
+						MU.Unlock()
+					}(hop.IP)
 				}
 
-				c <- hop
+				MU.Lock()
+				l, ok := GEO[hop.IP]
+				MU.Unlock()
+				if ok {
+					hop.City, hop.Country = l.City, l.Country
+				} else if hop.IP != "" && i.geo && geo.Offline() {
+					// the miss isn't kept, RIPEstat may find it in background
+					if l, err := geo.IPLocation(hop.IP); err == nil {
+						hop.City, hop.Country = l.City, l.Country
+						MU.Lock()
+						GEO[hop.IP] = l
+						MU.Unlock()
+					}
+				} else if hop.IP != "" && i.geo {
+					go func(ip string) {
+						l, _ := geo.IPLocation(ip)
+						MU.Lock()
+						GEO[ip] = l
+						MU.Unlock()
+					}(hop.IP)
+				}
 
-				if hop.last && maxTTL == i.maxTTL {
+				select {
+				case c <- hop:
+				case <-ctx.Done():
+					return
+				}
+
+				if hop.Last && maxTTL == i.maxTTL {
 					maxTTL = h
 				}
 				time.Sleep(100 * time.Millisecond)
 			}
 			count++
 			if i.count > 0 && count >= i.count {
-				break LOOP
+				return
+			}
+			select {
+			case <-time.After(1 * time.Second):
+			case <-ctx.Done():
+				return
 			}
-			time.Sleep(1 * time.Second)
 		}
-		close(c)
 	}()
 	return c, nil
 }
 
 // Marshal encodes hop response
 func (h *HopResp) Marshal() string {
-	return fmt.Sprintf(`{ "Id": %d, "Hop": "%s", "IP" : "%s", "Elapsed": %.3f, "Holder": "%s", "ASN": %.0f, "Last": %t }`,
-		h.num,
-		h.hop,
-		h.ip,
-		h.elapsed,
-		h.whois.holder,
-		h.whois.asn,
-		h.last,
-	)
+	b, err := json.Marshal(h)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"err": err.Error()})
+	}
+	return string(b)
 }
 
 // routerChange detects if the router changed
@@ -522,37 +661,53 @@ func routerChange(router, b string) bool {
 }
 
 // Print prints out trace result in normal or terminal mode
-func (i *Trace) Print() {
+func (i *Trace) Print(ctx context.Context) {
+	if i.multipath {
+		i.PrintMultiPath(ctx)
+		return
+	}
+	if i.tracebox {
+		i.PrintTracebox(ctx)
+		return
+	}
+	if i.pmtu {
+		i.PrintPathMTU(ctx)
+		return
+	}
+	if i.report && !i.realTime {
+		i.PrintReport(ctx)
+		return
+	}
 	if i.realTime {
-		if rep, err := i.TermUI(); err != nil {
+		if rep, err := i.TermUI(ctx); err != nil {
 			fmt.Println(err.Error())
 		} else if rep != "" {
 			fmt.Println(rep)
 		}
 	} else {
-		i.PrintPretty()
+		i.PrintPretty(ctx)
 	}
 }
 
 // PrintPretty prints out trace result
-func (i *Trace) PrintPretty() {
+func (i *Trace) PrintPretty(ctx context.Context) {
 	var (
 		counter int
-		sigCh   = make(chan os.Signal, 1)
 		resp    = make(chan []HopResp, 1)
 		err     error
 	)
 
-	if resp, err = i.Run(3); err != nil {
+	if resp, err = i.Run(ctx, 3); err != nil {
 		println(err.Error())
 		return
 	}
 
-	signal.Notify(sigCh, os.Interrupt)
-	defer signal.Stop(sigCh)
-
 	// header
-	fmt.Printf("trace route to %s (%s), %d hops max\n", i.host, i.ip, i.maxTTL)
+	if i.qos {
+		fmt.Printf("trace route to %s (%s), %d hops max, %s\n", i.host, i.ip, i.maxTTL, fmtQoS(i.tos))
+	} else {
+		fmt.Printf("trace route to %s (%s), %d hops max\n", i.host, i.ip, i.maxTTL)
+	}
 LOOP:
 	for {
 		select {
@@ -561,51 +716,50 @@ LOOP:
 				break LOOP
 			}
 			for _, R := range r {
-				if R.err != nil {
-					println(R.err.Error())
+				if R.Err != nil {
+					println(R.Err.Error())
 					break LOOP
 				}
 			}
 			counter++
 			sort.Sort(MHopResp(r))
 			// there is not any load balancing and there is at least a timeout
-			if r[0].ip != r[1].ip && (r[1].elapsed == 0 || r[2].elapsed == 0) {
+			if r[0].IP != r[1].IP && (r[1].Elapsed == 0 || r[2].Elapsed == 0) {
 				fmt.Printf("%-2d %s", counter, fmtHops(r, 1))
 				continue
 			}
 			// there is not any load balancing and there is at least a timeout
-			if r[1].ip != r[2].ip && (r[0].elapsed == 0 || r[1].elapsed == 0) {
+			if r[1].IP != r[2].IP && (r[0].Elapsed == 0 || r[1].Elapsed == 0) {
 				fmt.Printf("%-2d %s", counter, fmtHops(r, 1))
 				continue
 			}
 			// there is not any load balancing and there is at least a timeout
-			if r[0].ip == r[1].ip && r[0].elapsed != 0 && r[2].elapsed == 0 {
+			if r[0].IP == r[1].IP && r[0].Elapsed != 0 && r[2].Elapsed == 0 {
 				fmt.Printf("%-2d %s %s", counter, fmtHops(r[0:2], 0), fmtHops(r[2:3], 1))
 				continue
 			}
 
 			// load balance between three routes
-			if r[0].ip != r[1].ip && r[1].ip != r[2].ip {
+			if r[0].IP != r[1].IP && r[1].IP != r[2].IP {
 				fmt.Printf("%-2d %s   %s   %s", counter, fmtHops(r[0:1], 1), fmtHops(r[1:2], 1), fmtHops(r[2:3], 1))
 				continue
 			}
 			// load balance between two routes
-			if r[0].ip == r[1].ip && r[1].ip != r[2].ip {
+			if r[0].IP == r[1].IP && r[1].IP != r[2].IP {
 				fmt.Printf("%-2d %s   %s", counter, fmtHops(r[0:2], 1), fmtHops(r[2:3], 1))
 				continue
 			}
 			// load balance between two routes
-			if r[0].ip != r[1].ip && r[1].ip == r[2].ip {
+			if r[0].IP != r[1].IP && r[1].IP == r[2].IP {
 				fmt.Printf("%-2d %s   %s", counter, fmtHops(r[0:1], 1), fmtHops(r[1:3], 1))
 				continue
 			}
 			// there is not any load balancing
-			if r[0].ip == r[1].ip && r[1].ip == r[2].ip {
+			if r[0].IP == r[1].IP && r[1].IP == r[2].IP {
 				fmt.Printf("%-2d %s", counter, fmtHops(r, 1))
 			}
 			//fmt.Printf("%#v\n", r)
-		case <-sigCh:
-			close(resp)
+		case <-ctx.Done():
 			break LOOP
 		}
 	}
@@ -617,22 +771,40 @@ func fmtHops(m []HopResp, newLine int) string {
 		msg     string
 	)
 	for _, r := range m {
-		if (msg == "" || timeout) && r.hop != "" {
-			if r.whois.asn != 0 {
-				msg += fmt.Sprintf("%s (%s) [ASN %.0f/%s] ", r.hop, r.ip, r.whois.asn, strings.Fields(r.whois.holder)[0])
+		if (msg == "" || timeout) && r.Name != "" {
+			if r.ASN != 0 {
+				msg += fmt.Sprintf("%s (%s) %s ", r.Name, r.IP, fmtASN(r.ASN, r.Holder))
 			} else {
-				msg += fmt.Sprintf("%s (%s) ", r.hop, r.ip)
+				msg += fmt.Sprintf("%s (%s) ", r.Name, r.IP)
+			}
+			if r.Country != "" {
+				msg += fmtLocation(r.City, r.Country) + " "
+			}
+			if len(r.MPLS) > 0 {
+				msg += "[" + fmtMPLS(r.MPLS) + "] "
+			}
+			if r.Remark != "" {
+				msg += "[" + r.Remark + "] "
 			}
 		}
-		if (msg == "" || timeout) && r.hop == "" && r.elapsed != 0 {
-			if r.whois.asn != 0 {
-				msg += fmt.Sprintf("%s [ASN %.0f/%s] ", r.ip, r.whois.asn, strings.Fields(r.whois.holder)[0])
+		if (msg == "" || timeout) && r.Name == "" && r.Elapsed != 0 {
+			if r.ASN != 0 {
+				msg += fmt.Sprintf("%s %s ", r.IP, fmtASN(r.ASN, r.Holder))
 			} else {
-				msg += fmt.Sprintf("%s ", r.ip)
+				msg += fmt.Sprintf("%s ", r.IP)
+			}
+			if r.Country != "" {
+				msg += fmtLocation(r.City, r.Country) + " "
+			}
+			if len(r.MPLS) > 0 {
+				msg += "[" + fmtMPLS(r.MPLS) + "] "
+			}
+			if r.Remark != "" {
+				msg += "[" + r.Remark + "] "
 			}
 		}
-		if r.elapsed != 0 {
-			msg += fmt.Sprintf("%.3f ms ", r.elapsed)
+		if r.Elapsed != 0 {
+			msg += fmt.Sprintf("%.3f ms ", r.Elapsed)
 			timeout = false
 		} else {
 			msg += "* "
@@ -645,6 +817,34 @@ func fmtHops(m []HopResp, newLine int) string {
 	return msg
 }
 
+// fmtASN formats the ASN w/ the holder's name if it's available
+func fmtASN(asn float64, holder string) string {
+	if h := holderName(holder); h != "" {
+		return fmt.Sprintf("[ASN %.0f/%s]", asn, h)
+	}
+	return fmt.Sprintf("[ASN %.0f]", asn)
+}
+
+// fmtLocation formats the city and the country code
+func fmtLocation(city, country string) string {
+	if city != "" {
+		return fmt.Sprintf("[%s, %s]", city, country)
+	}
+	return fmt.Sprintf("[%s]", country)
+}
+
+// addGeo adds the hops' geolocation if available
+func addGeo(R []HopResp) {
+	for i := range R {
+		if R[i].IP == "" {
+			continue
+		}
+		if l, err := geo.IPLocation(R[i].IP); err == nil {
+			R[i].City, R[i].Country = l.City, l.Country
+		}
+	}
+}
+
 // addWhois adds whois info to response if available
 func (i *Trace) addWhois(R []HopResp) {
 	var (
@@ -654,7 +854,7 @@ func (i *Trace) addWhois(R []HopResp) {
 	)
 
 	for _, r := range R {
-		ips[r.ip] = Whois{}
+		ips[r.IP] = Whois{}
 	}
 
 	for ip := range ips {
@@ -662,7 +862,7 @@ func (i *Trace) addWhois(R []HopResp) {
 			continue
 		}
 
-		w, err = whois(ip)
+		w, err = ipWhois(ip)
 
 		if err != nil {
 			continue
@@ -672,7 +872,7 @@ func (i *Trace) addWhois(R []HopResp) {
 	}
 
 	for i := range R {
-		R[i].whois = ips[R[i].ip]
+		R[i].Whois = ips[R[i].IP]
 	}
 }
 
@@ -683,28 +883,14 @@ func (i *Trace) setReportDCount(c int) {
 	}
 }
 
-// whois returns prefix whois info from RIPE
-func whois(ip string) (Whois, error) {
-	var resp Whois
-
-	_, net, err := net.ParseCIDR(ip + "/24")
+// ipWhois returns the IP address's ASN, holder and prefix through
+// the whois source (RIPEstat or the offline table)
+func ipWhois(ip string) (Whois, error) {
+	w, err := whois.IPInfo(ip)
 	if err != nil {
-		ip = net.String()
+		return Whois{}, err
 	}
-
-	r := new(ripe.Prefix)
-	r.Set(ip)
-	r.GetData()
-	data, ok := r.Data["data"].(map[string]interface{})
-	if !ok {
-		return Whois{}, fmt.Errorf("data not available")
-	}
-	asns := data["asns"].([]interface{})
-	for _, h := range asns {
-		resp.holder = h.(map[string]interface{})["holder"].(string)
-		resp.asn = h.(map[string]interface{})["asn"].(float64)
-	}
-	return resp, nil
+	return Whois{Holder: w.Holder, ASN: w.ASN, Prefix: w.Prefix, PrefixLen: w.PrefixLen}, nil
 }
 
 func min(a, b float64) float64 {
@@ -716,12 +902,6 @@ func min(a, b float64) float64 {
 	}
 	return b
 }
-func avg(a, b float64) float64 {
-	if a != 0 {
-		return (a + b) / 2
-	}
-	return b
-}
 func max(a, b float64) float64 {
 	if a > b {
 		return a
@@ -747,9 +927,7 @@ func lookupAddr(ip net.IP) ([]string, error) {
 }
 
 func calcStatistics(s *Stats, elapsed float64) {
-	s.min = min(s.min, elapsed)
-	s.avg = avg(s.avg, elapsed)
-	s.max = max(s.max, elapsed)
+	s.rtt.Add(elapsed)
 }
 
 func udpMessage(lport, rport, pSize int, isIPv4 bool) []byte {
@@ -908,10 +1086,31 @@ func helpTrace() {
           -c             Set the number of pings sent
           -p             Set the packet size in bytes inclusive headers (default 52 bytes)
           -u             Use UDP datagram instead of ICMP
-          -R             Prints results of real-time trace, when completed
+          -t             Use TCP SYN instead of ICMP
+          -tb            Detect the middleboxes that modify the headers (tracebox, IPv4 only)
+          -pmtu          Discover the path MTU per hop w/ DF probes (-p sets the initial size)
+          -geo           Shows the hops' city and country (geo source)
+          -R             Prints MTR style report once completed (w/ -r after real-time trace)
+          -json          Prints the report in JSON format (w/ -R)
+          -csv           Prints the report in CSV format (w/ -R)
+          -save          Saves the report to the trace history (see trace diff)
+          -mp            Discover load balanced paths (flow-stable multipath)
+          -mf            Set the maximum number of flows per hop in multipath mode (default 32)
+          -dscp          Set the probes' DSCP by name (EF, AF41, CS5 ...) or number, shows the re-marked hops
+          -tos           Set the probes' IPv4 TOS / IPv6 traffic class byte (0-255)
+          -rev           Traces back from a looking glass node side by side (provider or provider/node)
     Example:
           trace 8.8.8.8
           trace freebsd.org -r
+          trace freebsd.org -mp
+          trace freebsd.org -t -tb
+          trace freebsd.org -u -pmtu
+          trace freebsd.org -R -c 10 -json
+          trace freebsd.org -save
+          trace diff freebsd.org
+          trace topo /home/user/pops.txt -o /tmp/pops.dot
+          trace freebsd.org -rev level3/los_angeles
+          trace freebsd.org -dscp EF
 	`)
 
 }
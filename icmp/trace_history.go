@@ -0,0 +1,312 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+// HistoryDir is the trace history directory, the reports of
+// each target and source saved at a separate file
+var HistoryDir = historyDir()
+
+// HopChange represents a hop's change between two trace runs
+type HopChange struct {
+	Num int `json:"hop"`
+	// Old and New are the hop at the previous and the next run
+	Old ReportHop `json:"old"`
+	New ReportHop `json:"new"`
+}
+
+// TraceDiff represents the path changes between two trace runs
+type TraceDiff struct {
+	Old     time.Time   `json:"old"`
+	New     time.Time   `json:"new"`
+	Added   []ReportHop `json:"added"`
+	Removed []ReportHop `json:"removed"`
+	Shifted []HopChange `json:"shifted"`
+	OldPath []float64   `json:"oldASPath"`
+	NewPath []float64   `json:"newASPath"`
+}
+
+// historyDir returns the mylg trace history directory at home
+func historyDir() string {
+	u, err := user.Current()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".mylg.traces")
+	}
+	return filepath.Join(u.HomeDir, ".mylg.traces")
+}
+
+// historyFile returns the file that keeps the target's reports from the source
+func historyFile(host, src string) string {
+	r := strings.NewReplacer("/", "-", ":", "-", "\\", "-")
+	return filepath.Join(HistoryDir, r.Replace(strings.ToLower(host)+"_"+src)+".json")
+}
+
+// Save appends the report to the trace history
+func (rp *Report) Save() error {
+	if err := os.MkdirAll(HistoryDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(historyFile(rp.Host, rp.Src), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := rp.JSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadReports returns the saved reports of the target in time order, the
+// reports from all sources return once the source is empty
+func LoadReports(host, src string) ([]Report, error) {
+	var reports []Report
+
+	files := []string{historyFile(host, src)}
+	if src == "" {
+		// the other targets' files may match as well, e.g. a_b_src for a
+		prefix := strings.TrimSuffix(historyFile(host, ""), ".json")
+		files, _ = filepath.Glob(globEscape(prefix) + "*.json")
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		r := bufio.NewReader(f)
+		for {
+			b, err := r.ReadBytes('\n')
+			if len(b) > 0 {
+				var rp Report
+				if err := json.Unmarshal(b, &rp); err == nil && strings.EqualFold(rp.Host, host) {
+					reports = append(reports, rp)
+				}
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+		f.Close()
+	}
+
+	sort.SliceStable(reports, func(a, b int) bool { return reports[a].Start.Before(reports[b].Start) })
+
+	return reports, nil
+}
+
+// globEscape escapes the glob pattern's special characters
+func globEscape(s string) string {
+	return strings.NewReplacer("[", "[[]", "*", "[*]", "?", "[?]").Replace(s)
+}
+
+// DiffReports compares two trace runs, a hop is added or removed once its
+// address changed and it's shifted once its average latency changed more
+// than the threshold in milliseconds
+func DiffReports(prev, next Report, threshold float64) TraceDiff {
+	var (
+		d     = TraceDiff{Old: prev.Start, New: next.Start}
+		oHops = make(map[int]ReportHop)
+		nHops = make(map[int]ReportHop)
+		nums  []int
+	)
+
+	for _, h := range prev.Hops {
+		oHops[h.Num] = h
+		nums = append(nums, h.Num)
+	}
+	for _, h := range next.Hops {
+		nHops[h.Num] = h
+		if _, ok := oHops[h.Num]; !ok {
+			nums = append(nums, h.Num)
+		}
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		o, n := oHops[num], nHops[num]
+		if o.IP == n.IP {
+			if o.IP != "" && math.Abs(n.Avg-o.Avg) >= threshold {
+				d.Shifted = append(d.Shifted, HopChange{Num: num, Old: o, New: n})
+			}
+			continue
+		}
+		if o.IP != "" {
+			d.Removed = append(d.Removed, o)
+		}
+		if n.IP != "" {
+			d.Added = append(d.Added, n)
+		}
+	}
+
+	if o, n := asPath(prev), asPath(next); !equalASPath(o, n) {
+		d.OldPath, d.NewPath = o, n
+	}
+
+	return d
+}
+
+// Changed returns true if the path or the hops' latency changed
+func (d TraceDiff) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Shifted) > 0 || d.NewPath != nil || d.OldPath != nil
+}
+
+// Text writes the changes, the removed hops start w/ -, the added
+// hops start w/ + and the latency shifts start w/ ~
+func (d TraceDiff) Text(w io.Writer) {
+	var layout = "2006-01-02 15:04:05"
+
+	fmt.Fprintf(w, "%s -> %s", d.Old.Local().Format(layout), d.New.Local().Format(layout))
+	if !d.Changed() {
+		fmt.Fprintln(w, "  no change")
+		return
+	}
+	fmt.Fprintln(w)
+
+	for _, h := range d.Removed {
+		fmt.Fprintf(w, "  - %-2d %s\n", h.Num, fmtReportHop(h))
+	}
+	for _, h := range d.Added {
+		fmt.Fprintf(w, "  + %-2d %s\n", h.Num, fmtReportHop(h))
+	}
+	for _, c := range d.Shifted {
+		fmt.Fprintf(w, "  ~ %-2d %s avg %.1f -> %.1f ms (%+.1f)\n", c.Num, fmtReportHop(c.New), c.Old.Avg, c.New.Avg, c.New.Avg-c.Old.Avg)
+	}
+	if d.OldPath != nil || d.NewPath != nil {
+		fmt.Fprintf(w, "  AS path: %s -> %s\n", fmtASPath(d.OldPath), fmtASPath(d.NewPath))
+	}
+}
+
+// PrintTraceDiff prints out the changes between the saved trace runs
+// of the target (trace diff target [options])
+func PrintTraceDiff(args string) {
+	target, flag := cli.Flag(args)
+	if _, ok := flag["help"]; ok || target == "" {
+		helpTraceDiff()
+		return
+	}
+
+	var (
+		src       = cli.SetFlag(flag, "src", "").(string)
+		count     = cli.SetFlag(flag, "c", 10).(int)
+		threshold = cli.SetFlag(flag, "d", 10).(int)
+		sources   []string
+		runs      = make(map[string][]Report)
+	)
+
+	reports, err := LoadReports(target, src)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if len(reports) == 0 {
+		fmt.Printf("there is no saved trace to %s, run trace %s -save\n", target, target)
+		return
+	}
+
+	for _, rp := range reports {
+		if _, ok := runs[rp.Src]; !ok {
+			sources = append(sources, rp.Src)
+		}
+		runs[rp.Src] = append(runs[rp.Src], rp)
+	}
+
+	for _, s := range sources {
+		r := runs[s]
+		if count > 1 && len(r) > count {
+			r = r[len(r)-count:]
+		}
+		fmt.Printf("trace diff %s from %s, %d runs\n", target, s, len(r))
+		if len(r) < 2 {
+			fmt.Println("  needs two runs at least")
+			continue
+		}
+		for n := 1; n < len(r); n++ {
+			DiffReports(r[n-1], r[n], float64(threshold)).Text(os.Stdout)
+		}
+	}
+}
+
+// asPath returns the report's ASNs in order w/o the repeated ones
+func asPath(rp Report) []float64 {
+	var path []float64
+	for _, h := range rp.Hops {
+		if h.ASN == 0 || (len(path) > 0 && path[len(path)-1] == h.ASN) {
+			continue
+		}
+		path = append(path, h.ASN)
+	}
+	return path
+}
+
+func equalASPath(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func fmtASPath(path []float64) string {
+	var s []string
+	for _, asn := range path {
+		s = append(s, fmt.Sprintf("AS%.0f", asn))
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, " ")
+}
+
+// fmtReportHop formats the hop's host, ip address and ASN
+func fmtReportHop(h ReportHop) string {
+	s := h.IP
+	if h.Host != "" && h.Host != h.IP {
+		s = fmt.Sprintf("%s (%s)", h.Host, h.IP)
+	}
+	if h.ASN != 0 {
+		s += fmt.Sprintf(" AS%.0f", h.ASN)
+	}
+	return s
+}
+
+func helpTraceDiff() {
+	fmt.Println(`
+    usage:
+          trace diff IP address / domain name [options]
+    options:
+          -src           Compares the runs from the source address (default all sources)
+          -c             Set the number of last runs to compare (default 10)
+          -d             Set the latency shift threshold in milliseconds (default 10)
+    Example:
+          trace 8.8.8.8 -save
+          trace diff 8.8.8.8
+          trace diff 8.8.8.8 -c 48 -d 20
+	`)
+}
@@ -0,0 +1,255 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+// Report represents MTR style report of a trace
+type Report struct {
+	Host  string      `json:"host"`
+	IP    string      `json:"ip"`
+	Src   string      `json:"src,omitempty"`
+	DSCP  string      `json:"dscp,omitempty"`
+	Count int         `json:"count"`
+	Start time.Time   `json:"start"`
+	Hops  []ReportHop `json:"hops"`
+}
+
+// ReportHop represents a hop's statistics at the report
+type ReportHop struct {
+	Num    int     `json:"hop"`
+	Host   string  `json:"host"`
+	IP     string  `json:"ip"`
+	ASN    float64 `json:"asn"`
+	Holder string  `json:"holder"`
+	Loss   float64 `json:"loss"`
+	Sent   int     `json:"sent"`
+	Last   float64 `json:"last"`
+	Avg    float64 `json:"avg"`
+	Best   float64 `json:"best"`
+	Wrst   float64 `json:"wrst"`
+	StDev  float64 `json:"stdev"`
+
+	MPLS    []MPLSLabel `json:"mpls,omitempty"`
+	City    string      `json:"city,omitempty"`
+	Country string      `json:"country,omitempty"`
+	DSCP    string      `json:"dscp,omitempty"`
+	Remark  string      `json:"remark,omitempty"`
+
+	rtts []float64
+}
+
+// Report runs the trace w/o terminal UI for the requested count
+// and returns statistics of each hop
+func (i *Trace) Report(ctx context.Context) (*Report, error) {
+	var (
+		hops = make(map[int]*ReportHop)
+		rp   = &Report{Host: i.host, IP: i.ip.String(), Src: i.src.String(), Count: i.count, Start: time.Now()}
+	)
+
+	if i.qos {
+		rp.DSCP = DSCPName(i.tos >> 2)
+	}
+
+	resp, err := i.MRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for r := range resp {
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		h, ok := hops[r.Num]
+		if !ok {
+			h = &ReportHop{Num: r.Num}
+			hops[r.Num] = h
+		}
+		h.Sent++
+		if r.IP == "" {
+			continue
+		}
+		if h.IP == "" {
+			h.IP = r.IP
+			h.Host = r.Name
+		}
+		if h.ASN == 0 && r.ASN != 0 {
+			h.ASN = r.ASN
+			h.Holder = r.Holder
+		}
+		h.Last = r.Elapsed
+		if len(r.MPLS) > 0 {
+			h.MPLS = r.MPLS
+		}
+		if r.Country != "" {
+			h.City, h.Country = r.City, r.Country
+		}
+		if r.DSCP != "" {
+			h.DSCP = r.DSCP
+		}
+		if r.Remark != "" && h.Remark == "" {
+			h.Remark = r.Remark
+		}
+		h.rtts = append(h.rtts, r.Elapsed)
+	}
+
+	for _, h := range hops {
+		h.calc()
+		if i.ripe && h.IP != "" && h.ASN == 0 {
+			if w, err := ipWhois(h.IP); err == nil {
+				h.ASN = w.ASN
+				h.Holder = w.Holder
+			}
+		}
+		rp.Hops = append(rp.Hops, *h)
+	}
+
+	sort.Slice(rp.Hops, func(a, b int) bool { return rp.Hops[a].Num < rp.Hops[b].Num })
+
+	if ctx.Err() != nil {
+		return rp, ctx.Err()
+	}
+	if i.save {
+		return rp, rp.Save()
+	}
+
+	return rp, nil
+}
+
+// PrintReport prints out the trace report in text, JSON or CSV format
+func (i *Trace) PrintReport(ctx context.Context) {
+	rp, err := i.Report(ctx)
+	if rp == nil {
+		println(err.Error())
+		return
+	}
+	if err != nil && err != ctx.Err() {
+		// the report couldn't be saved
+		println(err.Error())
+	}
+
+	switch {
+	case i.fmtJSON:
+		err = rp.JSON(os.Stdout)
+	case i.fmtCSV:
+		err = rp.CSV(os.Stdout)
+	default:
+		err = rp.Text(os.Stdout)
+	}
+
+	if err != nil {
+		println(err.Error())
+	}
+}
+
+// Text writes the report in MTR report format
+func (rp *Report) Text(w io.Writer) error {
+	var format = "%3d.|-- %-8s %-40s %5.1f%% %5d %6.1f %6.1f %6.1f %6.1f %6.1f\n"
+
+	fmt.Fprintf(w, "Start: %s\n", rp.Start.Format(time.RFC1123Z))
+	if rp.DSCP != "" {
+		fmt.Fprintf(w, "DSCP: %s\n", rp.DSCP)
+	}
+	fmt.Fprintf(w, "HOST: %-51s %6s %5s %6s %6s %6s %6s %6s\n",
+		fmt.Sprintf("%s (%s)", rp.Host, rp.IP),
+		"Loss%", "Snt", "Last", "Avg", "Best", "Wrst", "StDev")
+
+	for _, h := range rp.Hops {
+		var (
+			as   = "AS???"
+			host = "???"
+		)
+		if h.ASN != 0 {
+			as = fmt.Sprintf("AS%.0f", h.ASN)
+		}
+		if h.Host != "" {
+			host = trimLongStr(h.Host, 37)
+		} else if h.IP != "" {
+			host = h.IP
+		}
+		if _, err := fmt.Fprintf(w, format, h.Num, as, host, h.Loss, h.Sent, h.Last, h.Avg, h.Best, h.Wrst, h.StDev); err != nil {
+			return err
+		}
+		for _, l := range h.MPLS {
+			if _, err := fmt.Fprintf(w, "    |  |   [MPLS: %s]\n", l); err != nil {
+				return err
+			}
+		}
+		if h.Remark != "" {
+			if _, err := fmt.Fprintf(w, "    |  |   [%s]\n", h.Remark); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON writes the report in JSON format
+func (rp *Report) JSON(w io.Writer) error {
+	b, err := json.Marshal(rp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// CSV writes the report in CSV format
+func (rp *Report) CSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"Hop", "Host", "IP", "ASN", "Holder", "Loss%", "Snt", "Last", "Avg", "Best", "Wrst", "StDev", "MPLS"})
+	for _, h := range rp.Hops {
+		c.Write([]string{
+			fmt.Sprintf("%d", h.Num),
+			h.Host,
+			h.IP,
+			fmt.Sprintf("%.0f", h.ASN),
+			strings.TrimSpace(h.Holder),
+			fmt.Sprintf("%.1f", h.Loss),
+			fmt.Sprintf("%d", h.Sent),
+			fmt.Sprintf("%.3f", h.Last),
+			fmt.Sprintf("%.3f", h.Avg),
+			fmt.Sprintf("%.3f", h.Best),
+			fmt.Sprintf("%.3f", h.Wrst),
+			fmt.Sprintf("%.3f", h.StDev),
+			mplsLabels(h.MPLS),
+		})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// calc calculates the hop's statistics based on the round trip times
+func (h *ReportHop) calc() {
+	if h.Sent > 0 {
+		h.Loss = float64(h.Sent-len(h.rtts)) * 100 / float64(h.Sent)
+	}
+	if len(h.rtts) == 0 {
+		return
+	}
+
+	var st stats.Stats
+	for _, rtt := range h.rtts {
+		st.Add(rtt)
+	}
+	h.Avg, h.Best, h.Wrst, h.StDev = st.Mean(), st.Min(), st.Max(), st.StdDev()
+}
+
+// RTTs returns the hop's round trip times in milliseconds
+func (h ReportHop) RTTs() []float64 {
+	return h.rtts
+}
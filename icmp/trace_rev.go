@@ -0,0 +1,199 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// myIPAddr returns the public IP address that the looking glass
+// node traces back
+var myIPAddr = ripe.MyIPAddr
+
+// RevHop represents a hop of the reverse path, the IP
+// address is empty if the hop didn't reply
+type RevHop struct {
+	Num  int     `json:"hop"`
+	Name string  `json:"name,omitempty"`
+	IP   string  `json:"ip"`
+	RTT  float64 `json:"rtt"`
+	ASN  float64 `json:"asn"`
+}
+
+// RevTrace traces the IP address back from a looking glass node
+type RevTrace func(ctx context.Context, ip string) ([]RevHop, error)
+
+// BothWays represents the forward path to the target and the
+// reverse path from a looking glass node to the public address
+type BothWays struct {
+	Forward  *Report
+	Reverse  []RevHop
+	Provider string
+	Node     string
+	MyIP     string
+}
+
+// BothWays traces the target from local and at the same time
+// traces back the public IP address from the looking glass node
+func (i *Trace) BothWays(ctx context.Context, rev RevTrace, name, node string) (*BothWays, error) {
+	var (
+		bw   = &BothWays{Provider: name, Node: node}
+		done = make(chan error, 1)
+		err  error
+	)
+
+	if bw.MyIP, err = myIPAddr(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		var err error
+		bw.Forward, err = i.Report(ctx)
+		done <- err
+	}()
+
+	bw.Reverse, err = rev(ctx, bw.MyIP)
+
+	if err := <-done; bw.Forward == nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if i.ripe {
+		for n, h := range bw.Reverse {
+			if h.IP == "" || h.ASN != 0 {
+				continue
+			}
+			if w, err := ipWhois(h.IP); err == nil {
+				bw.Reverse[n].ASN = w.ASN
+			}
+		}
+	}
+
+	return bw, ctx.Err()
+}
+
+// Text writes the forward and reverse paths side by side and the
+// AS paths, the bracketed ASNs aren't at the other direction
+func (bw *BothWays) Text(w io.Writer) {
+	var (
+		fwd      = asPath(*bw.Forward)
+		rev      = reverseASPath(bw.Reverse)
+		fwdASNs  = asnSet(fwd)
+		revASNs  = asnSet(rev)
+		format   = "%-3s %-34s %-9s %7s | %-3s %-34s %-9s %7s\n"
+		rows     = len(bw.Forward.Hops)
+		fwdTitle = fmt.Sprintf("%s (%s)", bw.Forward.Host, bw.Forward.IP)
+	)
+
+	if len(bw.Reverse) > rows {
+		rows = len(bw.Reverse)
+	}
+
+	fmt.Fprintf(w, "forward: %s -> %s\n", bw.Forward.Src, fwdTitle)
+	fmt.Fprintf(w, "reverse: %s/%s -> %s\n", bw.Provider, bw.Node, bw.MyIP)
+	fmt.Fprintf(w, format, "", "forward", "ASN", "avg", "", "reverse", "ASN", "rtt")
+
+	for n := 0; n < rows; n++ {
+		var f, r [4]string
+		if n < len(bw.Forward.Hops) {
+			h := bw.Forward.Hops[n]
+			f = [4]string{fmt.Sprint(h.Num), "*", "", ""}
+			if h.IP != "" {
+				f[1] = trimLongStr(hostIP(h.Host, h.IP), 31)
+				f[2] = markASN(h.ASN, revASNs)
+				f[3] = fmt.Sprintf("%.1f", h.Avg)
+			}
+		}
+		if n < len(bw.Reverse) {
+			h := bw.Reverse[n]
+			r = [4]string{fmt.Sprint(h.Num), "*", "", ""}
+			if h.IP != "" {
+				r[1] = trimLongStr(hostIP(h.Name, h.IP), 31)
+				r[2] = markASN(h.ASN, fwdASNs)
+				r[3] = fmt.Sprintf("%.1f", h.RTT)
+			}
+		}
+		fmt.Fprintf(w, format, f[0], f[1], f[2], f[3], r[0], r[1], r[2], r[3])
+	}
+
+	fmt.Fprintf(w, "AS path forward: %s\n", fmtMarkedASPath(fwd, revASNs))
+	fmt.Fprintf(w, "AS path reverse: %s (reversed)\n", fmtMarkedASPath(rev, fwdASNs))
+	if equalASPath(fwd, rev) {
+		fmt.Fprintln(w, "the AS paths are symmetric")
+	} else {
+		fmt.Fprintln(w, "the AS paths are asymmetric")
+	}
+}
+
+// PrintBothWays prints out the forward and reverse paths
+func (i *Trace) PrintBothWays(ctx context.Context, rev RevTrace, name, node string) {
+	fmt.Printf("trace %s and back from %s/%s, please wait ...\n", i.host, name, node)
+	bw, err := i.BothWays(ctx, rev, name, node)
+	if bw == nil {
+		println(err.Error())
+		return
+	}
+	bw.Text(os.Stdout)
+}
+
+// reverseASPath returns the reverse path's ASNs in the forward
+// direction order w/o the repeated ones
+func reverseASPath(hops []RevHop) []float64 {
+	var path []float64
+	for n := len(hops) - 1; n >= 0; n-- {
+		asn := hops[n].ASN
+		if asn == 0 || (len(path) > 0 && path[len(path)-1] == asn) {
+			continue
+		}
+		path = append(path, asn)
+	}
+	return path
+}
+
+func asnSet(path []float64) map[float64]bool {
+	s := make(map[float64]bool, len(path))
+	for _, asn := range path {
+		s[asn] = true
+	}
+	return s
+}
+
+// markASN formats the ASN, it's bracketed if it isn't at the other path
+func markASN(asn float64, other map[float64]bool) string {
+	switch {
+	case asn == 0:
+		return "AS???"
+	case !other[asn]:
+		return fmt.Sprintf("[AS%.0f]", asn)
+	}
+	return fmt.Sprintf("AS%.0f", asn)
+}
+
+func fmtMarkedASPath(path []float64, other map[float64]bool) string {
+	var s []string
+	for _, asn := range path {
+		s = append(s, markASN(asn, other))
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, " ")
+}
+
+func hostIP(host, ip string) string {
+	if host != "" && host != ip {
+		return fmt.Sprintf("%s (%s)", host, ip)
+	}
+	return ip
+}
@@ -1,6 +1,7 @@
 package icmp
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net"
@@ -10,7 +11,7 @@ import (
 
 	ui "github.com/gizak/termui"
 
-	"github.com/mehrdadrad/mylg/ripe"
+	"github.com/mehrdadrad/mylg/geo"
 )
 
 // Widgets represents termui widgets
@@ -20,6 +21,7 @@ type Widgets struct {
 	RTT  *ui.List
 	Snt  *ui.List
 	Pkl  *ui.List
+	Loc  *ui.List
 
 	Menu   *ui.Par
 	Header *ui.Par
@@ -51,7 +53,7 @@ type Geo struct {
 }
 
 // TermUI prints out trace loop by termui
-func (i *Trace) TermUI() (string, error) {
+func (i *Trace) TermUI(ctx context.Context) (string, error) {
 	ui.DefaultEvtStream = ui.NewEvtStream()
 	if err := ui.Init(); err != nil {
 		return "", err
@@ -74,8 +76,11 @@ func (i *Trace) TermUI() (string, error) {
 	// init widgets parameters w/ trace info
 	i.bridgeWidgetsTrace(w)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// run loop trace route
-	resp, err := i.MRun()
+	resp, err := i.MRun(ctx)
 	if err != nil {
 		return rp, err
 	}
@@ -84,7 +89,7 @@ func (i *Trace) TermUI() (string, error) {
 		routers[i] = make(map[string]Stats, 30)
 	}
 
-	screen1, screen2 := w.makeScreens()
+	screen1, screen2 := w.makeScreens(i.geo)
 	w.eventsHandler(done, screen1, screen2, stats)
 
 	// update header each second
@@ -110,91 +115,95 @@ func (i *Trace) TermUI() (string, error) {
 					break LOOP
 				}
 
-				if r.hop != "" {
-					hop = r.hop
+				if r.Name != "" {
+					hop = r.Name
 				} else {
-					hop = r.ip
+					hop = r.IP
 				}
 
-				if r.whois.asn > 0 {
-					as = fmt.Sprintf("%.0f", r.whois.asn)
-					holder = trimASNHolder(r.whois.holder)
+				if r.ASN > 0 {
+					as = fmt.Sprintf("%.0f", r.ASN)
+					holder = trimASNHolder(r.Holder)
 				} else {
 					as = ""
 					holder = ""
 				}
 
 				// statistics
-				stats[r.num].count++
-				w.Snt.Items[r.num] = fmt.Sprintf("%d", stats[r.num].count)
+				stats[r.Num].count++
+				w.Snt.Items[r.Num] = fmt.Sprintf("%d", stats[r.Num].count)
 
-				router := routers[r.num][hop]
+				router := routers[r.Num][hop]
 				router.count++
 
-				if r.elapsed != 0 {
+				if r.Elapsed != 0 {
 
 					// hop level statistics
-					calcStatistics(&stats[r.num], r.elapsed)
+					calcStatistics(&stats[r.Num], r.Elapsed)
 					// router level statistics
-					calcStatistics(&router, r.elapsed)
+					calcStatistics(&router, r.Elapsed)
 					// detect router changes
-					rChanged = routerChange(hop, w.Hops.Items[r.num])
+					rChanged = routerChange(hop, w.Hops.Items[r.Num])
 
-					w.Hops.Items[r.num] = fmt.Sprintf("[%-2d] %s", r.num, hop)
-					w.ASN.Items[r.num] = fmt.Sprintf("%-6s %s", as, holder)
-					w.RTT.Items[r.num] = fmt.Sprintf("%-6.2f\t%-6.2f\t%-6.2f\t%-6.2f", r.elapsed, stats[r.num].avg, stats[r.num].min, stats[r.num].max)
+					w.Hops.Items[r.Num] = fmt.Sprintf("[%-2d] %s", r.Num, hop)
+					if len(r.MPLS) > 0 {
+						w.Hops.Items[r.Num] += " " + fmtMPLS(r.MPLS)
+					}
+					w.ASN.Items[r.Num] = fmt.Sprintf("%-6s %s", as, holder)
+					if r.Country != "" {
+						w.Loc.Items[r.Num] = trimLongStr(strings.TrimSpace(r.Country+" "+r.City), 12)
+					}
+					w.RTT.Items[r.Num] = fmt.Sprintf("%-6.2f\t%-6.2f\t%-6.2f\t%-6.2f", r.Elapsed, stats[r.Num].rtt.Mean(), stats[r.Num].rtt.Min(), stats[r.Num].rtt.Max())
 
 					if rChanged {
-						w.Hops.Items[r.num] = termUICColor(w.Hops.Items[r.num], "fg-bold")
+						w.Hops.Items[r.Num] = termUICColor(w.Hops.Items[r.Num], "fg-bold")
 					}
 
 					lcShift(r, w.LCRTT, ui.TermWidth())
 
-				} else if w.Hops.Items[r.num] == "" {
+				} else if w.Hops.Items[r.Num] == "" {
 
-					w.Hops.Items[r.num] = fmt.Sprintf("[%-2d] %-40s", r.num, "???")
-					stats[r.num].pkl++
+					w.Hops.Items[r.Num] = fmt.Sprintf("[%-2d] %-40s", r.Num, "???")
+					stats[r.Num].pkl++
 					router.pkl++
 
-				} else if !strings.Contains(w.Hops.Items[r.num], "???") {
+				} else if !strings.Contains(w.Hops.Items[r.Num], "???") {
 
-					hop = rmUIMetaData(w.Hops.Items[r.num])
-					hop = fmt.Sprintf("[%-2d] %s", r.num, hop)
-					w.Hops.Items[r.num] = termUICColor(hop, "fg-red")
-					w.RTT.Items[r.num] = fmt.Sprintf("%-6.2s\t%-6.2f\t%-6.2f\t%-6.2f", "?", stats[r.num].avg, stats[r.num].min, stats[r.num].max)
-					stats[r.num].pkl++
+					hop = rmUIMetaData(w.Hops.Items[r.Num])
+					hop = fmt.Sprintf("[%-2d] %s", r.Num, hop)
+					w.Hops.Items[r.Num] = termUICColor(hop, "fg-red")
+					w.RTT.Items[r.Num] = fmt.Sprintf("%-6.2s\t%-6.2f\t%-6.2f\t%-6.2f", "?", stats[r.Num].rtt.Mean(), stats[r.Num].rtt.Min(), stats[r.Num].rtt.Max())
+					stats[r.Num].pkl++
 					router.pkl++
 
 				} else {
-					w.Hops.Items[r.num] = fmt.Sprintf("[%-2d] %s", r.num, "???")
-					stats[r.num].pkl++
+					w.Hops.Items[r.Num] = fmt.Sprintf("[%-2d] %s", r.Num, "???")
+					stats[r.Num].pkl++
 					router.pkl++
 
 				}
 
-				if len(w.BCPKL.DataLabels) > r.num-1 {
-					w.BCPKL.DataLabels[r.num-1] = fmt.Sprintf("H%d", r.num)
-					w.BCPKL.Data[r.num-1] = int(stats[r.num].pkl)
+				if len(w.BCPKL.DataLabels) > r.Num-1 {
+					w.BCPKL.DataLabels[r.Num-1] = fmt.Sprintf("H%d", r.Num)
+					w.BCPKL.Data[r.Num-1] = int(stats[r.Num].pkl)
 				} else {
-					w.BCPKL.DataLabels = append(w.BCPKL.DataLabels, fmt.Sprintf("H%d", r.num))
-					w.BCPKL.Data = append(w.BCPKL.Data, int(stats[r.num].pkl))
+					w.BCPKL.DataLabels = append(w.BCPKL.DataLabels, fmt.Sprintf("H%d", r.Num))
+					w.BCPKL.Data = append(w.BCPKL.Data, int(stats[r.Num].pkl))
 				}
 
-				routers[r.num][hop] = router
+				routers[r.Num][hop] = router
 
-				w.Pkl.Items[r.num] = fmt.Sprintf("%.1f", float64(stats[r.num].pkl)*100/float64(stats[r.num].count))
+				w.Pkl.Items[r.Num] = fmt.Sprintf("%.1f", float64(stats[r.Num].pkl)*100/float64(stats[r.Num].count))
 				ui.Render(ui.Body)
 				// clean up in case of packet loss on the last hop at first try
-				if r.last {
-					for i := r.num + 1; i < 65; i++ {
+				if r.Last {
+					for i := r.Num + 1; i < 65; i++ {
 						w.Hops.Items[i] = ""
 					}
 				}
 			}
 		}
-		if _, ok := <-resp; ok {
-			close(resp)
-		}
+		cancel()
 	}()
 
 	ui.Loop()
@@ -222,9 +231,9 @@ func (i *Trace) bridgeWidgetsTrace(w *Widgets) {
 
 // lcShift shifs line chart once it filled out
 func lcShift(r HopResp, lc *ui.LineChart, width int) {
-	if r.last {
+	if r.Last {
 		t := time.Now()
-		lc.Data = append(lc.Data, r.elapsed)
+		lc.Data = append(lc.Data, r.Elapsed)
 		lc.DataLabels = append(lc.DataLabels, t.Format("04:05"))
 		if len(lc.Data) > (ui.TermWidth()/2)-10 {
 			lc.Data = lc.Data[1:]
@@ -321,12 +330,9 @@ func (w *Widgets) eventsHandler(done chan struct{}, s1, s2 []*ui.Row, stats []St
 			w.RTT.Items[i] = ""
 			w.Snt.Items[i] = ""
 			w.Pkl.Items[i] = ""
+			w.Loc.Items[i] = ""
 
-			stats[i].count = 0
-			stats[i].avg = 0
-			stats[i].min = 0
-			stats[i].max = 0
-			stats[i].pkl = 0
+			stats[i] = Stats{}
 		}
 		w.LCRTT.Data = w.LCRTT.Data[:0]
 		w.LCRTT.DataLabels = w.LCRTT.DataLabels[:0]
@@ -334,7 +340,7 @@ func (w *Widgets) eventsHandler(done chan struct{}, s1, s2 []*ui.Row, stats []St
 
 }
 
-func (w *Widgets) makeScreens() ([]*ui.Row, []*ui.Row) {
+func (w *Widgets) makeScreens(geo bool) ([]*ui.Row, []*ui.Row) {
 	// screens1 - trace statistics
 	screen1 := []*ui.Row{
 		ui.NewRow(
@@ -351,6 +357,17 @@ func (w *Widgets) makeScreens() ([]*ui.Row, []*ui.Row) {
 			ui.NewCol(3, 0, w.RTT),
 		),
 	}
+	// the hops' location column
+	if geo {
+		screen1[2] = ui.NewRow(
+			ui.NewCol(4, 0, w.Hops),
+			ui.NewCol(2, 0, w.ASN),
+			ui.NewCol(1, 0, w.Loc),
+			ui.NewCol(1, 0, w.Pkl),
+			ui.NewCol(1, 0, w.Snt),
+			ui.NewCol(3, 0, w.RTT),
+		)
+	}
 	// screen2 - trace line chart
 	screen2 := []*ui.Row{
 		ui.NewRow(
@@ -428,35 +445,19 @@ LOOP:
 	}
 }
 
-func getGeo(ipDst net.IP, geo *Geo) {
+func getGeo(ipDst net.IP, g *Geo) {
 	// find station public ip address and geo
-	ip, err := ripe.MyIPAddr()
-	// find src, dst geo
-	if err == nil {
-		p := new(ripe.Prefix)
-		p.Set(ip)
-		p.GetGeoData()
-		for _, g := range p.GeoData.Data.Locations {
-			if g.City != "" {
-				geo.CitySrc = g.City
-				geo.CountrySrc = g.Country
-				geo.LatSrc = g.Latitude
-				geo.LonSrc = g.Longitude
-				break
-			}
-		}
-		p.Set(ipDst.String())
-		p.GetGeoData()
-		for _, g := range p.GeoData.Data.Locations {
-			if g.City != "" {
-				geo.CityDst = g.City
-				geo.CountryDst = g.Country
-				geo.LatDst = g.Latitude
-				geo.LonDst = g.Longitude
-				break
-			}
-		}
-
+	if _, src, err := geo.MyLocation(); err == nil {
+		g.CitySrc = src.City
+		g.CountrySrc = src.Country
+		g.LatSrc = src.Latitude
+		g.LonSrc = src.Longitude
+	}
+	if dst, err := geo.IPLocation(ipDst.String()); err == nil {
+		g.CityDst = dst.City
+		g.CountryDst = dst.Country
+		g.LatDst = dst.Latitude
+		g.LonDst = dst.Longitude
 	}
 }
 func initWidgets() *Widgets {
@@ -466,8 +467,9 @@ func initWidgets() *Widgets {
 		rtt  = ui.NewList()
 		snt  = ui.NewList()
 		pkl  = ui.NewList()
+		loc  = ui.NewList()
 
-		lists = []*ui.List{hops, asn, rtt, snt, pkl}
+		lists = []*ui.List{hops, asn, rtt, snt, pkl, loc}
 	)
 
 	for _, l := range lists {
@@ -484,6 +486,7 @@ func initWidgets() *Widgets {
 	rtt.Items[0] = fmt.Sprintf("[%-6s %-6s %-6s %-6s](fg-bold)", "Last", "Avg", "Best", "Wrst")
 	snt.Items[0] = "[Sent](fg-bold)"
 	pkl.Items[0] = "[Loss%](fg-bold)"
+	loc.Items[0] = "[Location](fg-bold)"
 
 	return &Widgets{
 		Hops: hops,
@@ -491,6 +494,7 @@ func initWidgets() *Widgets {
 		RTT:  rtt,
 		Snt:  snt,
 		Pkl:  pkl,
+		Loc:  loc,
 
 		Menu:   menuWidget(),
 		Header: headerWidget(),
@@ -525,8 +529,18 @@ func report(w *Widgets, i *Trace) string {
 	var (
 		r      string
 		format = "%-45s %-25s %-5s %-6s %s\n"
+		asn    = "ASN    Holder"
 	)
 
+	// the location follows the holder
+	if i.geo {
+		format = "%-45s %-40s %-5s %-6s %s\n"
+		asn = "ASN    Holder            Location"
+		for n := 1; n < 65; n++ {
+			w.ASN.Items[n] = fmt.Sprintf("%-25s %s", w.ASN.Items[n], w.Loc.Items[n])
+		}
+	}
+
 	r = fmt.Sprintf("──[ myLG ]── traceroute to %s (%s)\n",
 		i.host,
 		i.ip,
@@ -534,7 +548,7 @@ func report(w *Widgets, i *Trace) string {
 
 	r += fmt.Sprintf(format,
 		"Host",
-		"ASN    Holder",
+		asn,
 		"Sent",
 		"Lost%",
 		"Last       Avg     Best    Wrst",
@@ -559,7 +573,7 @@ func report(w *Widgets, i *Trace) string {
 }
 
 func trimASNHolder(h string) string {
-	h = strings.Fields(h)[0]
+	h = holderName(h)
 	h = strings.Split(h, "-")[0]
 
 	if len(h) > 12 {
@@ -569,6 +583,15 @@ func trimASNHolder(h string) string {
 	return h
 }
 
+// holderName returns the holder's first word, the offline
+// whois sources may not have the holder
+func holderName(h string) string {
+	if f := strings.Fields(h); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
 func trimLongStr(s string, l int) string {
 	if len(s) > l {
 		return s[:l] + "..."
@@ -1,7 +1,16 @@
 package icmp_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mehrdadrad/mylg/cli"
 	"github.com/mehrdadrad/mylg/icmp"
@@ -14,3 +23,357 @@ func TestNewTrace(t *testing.T) {
 		t.Error("unexpected error. expected %v, actual %v", nil, err)
 	}
 }
+
+func TestHopRespMarshal(t *testing.T) {
+	h := icmp.HopResp{
+		Num:     3,
+		Name:    "ae-1.r01.example.net",
+		IP:      "192.0.2.1",
+		Elapsed: 1.5,
+		Type:    icmp.IPv4ICMPTypeTimeExceeded,
+		Whois:   icmp.Whois{Holder: "EXAMPLE", ASN: 64500, Prefix: "192.0.2.0/24", PrefixLen: 24},
+	}
+	var r map[string]interface{}
+	if err := json.Unmarshal([]byte(h.Marshal()), &r); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if r["Id"].(float64) != 3 || r["Hop"] != "ae-1.r01.example.net" || r["ASN"].(float64) != 64500 {
+		t.Error("unexpected marshaled hop response", r)
+	}
+	if r["Type"].(float64) != 11 || r["PrefixLen"].(float64) != 24 || r["Last"] != false {
+		t.Error("unexpected marshaled hop response", r)
+	}
+
+	// the error should be valid JSON too
+	h.Elapsed = math.NaN()
+	var e map[string]string
+	if err := json.Unmarshal([]byte(h.Marshal()), &e); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if !strings.Contains(e["err"], "NaN") {
+		t.Error("unexpected marshaled error", e)
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	rp := icmp.Report{
+		Host: "example.net",
+		IP:   "192.0.2.10",
+		Hops: []icmp.ReportHop{
+			{Num: 1, IP: "192.0.2.1", Sent: 10, Loss: 10, Last: 1.2, Avg: 1.1, Best: 0.9, Wrst: 1.5, StDev: 0.2},
+			{Num: 2, Sent: 10, Loss: 100},
+			{Num: 3, Host: "example.net", IP: "192.0.2.10", ASN: 64500, Sent: 10, Avg: 9.9},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := rp.Text(&b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatal("expected 5 lines but got", len(lines))
+	}
+	if !strings.Contains(lines[3], "AS???") || !strings.Contains(lines[3], "???") || !strings.Contains(lines[3], "100.0%") {
+		t.Error("unexpected timeout hop line", lines[3])
+	}
+	if !strings.Contains(lines[4], "AS64500") || !strings.Contains(lines[4], "example.net") {
+		t.Error("unexpected target hop line", lines[4])
+	}
+
+	b.Reset()
+	if err := rp.CSV(&b); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	lines = strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 4 || lines[1] != "1,,192.0.2.1,0,,10.0,10,1.200,1.100,0.900,1.500,0.200," {
+		t.Error("unexpected csv report", lines)
+	}
+}
+
+func TestParseMPLS(t *testing.T) {
+	labels := []icmp.MPLSLabel{{Label: 24005, TTL: 1}, {Label: 16003, TC: 5, S: true, TTL: 254}}
+
+	ext := []byte{0x20, 0, 0, 0, 0, 12, 1, 1}
+	for _, l := range labels {
+		e := []byte{byte(l.Label >> 12), byte(l.Label >> 4), byte(l.Label<<4) | byte(l.TC<<1), byte(l.TTL)}
+		if l.S {
+			e[2] |= 1
+		}
+		ext = append(ext, e...)
+	}
+
+	// time exceeded w/ 128 bytes original datagram (length attribute: 32 words)
+	m := append(append([]byte{11, 0, 0, 0, 0, 32, 0, 0}, make([]byte, 128)...), ext...)
+	if r := icmp.ParseMPLS(m, 5, 4); !reflect.DeepEqual(r, labels) {
+		t.Error("unexpected labels", r)
+	}
+	// non-compliant w/o length attribute
+	m[5] = 0
+	if r := icmp.ParseMPLS(m, 5, 4); !reflect.DeepEqual(r, labels) {
+		t.Error("unexpected labels", r)
+	}
+	// wrong checksum
+	m[138], m[139] = 0xff, 0x00
+	if r := icmp.ParseMPLS(m, 5, 4); r != nil {
+		t.Error("expected no labels", r)
+	}
+	// w/o extension
+	if r := icmp.ParseMPLS(m[:36], 5, 4); r != nil {
+		t.Error("expected no labels", r)
+	}
+
+	if labels[1].String() != "L=16003,TC=5,S=1,TTL=254" {
+		t.Error("unexpected label format", labels[1])
+	}
+
+	rp := icmp.Report{Hops: []icmp.ReportHop{{Num: 1, IP: "192.0.2.1", Sent: 1, MPLS: labels}}}
+	var b bytes.Buffer
+	rp.Text(&b)
+	if !strings.Contains(b.String(), "[MPLS: L=24005,TC=0,S=0,TTL=1]") {
+		t.Error("expected MPLS labels at the report", b.String())
+	}
+}
+
+func TestBoxCompare(t *testing.T) {
+	sent := []byte{
+		0x45, 0x00, 0x00, 0x3c, 0x97, 0xb9, 0x00, 0x00, 0x05, 0x06, 0x00, 0x00,
+		192, 168, 1, 10, 192, 0, 2, 1,
+		// tcp header w/ mss, window scale, sack permitted and timestamp options
+		0xfa, 0x00, 0x82, 0x9a, 0x01, 0x02, 0x03, 0x04, 0, 0, 0, 0, 0xa0, 0x02, 0xff, 0xff, 0x12, 0x34, 0, 0,
+		2, 4, 0x05, 0xb4, 3, 3, 5, 4, 2, 1, 8, 10, 0, 0, 0, 0, 0, 0, 0, 1,
+	}
+
+	quote := append([]byte{}, sent...)
+	copy(quote[12:16], []byte{203, 0, 113, 5})
+	quote[1] = 10 << 2
+	quote[8] = 1
+	quote[42], quote[43] = 0x05, 0x64
+	quote[36], quote[37] = 0x56, 0x78
+
+	mods := icmp.BoxCompare(sent, quote)
+	expected := []icmp.Mod{
+		{Field: "IP::Src", Sent: "192.168.1.10", Recv: "203.0.113.5"},
+		{Field: "IP::DSCP", Sent: "0", Recv: "10"},
+		{Field: "TCP::Checksum", Sent: "4660", Recv: "22136"},
+		{Field: "TCP::MSS", Sent: "1460", Recv: "1380"},
+	}
+	if !reflect.DeepEqual(mods, expected) {
+		t.Error("unexpected modifications", mods)
+	}
+
+	// RFC 792 quote: ip header + 8 bytes, the TTL has been reset
+	quote = append([]byte{}, sent[:28]...)
+	quote[8] = 64
+	mods = icmp.BoxCompare(sent, quote)
+	if !reflect.DeepEqual(mods, []icmp.Mod{{Field: "IP::TTL", Sent: "1", Recv: "64"}}) {
+		t.Error("unexpected modifications", mods)
+	}
+
+	// the option removed
+	quote = append([]byte{}, sent...)
+	quote[8] = 0
+	quote[49] = 0
+	mods = icmp.BoxCompare(sent, quote)
+	if len(mods) != 1 || mods[0].Field != "TCP::Timestamp" || mods[0].Recv != "none" {
+		t.Error("unexpected modifications", mods)
+	}
+}
+
+func TestLowerMTU(t *testing.T) {
+	for _, c := range [][3]int{
+		{1500, 68, 1492},
+		{1400, 1280, 1280},
+		{1280, 1280, 1280},
+		{9001, 68, 9000},
+		{100, 68, 68},
+	} {
+		if mtu := icmp.LowerMTU(c[0], c[1]); mtu != c[2] {
+			t.Errorf("LowerMTU(%d, %d) expected %d got %d", c[0], c[1], c[2], mtu)
+		}
+	}
+}
+
+func TestTraceDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	icmp.HistoryDir = dir
+
+	start := time.Date(2016, 10, 12, 3, 0, 0, 0, time.UTC)
+	for _, rp := range []icmp.Report{
+		{Host: "example.net", Src: "192.0.2.100", Start: start.Add(5 * time.Minute), Hops: []icmp.ReportHop{
+			{Num: 1, IP: "192.0.2.1", ASN: 64500, Avg: 1},
+			{Num: 2, IP: "198.51.100.1", ASN: 64502, Avg: 5},
+			{Num: 3, IP: "203.0.113.10", ASN: 64510, Avg: 30},
+		}},
+		{Host: "example.net", Src: "192.0.2.100", Start: start, Hops: []icmp.ReportHop{
+			{Num: 1, IP: "192.0.2.1", ASN: 64500, Avg: 1.5},
+			{Num: 2, IP: "192.0.2.2", ASN: 64501, Avg: 4},
+			{Num: 3, IP: "203.0.113.10", ASN: 64510, Avg: 10},
+		}},
+		{Host: "example.net", Src: "2001:db8::1", Start: start},
+		{Host: "example.net_b", Src: "192.0.2.100", Start: start},
+		{Host: "ex[a]*", Src: "192.0.2.100", Start: start},
+	} {
+		if err := rp.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports, err := icmp.LoadReports("example.net", "192.0.2.100")
+	if err != nil || len(reports) != 2 || !reports[0].Start.Equal(start) {
+		t.Fatal("unexpected reports", reports, err)
+	}
+	if all, _ := icmp.LoadReports("example.net", ""); len(all) != 3 {
+		t.Error("expected 3 reports but got", len(all))
+	}
+	if all, _ := icmp.LoadReports("ex[a]*", ""); len(all) != 1 {
+		t.Error("expected 1 report but got", len(all))
+	}
+
+	d := icmp.DiffReports(reports[0], reports[1], 10)
+	if len(d.Removed) != 1 || d.Removed[0].IP != "192.0.2.2" || len(d.Added) != 1 || d.Added[0].IP != "198.51.100.1" {
+		t.Error("unexpected added/removed hops", d)
+	}
+	if len(d.Shifted) != 1 || d.Shifted[0].Num != 3 {
+		t.Error("unexpected latency shifts", d.Shifted)
+	}
+	if !reflect.DeepEqual(d.NewPath, []float64{64500, 64502, 64510}) {
+		t.Error("unexpected AS path", d.NewPath)
+	}
+	if icmp.DiffReports(reports[1], reports[1], 10).Changed() {
+		t.Error("expected no change")
+	}
+}
+
+func TestTopology(t *testing.T) {
+	tp := icmp.NewTopology()
+	tp.AddPath("a.example", "192.0.2.100", []icmp.HopResp{
+		{Num: 1, IP: "192.0.2.1", Elapsed: 1},
+		{Num: 2, IP: "198.51.100.1", Elapsed: 5, Whois: icmp.Whois{ASN: 64500, Holder: "EXAMPLE-AS some network"}},
+		{Num: 3, IP: "203.0.113.10", Elapsed: 9, Last: true},
+	})
+	tp.AddPath("b.example", "192.0.2.100", []icmp.HopResp{
+		{Num: 1, IP: "192.0.2.1", Elapsed: 3},
+		{Num: 2},
+		{Num: 3, IP: "203.0.113.20", Elapsed: 12, Last: true},
+	})
+	tp.Calc()
+
+	if len(tp.Nodes) != 5 || len(tp.Edges) != 4 {
+		t.Fatal("expected 5 nodes and 4 edges but got", len(tp.Nodes), len(tp.Edges))
+	}
+	if n := tp.Nodes[0]; !n.Source || len(n.Targets) != 2 {
+		t.Error("unexpected source node", n)
+	}
+	if n := tp.Nodes[1]; n.IP != "192.0.2.1" || n.Avg != 2 || n.Best != 1 || n.Wrst != 3 {
+		t.Error("unexpected router node", n)
+	}
+	if e := tp.Edges[3]; e.From != "192.0.2.1" || e.To != "203.0.113.20" || e.Gap != 1 {
+		t.Error("unexpected edge", e)
+	}
+
+	var buf bytes.Buffer
+	tp.DOT(&buf)
+	for _, s := range []string{`subgraph "cluster_AS64500"`, `"192.0.2.1" -> "203.0.113.20" [label="1", style=dashed`,
+		`"192.0.2.100" -> "192.0.2.1" [label="2"]`} {
+		if !strings.Contains(buf.String(), s) {
+			t.Error("expected in DOT", s, buf.String())
+		}
+	}
+
+	buf.Reset()
+	tp.GraphML(&buf)
+	if !strings.Contains(buf.String(), `<edge source="198.51.100.1" target="203.0.113.10">`) ||
+		!strings.Contains(buf.String(), `<data key="holder">EXAMPLE-AS</data>`) {
+		t.Error("unexpected GraphML", buf.String())
+	}
+
+	buf.Reset()
+	tp.JSON(&buf)
+	var r icmp.Topology
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil || len(r.Nodes) != 5 {
+		t.Error("unexpected JSON", err)
+	}
+
+	targets, err := icmp.ParseTargets("192.0.2.0/30 example.net 192.0.2.2")
+	if err != nil || !reflect.DeepEqual(targets, []string{"192.0.2.1", "192.0.2.2", "example.net"}) {
+		t.Error("unexpected targets", targets, err)
+	}
+	if _, err := icmp.ParseTargets("10.0.0.0/8"); err == nil {
+		t.Error("expected too many targets error")
+	}
+}
+
+func TestBothWaysText(t *testing.T) {
+	var buf bytes.Buffer
+	bw := icmp.BothWays{
+		Forward: &icmp.Report{Host: "example.net", IP: "203.0.113.10", Src: "192.0.2.100", Hops: []icmp.ReportHop{
+			{Num: 1, IP: "192.0.2.1", ASN: 64500, Avg: 1},
+			{Num: 2, IP: "198.51.100.1", ASN: 64502, Avg: 5},
+			{Num: 3, IP: "203.0.113.10", ASN: 64510, Avg: 10},
+		}},
+		Reverse: []icmp.RevHop{
+			{Num: 1, IP: "203.0.113.1", ASN: 64510, RTT: 0.5},
+			{Num: 2},
+			{Num: 3, IP: "198.51.100.9", ASN: 64503, RTT: 6},
+			{Num: 4, IP: "192.0.2.100", ASN: 64500, RTT: 11},
+		},
+		Provider: "rs",
+		Node:     "r1",
+		MyIP:     "192.0.2.100",
+	}
+	bw.Text(&buf)
+
+	for _, s := range []string{"reverse: rs/r1 -> 192.0.2.100", "AS path forward: AS64500 [AS64502] AS64510",
+		"AS path reverse: AS64500 [AS64503] AS64510 (reversed)", "asymmetric"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Error("expected", s, buf.String())
+		}
+	}
+}
+
+func TestBothWays(t *testing.T) {
+	cfg, _ := cli.ReadDefaultConfig()
+	defer icmp.SetMyIPAddr("127.0.0.1")()
+
+	// the reverse trace should end w/o the report flag
+	tr, err := icmp.NewTrace("127.0.0.1 -rev level3/los_angeles -n -nr", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Count() != 10 {
+		t.Error("expected the report's default count but got", tr.Count())
+	}
+
+	tr, err = icmp.NewTrace("127.0.0.1 -rev level3 -c 1 -n -nr", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// traces back w/ the canned hops
+	var host string
+	rev := func(ctx context.Context, ip string) ([]icmp.RevHop, error) {
+		host = ip
+		return []icmp.RevHop{{Num: 1, IP: "192.0.2.1", RTT: 0.512}, {Num: 2, IP: "127.0.0.1", RTT: 1.024}}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bw, err := tr.BothWays(ctx, rev, "level3", "los_angeles")
+	if bw == nil && os.Geteuid() != 0 {
+		t.Skip("the trace needs the raw socket", err)
+	}
+	if bw == nil || ctx.Err() != nil {
+		t.Fatal("expected both ways before the timeout", err)
+	}
+	if host != "127.0.0.1" || len(bw.Reverse) != 2 || bw.Reverse[1].IP != "127.0.0.1" {
+		t.Error("unexpected reverse path", host, bw.Reverse)
+	}
+	if len(bw.Forward.Hops) != 1 || bw.Forward.Hops[0].IP != "127.0.0.1" {
+		t.Error("unexpected forward path", bw.Forward.Hops)
+	}
+}
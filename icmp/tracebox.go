@@ -0,0 +1,253 @@
+// Copyright 2016 Mehrdad Arshad Rad <arshad.rad@gmail.com>. All rights reserved.
+// Use of this source code is governed by a MIT license that can
+// be found in the LICENSE file.
+
+package icmp
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/ipv4"
+)
+
+// Mod represents a header field that modified on the way,
+// Sent is the sent value and Recv is the quoted one
+type Mod struct {
+	Field string `json:"Field"`
+	Sent  string `json:"Sent"`
+	Recv  string `json:"Recv"`
+}
+
+// boxFields is the order of the fields that tracebox compares
+var boxFields = []string{
+	"IP::Src", "IP::DSCP", "IP::ECN", "IP::TTL", "IP::Length", "IP::ID",
+	"UDP::SrcPort", "UDP::DstPort", "UDP::Length", "UDP::Checksum",
+	"TCP::SrcPort", "TCP::DstPort", "TCP::Seq", "TCP::Window", "TCP::Checksum",
+	"TCP::MSS", "TCP::WScale", "TCP::SACKPermitted", "TCP::Timestamp",
+}
+
+// ipv4Packet returns the sent IPv4 packet in network byte order
+func ipv4Packet(h *ipv4.Header, src net.IP, b []byte) []byte {
+	p := make([]byte, ipv4.HeaderLen, ipv4.HeaderLen+len(b))
+	p[0] = byte(ipv4.Version<<4 | ipv4.HeaderLen>>2)
+	p[1] = byte(h.TOS)
+	binary.BigEndian.PutUint16(p[2:4], uint16(h.TotalLen))
+	binary.BigEndian.PutUint16(p[4:6], uint16(h.ID))
+	p[8] = byte(h.TTL)
+	p[9] = byte(h.Protocol)
+	copy(p[12:16], src.To4())
+	copy(p[16:20], h.Dst.To4())
+	return append(p, b...)
+}
+
+// boxHeaders returns the IPv4 packet's header fields, the fields
+// which are not in the packet (short quote) don't exist
+func boxHeaders(pkt []byte) map[string]string {
+	var f = make(map[string]string)
+
+	if len(pkt) < ipv4.HeaderLen || pkt[0]>>4 != ipv4.Version {
+		return f
+	}
+	hl := int(pkt[0]&0x0f) << 2
+	if hl < ipv4.HeaderLen || len(pkt) < hl {
+		return f
+	}
+
+	f["IP::DSCP"] = strconv.Itoa(int(pkt[1] >> 2))
+	f["IP::ECN"] = strconv.Itoa(int(pkt[1] & 0x03))
+	f["IP::Length"] = uint16Str(pkt[2:4])
+	f["IP::ID"] = uint16Str(pkt[4:6])
+	f["IP::TTL"] = strconv.Itoa(int(pkt[8]))
+	f["IP::Src"] = net.IP(pkt[12:16]).String()
+
+	l4 := pkt[hl:]
+	switch pkt[9] {
+	case 17:
+		for n, field := range []string{"UDP::SrcPort", "UDP::DstPort", "UDP::Length", "UDP::Checksum"} {
+			if len(l4) >= n*2+2 {
+				f[field] = uint16Str(l4[n*2 : n*2+2])
+			}
+		}
+	case 6:
+		tcpHeaders(l4, f)
+	}
+
+	return f
+}
+
+// tcpHeaders adds the TCP header fields and options to f
+func tcpHeaders(b []byte, f map[string]string) {
+	if len(b) >= 4 {
+		f["TCP::SrcPort"] = uint16Str(b[0:2])
+		f["TCP::DstPort"] = uint16Str(b[2:4])
+	}
+	if len(b) >= 8 {
+		f["TCP::Seq"] = strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[4:8])), 10)
+	}
+	if len(b) >= 16 {
+		f["TCP::Window"] = uint16Str(b[14:16])
+	}
+	if len(b) >= 18 {
+		f["TCP::Checksum"] = uint16Str(b[16:18])
+	}
+
+	// the options are comparable once they're quoted completely
+	if len(b) < 13 {
+		return
+	}
+	off := int(b[12]>>4) << 2
+	if off < 20 || len(b) < off {
+		return
+	}
+
+	for _, field := range []string{"TCP::MSS", "TCP::WScale", "TCP::SACKPermitted", "TCP::Timestamp"} {
+		f[field] = "none"
+	}
+
+	for opts := b[20:off]; len(opts) > 0; {
+		kind := opts[0]
+		if kind == 0 {
+			break
+		}
+		if kind == 1 {
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || opts[1] < 2 || int(opts[1]) > len(opts) {
+			break
+		}
+		data := opts[2:opts[1]]
+		switch {
+		case kind == 2 && len(data) == 2:
+			f["TCP::MSS"] = uint16Str(data)
+		case kind == 3 && len(data) == 1:
+			f["TCP::WScale"] = strconv.Itoa(int(data[0]))
+		case kind == 4:
+			f["TCP::SACKPermitted"] = "yes"
+		case kind == 8:
+			f["TCP::Timestamp"] = hex.EncodeToString(data)
+		}
+		opts = opts[opts[1]:]
+	}
+}
+
+// boxCompare returns the fields that quoted w/ different values, the
+// quoted TTL is expected to be one (or zero by some routers)
+func boxCompare(sent, quote []byte) []Mod {
+	var (
+		mods []Mod
+		s    = boxHeaders(sent)
+		q    = boxHeaders(quote)
+	)
+
+	for _, field := range boxFields {
+		sv, ok1 := s[field]
+		qv, ok2 := q[field]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if field == "IP::TTL" {
+			if qv != "0" && qv != "1" {
+				mods = append(mods, Mod{Field: field, Sent: "1", Recv: qv})
+			}
+			continue
+		}
+		if sv != qv {
+			mods = append(mods, Mod{Field: field, Sent: sv, Recv: qv})
+		}
+	}
+
+	return mods
+}
+
+// PrintTracebox prints out the hops that modified the probe's headers,
+// each hop shows the modifications that weren't at the previous hop
+func (i *Trace) PrintTracebox(ctx context.Context) {
+	var (
+		proto = "icmp"
+		prev  []Mod
+		first = make(map[string]int)
+		all   []Mod
+	)
+
+	if i.udp {
+		proto = "udp"
+	} else if i.tcp {
+		proto = "tcp"
+	}
+
+	resp, err := i.Run(ctx, 1)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	fmt.Printf("tracebox to %s (%s), %d hops max, %s\n", i.host, i.ip, i.maxTTL, proto)
+
+	for r := range resp {
+		h := r[0]
+		if h.Err != nil {
+			println(h.Err.Error())
+			return
+		}
+		if h.IP == "" {
+			fmt.Printf("%-2d *\n", h.Num)
+			continue
+		}
+
+		var msg []string
+		for _, m := range h.Mods {
+			if hasMod(prev, m) {
+				continue
+			}
+			msg = append(msg, fmt.Sprintf("%s %s->%s", m.Field, m.Sent, m.Recv))
+			if _, ok := first[m.Field]; !ok {
+				first[m.Field] = h.Num
+				all = append(all, m)
+			}
+		}
+		prev = h.Mods
+
+		fmt.Println(strings.TrimSpace(fmt.Sprintf("%-2d %s%.3f ms  %s", h.Num, fmtHopAddr(h), h.Elapsed, strings.Join(msg, "  "))))
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if len(all) == 0 {
+		fmt.Println("no header modification detected")
+		return
+	}
+	fmt.Println("modifications:")
+	for _, m := range all {
+		fmt.Printf("  %-20s %s -> %s at hop %d\n", m.Field, m.Sent, m.Recv, first[m.Field])
+	}
+}
+
+// fmtHopAddr formats the hop's name and ip address
+func fmtHopAddr(h HopResp) string {
+	if h.Name != "" {
+		return fmt.Sprintf("%s (%s) ", h.Name, h.IP)
+	}
+	return h.IP + " "
+}
+
+// hasMod returns true if the modification exists at mods
+func hasMod(mods []Mod, m Mod) bool {
+	for _, mod := range mods {
+		if mod == m {
+			return true
+		}
+	}
+	return false
+}
+
+func uint16Str(b []byte) string {
+	return strconv.Itoa(int(binary.BigEndian.Uint16(b)))
+}
@@ -0,0 +1,377 @@
+package lg
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// BGPRoute represents a BGP path of a prefix
+type BGPRoute struct {
+	Prefix      string   `json:"prefix"`
+	ASPath      string   `json:"as_path"`
+	Origin      string   `json:"origin"`
+	Communities []string `json:"communities"`
+	LocalPref   int      `json:"local_pref"`
+	MED         int      `json:"med"`
+	NextHop     string   `json:"next_hop"`
+	From        string   `json:"from"`
+	Best        bool     `json:"best"`
+}
+
+var (
+	bgpPrefixRgx = regexp.MustCompile(`^([0-9a-fA-F.:]+/\d{1,3})\b`)
+
+	// Juniper
+	junosPathRgx  = regexp.MustCompile(`^\s*(\*?)\s*(?:\[BGP/\d+\]|BGP\s+Preference:)`)
+	junosASPath   = regexp.MustCompile(`AS path:\s*([^,]*)`)
+	junosComm     = regexp.MustCompile(`Communities:\s*(.*)$`)
+	junosLPref    = regexp.MustCompile(`(?i)\blocalpref:?\s+(\d+)`)
+	junosMED      = regexp.MustCompile(`(?i)\b(?:MED|Metric):?\s+(\d+)`)
+	junosProtoNH  = regexp.MustCompile(`Protocol next hop:\s*(\S+)`)
+	junosNH       = regexp.MustCompile(`(?:Next hop:|>\s+to)\s*([0-9a-fA-F.:]+)`)
+	junosFrom     = regexp.MustCompile(`(?:Source:|\bfrom)\s+([0-9a-fA-F.:]+)`)
+	junosASPathOr = map[string]string{"I": "IGP", "E": "EGP", "?": "incomplete"}
+
+	// Cisco
+	iosPrefix = regexp.MustCompile(`BGP routing table entry for (\S+?),`)
+	iosNH     = regexp.MustCompile(`^\s+([0-9a-fA-F.:]+)(?:\s+\(.*?\))?\s+from\s+([0-9a-fA-F.:]+)`)
+	iosOrigin = regexp.MustCompile(`Origin (IGP|EGP|incomplete)`)
+	iosMED    = regexp.MustCompile(`\bmetric (\d+)`)
+	iosLPref  = regexp.MustCompile(`\blocalpref (\d+)`)
+	iosComm   = regexp.MustCompile(`^\s*(?:Large )?Community:\s*(.*)$`)
+
+	// BIRD
+	birdPathRgx = regexp.MustCompile(`^(\S+/\d+)?\s+(?:unicast|via|blackhole|unreachable)\b.*?\[(\S+)[^\]]*\]\s*(\*)?`)
+	birdVia     = regexp.MustCompile(`\bvia\s+([0-9a-fA-F.:]+)`)
+	birdAttr    = regexp.MustCompile(`^\s*BGP\.(\w+):\s*(.*)$`)
+	birdComm    = regexp.MustCompile(`\(([^)]*)\)`)
+)
+
+// ParseBGP parses the looking glass BGP output, the format can be
+// junos, ios or bird. it tries to detect the format if it's empty
+func ParseBGP(lines []string, format string) []BGPRoute {
+	var text []string
+	for _, l := range lines {
+		text = append(text, strings.Split(l, "\n")...)
+	}
+
+	if format == "" {
+		format = detectFormat(text)
+	}
+
+	switch format {
+	case "ios":
+		return parseIOS(text)
+	case "bird":
+		return parseBIRD(text)
+	default:
+		return parseJunos(text)
+	}
+}
+
+// detectFormat returns the output format based on the keywords
+func detectFormat(lines []string) string {
+	for _, l := range lines {
+		switch {
+		case strings.Contains(l, "BGP.as_path"):
+			return "bird"
+		case strings.Contains(l, "AS path:"):
+			return "junos"
+		case iosOrigin.MatchString(l), iosPrefix.MatchString(l):
+			return "ios"
+		}
+	}
+	return "junos"
+}
+
+// parseJunos parses Juniper show route detail or terse output
+func parseJunos(lines []string) []BGPRoute {
+	var (
+		routes []BGPRoute
+		r      *BGPRoute
+		prefix string
+		nh     bool
+	)
+
+	for _, l := range lines {
+		if m := bgpPrefixRgx.FindStringSubmatch(l); len(m) > 1 {
+			prefix = m[1]
+			l = l[len(m[0]):]
+		}
+		if m := junosPathRgx.FindStringSubmatch(l); len(m) > 1 {
+			routes = append(routes, BGPRoute{Prefix: prefix, Best: m[1] == "*"})
+			r, nh = &routes[len(routes)-1], false
+		}
+		if r == nil {
+			continue
+		}
+		if m := junosASPath.FindStringSubmatch(l); len(m) > 1 {
+			r.ASPath, r.Origin = junosPath(m[1])
+		}
+		if m := junosComm.FindStringSubmatch(l); len(m) > 1 {
+			r.Communities = strings.Fields(m[1])
+		}
+		if m := junosLPref.FindStringSubmatch(l); len(m) > 1 {
+			r.LocalPref, _ = strconv.Atoi(m[1])
+		}
+		if m := junosMED.FindStringSubmatch(l); len(m) > 1 {
+			r.MED, _ = strconv.Atoi(m[1])
+		}
+		if m := junosProtoNH.FindStringSubmatch(l); len(m) > 1 {
+			r.NextHop, nh = m[1], true
+		} else if m := junosNH.FindStringSubmatch(l); len(m) > 1 && !nh && r.NextHop == "" {
+			r.NextHop = m[1]
+		}
+		if m := junosFrom.FindStringSubmatch(l); len(m) > 1 && r.From == "" {
+			r.From = m[1]
+		}
+	}
+
+	return routes
+}
+
+// junosPath returns AS path and origin from "15169 I (Atomic)"
+func junosPath(s string) (string, string) {
+	var (
+		path   []string
+		origin string
+	)
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "(") {
+			break
+		}
+		if o, ok := junosASPathOr[f]; ok {
+			origin = o
+			continue
+		}
+		path = append(path, f)
+	}
+	return strings.Join(path, " "), origin
+}
+
+// parseIOS parses Cisco show ip bgp output
+func parseIOS(lines []string) []BGPRoute {
+	var (
+		routes []BGPRoute
+		r      *BGPRoute
+		prefix string
+		prev   string
+	)
+
+	for _, l := range lines {
+		if m := iosPrefix.FindStringSubmatch(l); len(m) > 1 {
+			prefix = m[1]
+		}
+		// the AS path line is followed by next hop from peer
+		if m := iosNH.FindStringSubmatch(l); len(m) > 2 {
+			path := strings.TrimSpace(prev)
+			if i := strings.Index(path, ","); i > -1 {
+				path = path[:i]
+			}
+			if path == "Local" {
+				path = ""
+			}
+			routes = append(routes, BGPRoute{Prefix: prefix, ASPath: path, NextHop: m[1], From: m[2]})
+			r = &routes[len(routes)-1]
+		}
+		if strings.TrimSpace(l) != "" {
+			prev = l
+		}
+		if r == nil {
+			continue
+		}
+		if m := iosOrigin.FindStringSubmatch(l); len(m) > 1 {
+			r.Origin = m[1]
+			if m := iosMED.FindStringSubmatch(l); len(m) > 1 {
+				r.MED, _ = strconv.Atoi(m[1])
+			}
+			if m := iosLPref.FindStringSubmatch(l); len(m) > 1 {
+				r.LocalPref, _ = strconv.Atoi(m[1])
+			}
+			r.Best = strings.Contains(l, "best")
+		}
+		if m := iosComm.FindStringSubmatch(l); len(m) > 1 {
+			r.Communities = append(r.Communities, strings.Fields(m[1])...)
+		}
+	}
+
+	return routes
+}
+
+// parseBIRD parses BIRD show route all output
+func parseBIRD(lines []string) []BGPRoute {
+	var (
+		routes []BGPRoute
+		r      *BGPRoute
+		prefix string
+	)
+
+	for _, l := range lines {
+		if m := birdPathRgx.FindStringSubmatch(l); len(m) > 3 {
+			if m[1] != "" {
+				prefix = m[1]
+			}
+			routes = append(routes, BGPRoute{Prefix: prefix, From: m[2], Best: m[3] == "*"})
+			r = &routes[len(routes)-1]
+		}
+		if r == nil {
+			continue
+		}
+		if m := birdVia.FindStringSubmatch(l); len(m) > 1 && r.NextHop == "" {
+			r.NextHop = m[1]
+		}
+		m := birdAttr.FindStringSubmatch(l)
+		if len(m) < 3 {
+			continue
+		}
+		switch m[1] {
+		case "origin":
+			r.Origin = m[2]
+		case "as_path":
+			r.ASPath = m[2]
+		case "next_hop":
+			r.NextHop = strings.Fields(m[2] + " ")[0]
+		case "local_pref":
+			r.LocalPref, _ = strconv.Atoi(m[2])
+		case "med":
+			r.MED, _ = strconv.Atoi(m[2])
+		case "community", "large_community":
+			for _, c := range birdComm.FindAllStringSubmatch(m[2], -1) {
+				c := strings.Replace(c[1], " ", "", -1)
+				r.Communities = append(r.Communities, strings.Replace(c, ",", ":", -1))
+			}
+		}
+	}
+
+	return routes
+}
+
+// OriginAS returns the last AS at the AS path
+func (r *BGPRoute) OriginAS() string {
+	f := strings.Fields(r.ASPath)
+	if len(f) == 0 {
+		return "local"
+	}
+	return strings.Trim(f[len(f)-1], "{}[]")
+}
+
+// routeLines returns the routes in text format
+func routeLines(routes []BGPRoute) []string {
+	var (
+		lines  []string
+		prefix string
+	)
+
+	for i, r := range routes {
+		if i == 0 || r.Prefix != prefix {
+			prefix = r.Prefix
+			lines = append(lines, prefix)
+		}
+		var best string
+		if r.Best {
+			best = " (best)"
+		}
+		lines = append(lines, fmt.Sprintf("  via %s from %s%s", r.NextHop, r.From, best))
+		lines = append(lines, fmt.Sprintf("    AS path: %s, origin %s, localpref %d, med %d", r.ASPath, r.Origin, r.LocalPref, r.MED))
+		if len(r.Communities) > 0 {
+			lines = append(lines, "    communities: "+strings.Join(r.Communities, " "))
+		}
+	}
+
+	return lines
+}
+
+// bestRoutes returns the best paths or all if there is no best flag
+func bestRoutes(routes []BGPRoute) []BGPRoute {
+	var best []BGPRoute
+	for _, r := range routes {
+		if r.Best {
+			best = append(best, r)
+		}
+	}
+	if len(best) == 0 {
+		return routes
+	}
+	return best
+}
+
+// PrintCompare prints the providers best paths side by side
+// and the origin AS / prefix differences
+func PrintCompare(w io.Writer, routes map[string][]BGPRoute, errs map[string]error) {
+	var (
+		names    []string
+		origins  = make(map[string][]string)
+		prefixes = make(map[string][]string)
+	)
+
+	for name := range routes {
+		names = append(names, name)
+	}
+	for name := range errs {
+		if _, ok := routes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Provider", "Prefix", "AS Path", "Origin", "Next Hop", "LPref", "MED", "Communities"})
+	table.SetAutoWrapText(false)
+
+	for _, name := range names {
+		if err, ok := errs[name]; ok && len(routes[name]) == 0 {
+			table.Append([]string{name, "", "", "", "", "", "", err.Error()})
+			continue
+		}
+		for _, r := range bestRoutes(routes[name]) {
+			table.Append([]string{
+				name,
+				r.Prefix,
+				r.ASPath,
+				r.Origin,
+				r.NextHop,
+				strconv.Itoa(r.LocalPref),
+				strconv.Itoa(r.MED),
+				strings.Join(r.Communities, " "),
+			})
+			origins[r.OriginAS()] = appendUniq(origins[r.OriginAS()], name)
+			prefixes[r.Prefix] = appendUniq(prefixes[r.Prefix], name)
+		}
+	}
+	table.Render()
+
+	if len(origins) > 1 {
+		fmt.Fprintln(w, "warning: different origin AS")
+		printGroups(w, origins, "AS")
+	}
+	if len(prefixes) > 1 {
+		fmt.Fprintln(w, "warning: different prefixes")
+		printGroups(w, prefixes, "")
+	}
+}
+
+func printGroups(w io.Writer, groups map[string][]string, label string) {
+	var keys []string
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s%-20s %s\n", label, k, strings.Join(groups[k], ", "))
+	}
+}
+
+func appendUniq(s []string, v string) []string {
+	for _, i := range s {
+		if i == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
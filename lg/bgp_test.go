@@ -0,0 +1,118 @@
+package lg_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestParseBGPJunos(t *testing.T) {
+	out := `inet.0: 812345 destinations, 4064102 routes (812000 active, 0 holddown, 10 hidden)
+8.8.8.0/24 (2 entries, 1 announced)
+        *BGP    Preference: 170/-101
+                Next hop type: Indirect, Next hop index: 0
+                Source: 62.115.1.1
+                Next hop: 62.115.2.2 via ae1.0, selected
+                Protocol next hop: 62.115.1.1
+                Age: 3w3d 20:45:02 	Metric: 10 	Metric2: 20
+                AS path: 15169 I
+                Communities: 1299:30000 1299:35000
+                Localpref: 200
+         BGP    Preference: 170/-101
+                Source: 62.115.3.3
+                Protocol next hop: 62.115.3.3
+                AS path: 3356 15169 I (Originator)
+                Localpref: 100`
+
+	routes := lg.ParseBGP(strings.Split(out, "\n"), "")
+	expected := []lg.BGPRoute{
+		{Prefix: "8.8.8.0/24", ASPath: "15169", Origin: "IGP", Communities: []string{"1299:30000", "1299:35000"},
+			LocalPref: 200, MED: 10, NextHop: "62.115.1.1", From: "62.115.1.1", Best: true},
+		{Prefix: "8.8.8.0/24", ASPath: "3356 15169", Origin: "IGP", LocalPref: 100, NextHop: "62.115.3.3", From: "62.115.3.3"},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+
+	terse := []string{
+		"8.8.8.0/24         *[BGP/170] 3w3d 20:45:02, MED 5, localpref 100, from 62.115.1.1",
+		"                      AS path: 15169 ?, validation-state: unverified",
+		"                    > to 62.115.2.2 via ae1.0",
+	}
+	routes = lg.ParseBGP(terse, "junos")
+	if len(routes) != 1 || routes[0].MED != 5 || routes[0].Origin != "incomplete" || routes[0].NextHop != "62.115.2.2" || !routes[0].Best {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+}
+
+func TestParseBGPIOS(t *testing.T) {
+	out := `BGP routing table entry for 8.8.8.0/24, version 123
+Paths: (2 available, best #1)
+  Advertised to update-groups:
+     1
+  15169
+    38.1.1.1 (metric 10) from 38.2.2.2 (38.2.2.2)
+      Origin IGP, metric 0, localpref 100, valid, internal, best
+      Community: 174:21001 174:22013
+  3356 15169, (aggregated by 15169 8.8.8.8)
+    4.68.1.1 from 4.68.1.1 (4.68.1.1)
+      Origin incomplete, metric 20, localpref 90, valid, external`
+
+	routes := lg.ParseBGP(strings.Split(out, "\n"), "")
+	expected := []lg.BGPRoute{
+		{Prefix: "8.8.8.0/24", ASPath: "15169", Origin: "IGP", Communities: []string{"174:21001", "174:22013"},
+			LocalPref: 100, NextHop: "38.1.1.1", From: "38.2.2.2", Best: true},
+		{Prefix: "8.8.8.0/24", ASPath: "3356 15169", Origin: "incomplete", LocalPref: 90, MED: 20, NextHop: "4.68.1.1", From: "4.68.1.1"},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+}
+
+func TestParseBGPBIRD(t *testing.T) {
+	out := "8.8.8.0/24           unicast [rs1 2020-01-01] * (100) [AS15169i]\n" +
+		"\tvia 192.0.2.1 on eth0\n" +
+		"\tBGP.origin: IGP\n" +
+		"\tBGP.as_path: 64500 15169\n" +
+		"\tBGP.next_hop: 192.0.2.1\n" +
+		"\tBGP.local_pref: 100\n" +
+		"\tBGP.community: (64500,1) (64500,2)\n" +
+		"                     unicast [rs2 2020-01-01] (100) [AS15169i]\n" +
+		"\tvia 192.0.2.2 on eth0\n" +
+		"\tBGP.med: 50\n" +
+		"\tBGP.large_community: (64500, 1, 2)"
+
+	routes := lg.ParseBGP([]string{out}, "")
+	expected := []lg.BGPRoute{
+		{Prefix: "8.8.8.0/24", ASPath: "64500 15169", Origin: "IGP", Communities: []string{"64500:1", "64500:2"},
+			LocalPref: 100, NextHop: "192.0.2.1", From: "rs1", Best: true},
+		{Prefix: "8.8.8.0/24", Communities: []string{"64500:1:2"}, MED: 50, NextHop: "192.0.2.2", From: "rs2"},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+}
+
+func TestPrintCompare(t *testing.T) {
+	var buf bytes.Buffer
+
+	lg.PrintCompare(&buf, map[string][]lg.BGPRoute{
+		"telia":  {{Prefix: "8.8.8.0/24", ASPath: "15169", Best: true}, {Prefix: "8.8.8.0/24", ASPath: "3356 15169"}},
+		"cogent": {{Prefix: "8.8.8.0/24", ASPath: "174 15169"}},
+		"ntt":    {{Prefix: "8.8.8.0/25", ASPath: "2914 64666"}},
+	}, map[string]error{"kpn": errors.New("no route found")})
+
+	out := buf.String()
+	for _, s := range []string{"no route found", "different origin AS", "AS64666", "different prefixes", "8.8.8.0/25"} {
+		if !strings.Contains(out, s) {
+			t.Error("expected to see", s, "\n", out)
+		}
+	}
+	if strings.Contains(out, "3356 15169") {
+		t.Error("expected only the best path")
+	}
+}
@@ -0,0 +1,130 @@
+package lg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const birdSocket = "/var/run/bird/bird.ctl"
+
+// bird represents BIRD routing daemon control socket
+type bird struct{}
+
+func (b *bird) bgp(ctx context.Context, addr, prefix string) ([]string, error) {
+	if err := validPrefix(prefix); err != nil {
+		return nil, err
+	}
+	if addr == "" {
+		addr = birdSocket
+	}
+	return birdQuery(ctx, addr, "show route for "+prefix+" all")
+}
+
+func (b *bird) routes(ctx context.Context, addr, prefix string) ([]BGPRoute, error) {
+	lines, err := b.bgp(ctx, addr, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBGP(lines, "bird"), nil
+}
+
+// birdQuery sends a command through the control socket
+// and returns the reply lines
+func birdQuery(ctx context.Context, socket, query string) ([]string, error) {
+	d := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	// unblocks the reply reading once it's canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	// welcome message
+	_, err = birdReply(r)
+	if err == nil {
+		_, err = fmt.Fprintf(conn, "%s\n", query)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	lines, err := birdReply(r)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return lines, err
+}
+
+// birdReply reads a reply, each line starts w/ a four digits code
+// and '-' or just a space for the continuation, the reply ends at
+// the code w/ space. the codes 8xxx and 9xxx are errors
+func birdReply(r *bufio.Reader) ([]string, error) {
+	var lines []string
+
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+		l = strings.TrimRight(l, "\r\n")
+
+		if !birdCode(l) {
+			lines = append(lines, strings.TrimPrefix(l, " "))
+			continue
+		}
+
+		code, text := l[:4], ""
+		if len(l) > 5 {
+			text = l[5:]
+		}
+
+		if code[0] == '8' || code[0] == '9' {
+			return lines, errors.New(text)
+		}
+		if text != "" {
+			lines = append(lines, text)
+		}
+		if len(l) == 4 || l[4] == ' ' {
+			return lines, nil
+		}
+	}
+}
+
+// birdCode checks if the line starts w/ a reply code
+func birdCode(l string) bool {
+	if len(l) < 4 || (len(l) > 4 && l[4] != '-' && l[4] != ' ') {
+		return false
+	}
+	for _, c := range l[:4] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
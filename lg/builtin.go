@@ -0,0 +1,157 @@
+package lg
+
+import "github.com/mehrdadrad/mylg/cli"
+
+// builtin returns the built-in looking glasses definitions, they
+// have the same format as the configuration definitions
+func builtin() []cli.LGProvider {
+	return []cli.LGProvider{
+		// Telia Carrier Looking Glass ASN 1299
+		{
+			Name:    "telia",
+			Format:  "junos",
+			Default: "Los Angeles",
+			Nodes: cli.LGNodes{
+				URL:   "http://looking-glass.telia.net/",
+				Match: `(?i)<option value="(?s)([\w|\s|)(._-]+)"> (?s)([\w|\s|)(._-]+)`,
+			},
+			Ping: cli.LGCommand{
+				URL:   "http://looking-glass.telia.net/",
+				Form:  map[string]string{"query": "ping", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Match: `<CODE>(?s)(.*?)</CODE>`,
+			},
+			Trace: cli.LGCommand{
+				URL:   "http://looking-glass.telia.net/",
+				Form:  map[string]string{"query": "trace", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Match: `^(?:traceroute|\s*\d{1,2})`,
+				ASN:   true,
+			},
+			BGP: cli.LGCommand{
+				URL:      "http://looking-glass.telia.net/",
+				Form:     map[string]string{"query": "bgp", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Start:    "Telia Carrier",
+				Sanitize: true,
+				Dedup:    true,
+			},
+		},
+		// Level3 Carrier Looking Glass ASN 3356
+		{
+			Name:    "level3",
+			Default: "Los Angeles, CA",
+			Nodes: cli.LGNodes{
+				URL:    "http://lookingglass.level3.net/ping/lg_ping_main.php",
+				Match:  `(?i)<option value="(?s)([\w|\s|)(._-]+)">(?s)([a-z|\s|)(,._-]+)</option>`,
+				Name:   2,
+				Value:  1,
+				Static: map[string]string{"Los Angeles, CA": "ear1.lax1"},
+			},
+			Ping: cli.LGCommand{
+				URL:      "http://lookingglass.level3.net/ping/lg_ping_output.php",
+				Form:     map[string]string{"count": "5", "size": "64", "address": "${addr}", "sitename": "${node}"},
+				Match:    `</div></div>(?s)(.*?)</font></pre>`,
+				Sanitize: true,
+			},
+			Trace: cli.LGCommand{
+				URL:      "http://lookingglass.level3.net/traceroute/lg_tr_output.php",
+				Form:     map[string]string{"address": "${addr}", "sitename": "${node}"},
+				Match:    `(?i)(?:^traceroute|\s+\d{1,2})\s+`,
+				Sanitize: true,
+			},
+			BGP: cli.LGCommand{
+				URL:      "http://lookingglass.level3.net/bgp/lg_bgp_output.php",
+				Form:     map[string]string{"address": "${addr}", "length": "${len}", "sitename": "${node}"},
+				Match:    `(Route results.*)`,
+				Sanitize: true,
+			},
+		},
+		// Cogent Carrier Looking Glass ASN 174
+		{
+			Name:    "cogent",
+			Format:  "ios",
+			Default: "US - Los Angeles",
+			Nodes: cli.LGNodes{
+				URL:   "http://www.cogentco.com/lookingglass.php",
+				Match: `(?is)Option\("([\w|,|\s|-]+)","([\w|\d]+)"`,
+				Value: 2,
+				After: "default:",
+			},
+			Ping: cli.LGCommand{
+				URL:   "http://www.cogentco.com/lookingglass.php",
+				Form:  map[string]string{"FKT": "go!", "CMD": "P${af}", "DST": "${host}", "LOC": "${node}"},
+				Match: `<pre>(?s)(.*?)</pre>`,
+			},
+			Trace: cli.LGCommand{
+				URL:   "http://www.cogentco.com/lookingglass.php",
+				Form:  map[string]string{"FKT": "go!", "CMD": "T${af}", "DST": "${host}", "LOC": "${node}"},
+				Match: `^(?:traceroute|\s*\d{1,2})`,
+				ASN:   true,
+			},
+			BGP: cli.LGCommand{
+				URL:  "http://www.cogentco.com/lookingglass.php",
+				Form: map[string]string{"FKT": "go!", "CMD": "BGP", "DST": "${host}", "LOC": "${node}"},
+				Nodes: &cli.LGNodes{
+					URL:    "http://www.cogentco.com/lookingglass.php",
+					Match:  `(?is)Option\("([\w|,|\s|-]+)","([\w|\d]+)"`,
+					Value:  2,
+					Before: "default:",
+				},
+			},
+		},
+		// NTT communications Looking Glass
+		{
+			Name:    "ntt",
+			Format:  "junos",
+			Default: "Los Angeles, CA - US",
+			Notice:  "please check NTT Com LG terms of use first at https://www.us.ntt.net/support/looking-glass/",
+			Nodes: cli.LGNodes{
+				URL:   "http://ssp.pme.gin.ntt.net/lg/lg.cgi",
+				Match: `(?i)<option value="(?s)([\w|\s|)(,._-]+)"> (?s)([\w|\s|)(,._-]+)`,
+			},
+			Ping: cli.LGCommand{
+				URL:   "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+				Form:  map[string]string{"query": "ping", "protocol": "${ipv}", "addrFQDN": "${host}", "router": "${node}", "sourceIP": "FQDN"},
+				Match: `<CODE>(?s)(.*?)</CODE>`,
+			},
+			Trace: cli.LGCommand{
+				URL:   "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+				Form:  map[string]string{"query": "trace", "protocol": "${ipv}", "addrFQDN": "${host}", "router": "${node}", "sourceIP": "FQDN"},
+				Match: `(?i)^(?:tracing|traceroute|\s*\d{1,2})`,
+				ASN:   true,
+			},
+			BGP: cli.LGCommand{
+				URL:      "https://ssp.pme.gin.ntt.net/lg/lg.cgi",
+				Form:     map[string]string{"query": "bgp", "protocol": "${ipv}", "addr": "${host}", "router": "${node}", "sourceIP": "IP"},
+				Start:    "Query Results",
+				Sanitize: true,
+				Dedup:    true,
+			},
+		},
+		// KPN Looking Glass ASN 286
+		{
+			Name:    "kpn",
+			Default: "Amsterdam (NL)",
+			Nodes: cli.LGNodes{
+				URL:   "http://lg.eurorings.net/index.cgi",
+				Match: `(?i)<option value="(?s)([\w|\s|)(._-]+)"> (?s)([\w|\s|)(._-]+)`,
+			},
+			Ping: cli.LGCommand{
+				URL:   "http://lg.eurorings.net/index.cgi",
+				Form:  map[string]string{"query": "ping", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Match: `<CODE>(?s)(.*?)</CODE>`,
+			},
+			Trace: cli.LGCommand{
+				URL:   "http://lg.eurorings.net/index.cgi",
+				Form:  map[string]string{"query": "trace", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Match: `^(?:traceroute|\s*\d{1,2})`,
+				ASN:   true,
+			},
+			BGP: cli.LGCommand{
+				URL:      "http://lg.eurorings.net/index.cgi",
+				Form:     map[string]string{"query": "bgp", "protocol": "${ipv}", "addr": "${host}", "router": "${node}"},
+				Start:    "Location:",
+				Sanitize: true,
+				Dedup:    true,
+			},
+		},
+	}
+}
@@ -0,0 +1,141 @@
+package lg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// daemon represents a route server daemon which
+// is queried natively instead of a web page
+type daemon interface {
+	bgp(ctx context.Context, addr, prefix string) ([]string, error)
+	routes(ctx context.Context, addr, prefix string) ([]BGPRoute, error)
+}
+
+// cmdReader represents a running command output
+type cmdReader struct {
+	*io.PipeReader
+	cmd *exec.Cmd
+}
+
+// run starts a command and returns its output
+func run(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+	}()
+
+	return &cmdReader{pr, cmd}, nil
+}
+
+// Close stops the command if it's still running
+func (r *cmdReader) Close() error {
+	r.cmd.Process.Kill()
+	return r.PipeReader.Close()
+}
+
+// fields splits the exec command around the spaces, a single
+// or double quoted part is kept as one argument w/o the quotes
+func fields(s string) ([]string, error) {
+	var (
+		args  []string
+		arg   []rune
+		quote rune
+		inArg bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg = append(arg, r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = arg[:0], false
+			}
+		default:
+			arg, inArg = append(arg, r), true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in exec command")
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty exec command")
+	}
+
+	return args, nil
+}
+
+// output runs a command and returns its standard output
+func output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// validPrefix checks the lookup argument to avoid
+// passing anything else to the daemon
+func validPrefix(prefix string) error {
+	if net.ParseIP(prefix) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(prefix); err == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid prefix %s", prefix)
+}
+
+// hostname is a strict DNS name w/o the shell special characters
+var hostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*\.?$`)
+
+// validHost checks the host before it's passed
+// to the ping or trace exec command
+func validHost(host string) error {
+	if validPrefix(host) == nil || hostname.MatchString(host) {
+		return nil
+	}
+	return errors.New("invalid host/ip address")
+}
+
+// afi returns the address family of the prefix
+func afi(prefix string) string {
+	if strings.Contains(prefix, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
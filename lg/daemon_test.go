@@ -0,0 +1,284 @@
+package lg_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func bgpLines(t *testing.T, def cli.LGProvider, prefix string) []string {
+	p, err := lg.NewProvider(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set(prefix, "ipv4")
+
+	var lines []string
+	for l := range p.BGP() {
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// fakeCommand creates a command which prints out the output
+func fakeCommand(t *testing.T, dir, output string) string {
+	f := filepath.Join(dir, "cmd")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s.args\ncat <<'EOF'\n%s\nEOF\n", f, output)
+	if err := ioutil.WriteFile(f, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestBIRD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "bird.ctl")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprint(conn, "0001 BIRD 2.0.7 ready.\n")
+			q, _ := bufio.NewReader(conn).ReadString('\n')
+			if strings.Contains(q, "8.8.8.8") {
+				fmt.Fprint(conn, "1007-8.8.8.0/24      unicast [rs1 10:00:00.000] * (100) [AS15169i]\n"+
+					" \tvia 192.0.2.1 on eth0\n"+
+					"1012-\tBGP.as_path: 64500 15169\n"+
+					"0000 \n")
+			} else {
+				fmt.Fprint(conn, "8001 Network not found\n")
+			}
+			conn.Close()
+		}
+	}()
+
+	def := cli.LGProvider{Name: "rs", Type: "bird", Address: socket}
+	lines := bgpLines(t, def, "8.8.8.8")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "8.8.8.0/24") || lines[2] != "\tBGP.as_path: 64500 15169" {
+		t.Error("unexpected bird result", lines)
+	}
+
+	lines = bgpLines(t, def, "10.0.0.1")
+	if len(lines) != 1 || lines[0] != "Network not found" {
+		t.Error("expected bird error", lines)
+	}
+
+	lines = bgpLines(t, def, "8.8.8.8 all\nconfigure")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "invalid prefix") {
+		t.Error("expected invalid prefix", lines)
+	}
+}
+
+func TestFRR(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := fakeCommand(t, dir, `{"prefix":"8.8.8.0/24","paths":[{"aspath":{"string":"64500 15169"},"origin":"IGP",
+	"metric":10,"localpref":200,"community":{"string":"64500:1"},"bestpath":{"overall":true},
+	"nexthops":[{"ip":"192.0.2.1"}],"peer":{"peerId":"192.0.2.1"}}]}`)
+
+	lines := bgpLines(t, cli.LGProvider{Name: "rs", Type: "frr", Command: cmd}, "8.8.8.8")
+	expected := []string{
+		"8.8.8.0/24",
+		"  via 192.0.2.1 from 192.0.2.1 (best)",
+		"    AS path: 64500 15169, origin IGP, localpref 200, med 10",
+		"    communities: 64500:1",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Error("unexpected frr result", lines)
+	}
+
+	args, _ := ioutil.ReadFile(cmd + ".args")
+	if strings.TrimSpace(string(args)) != "-c show bgp ipv4 unicast 8.8.8.8 json" {
+		t.Error("unexpected vtysh args", string(args))
+	}
+}
+
+func TestGoBGP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := fakeCommand(t, dir, `{"8.8.8.0/24":[{"nlri":{"prefix":"8.8.8.0/24"},"attrs":[{"type":1,"value":0},
+	{"type":2,"as_paths":[{"segment_type":2,"num":2,"asns":[64500,15169]}]},{"type":3,"nexthop":"192.0.2.1"},
+	{"type":4,"metric":0},{"type":8,"communities":[4227072001]}],"age":1,"neighbor-ip":"192.0.2.1"}]}`)
+
+	def := cli.LGProvider{Name: "rs", Type: "gobgp", Command: cmd, Address: "127.0.0.1:50051"}
+	lines := bgpLines(t, def, "8.8.8.0/24")
+	expected := []string{
+		"8.8.8.0/24",
+		"  via 192.0.2.1 from 192.0.2.1 (best)",
+		"    AS path: 64500 15169, origin IGP, localpref 0, med 0",
+		"    communities: 64500:1",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Error("unexpected gobgp result", lines)
+	}
+
+	args, _ := ioutil.ReadFile(cmd + ".args")
+	if strings.TrimSpace(string(args)) != "-u 127.0.0.1 -p 50051 global rib -a ipv4 8.8.8.0/24 -j" {
+		t.Error("unexpected gobgp args", string(args))
+	}
+}
+
+func TestDaemonContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the daemon accepts and never replies
+	socket := filepath.Join(dir, "bird.ctl")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cmd := filepath.Join(dir, "vtysh")
+	if err := ioutil.WriteFile(cmd, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, def := range []cli.LGProvider{
+		{Name: "rs", Type: "bird", Address: socket},
+		{Name: "rs", Type: "frr", Command: cmd},
+	} {
+		p, err := lg.NewProvider(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Set("8.8.8.8", "ipv4")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		_, err = p.BGPRoutesContext(ctx, "8.8.8.8")
+		cancel()
+		if err == nil || time.Since(start) > 5*time.Second {
+			t.Error("expected canceled lookup", def.Type, err, time.Since(start))
+		}
+	}
+}
+
+func TestDaemonExec(t *testing.T) {
+	def := cli.LGProvider{
+		Name:  "rs",
+		Type:  "bird",
+		Ping:  cli.LGCommand{Exec: "echo ping ${host}"},
+		Trace: cli.LGCommand{Exec: "echo trace ${host}"},
+	}
+	p, err := lg.NewProvider(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set("192.0.2.1", "ipv4")
+
+	if r, err := p.Ping(); err != nil || strings.TrimSpace(r) != "ping 192.0.2.1" {
+		t.Error("unexpected ping result", r, err)
+	}
+	if l := <-p.Trace(); l != "trace 192.0.2.1" {
+		t.Error("unexpected trace result", l)
+	}
+
+	for _, host := range []string{"-f192.0.2.1", "8.8.8.8;reboot", "$(reboot)", "8.8.8.8 -f"} {
+		p.Set(host, "ipv4")
+		if _, err := p.Ping(); err == nil {
+			t.Error("expected invalid host error", host)
+		}
+		if _, ok := <-p.Trace(); ok {
+			t.Error("unexpected trace result", host)
+		}
+	}
+
+	p.Set("dns.google.", "ipv4")
+	if r, err := p.Ping(); err != nil || strings.TrimSpace(r) != "ping dns.google." {
+		t.Error("unexpected ping result", r, err)
+	}
+}
+
+func TestDaemonExecQuote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mylg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the README frr trace example w/ a fake vtysh
+	vtysh := filepath.Join(dir, "vtysh")
+	script := "#!/bin/sh\n[ $# -eq 2 ] && [ \"$1\" = -c ] && echo \"$2\"\n"
+	if err := ioutil.WriteFile(vtysh, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	def := cli.LGProvider{
+		Name:  "rs2",
+		Type:  "frr",
+		Ping:  cli.LGCommand{Exec: vtysh + ` -c "ping ${host}" x'`},
+		Trace: cli.LGCommand{Exec: vtysh + " -c 'traceroute ${host}'"},
+	}
+	p, err := lg.NewProvider(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set("8.8.8.8", "ipv4")
+
+	if l := <-p.Trace(); l != "traceroute 8.8.8.8" {
+		t.Error("unexpected trace result", l)
+	}
+	if _, err := p.Ping(); err == nil || !strings.Contains(err.Error(), "quote") {
+		t.Error("expected unterminated quote error", err)
+	}
+}
+
+func TestDaemonNoExec(t *testing.T) {
+	p, err := lg.NewProvider(cli.LGProvider{Name: "rs", Type: "frr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set("192.0.2.1", "ipv4")
+
+	// the route server doesn't fall back to the local ping / trace
+	if _, err := p.Ping(); err == nil || !strings.Contains(err.Error(), "exec") {
+		t.Error("expected the exec command error", err)
+	}
+	if _, ok := <-p.Trace(); ok {
+		t.Error("unexpected local trace")
+	}
+}
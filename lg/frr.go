@@ -0,0 +1,105 @@
+package lg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// frr represents FRRouting through vtysh JSON output
+type frr struct {
+	bin string
+}
+
+// frrRoute represents vtysh show bgp json output
+type frrRoute struct {
+	Prefix string `json:"prefix"`
+	Paths  []struct {
+		ASPath struct {
+			String string `json:"string"`
+		} `json:"aspath"`
+		Origin    string `json:"origin"`
+		Med       int    `json:"metric"`
+		LocalPref int    `json:"localpref"`
+		Community struct {
+			String string `json:"string"`
+		} `json:"community"`
+		LargeCommunity struct {
+			String string `json:"string"`
+		} `json:"largeCommunity"`
+		Bestpath struct {
+			Overall bool `json:"overall"`
+		} `json:"bestpath"`
+		Nexthops []struct {
+			IP string `json:"ip"`
+		} `json:"nexthops"`
+		Peer struct {
+			PeerID string `json:"peerId"`
+		} `json:"peer"`
+	} `json:"paths"`
+}
+
+func (f *frr) bgp(ctx context.Context, addr, prefix string) ([]string, error) {
+	routes, err := f.routes(ctx, addr, prefix)
+	return routeLines(routes), err
+}
+
+func (f *frr) routes(ctx context.Context, addr, prefix string) ([]BGPRoute, error) {
+	var (
+		bin  = "vtysh"
+		args []string
+	)
+
+	if err := validPrefix(prefix); err != nil {
+		return nil, err
+	}
+	if f.bin != "" {
+		bin = f.bin
+	}
+	if addr != "" {
+		args = append(args, "--vty_socket", addr)
+	}
+	args = append(args, "-c", "show bgp "+afi(prefix)+" unicast "+prefix+" json")
+
+	b, err := output(ctx, bin, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFRR(b)
+}
+
+// parseFRR parses vtysh show bgp json output
+func parseFRR(b []byte) ([]BGPRoute, error) {
+	var (
+		fr     frrRoute
+		routes []BGPRoute
+	)
+
+	if err := json.Unmarshal(b, &fr); err != nil {
+		return nil, err
+	}
+	if fr.Prefix == "" {
+		return nil, errors.New("network not in table")
+	}
+
+	for _, p := range fr.Paths {
+		r := BGPRoute{
+			Prefix:    fr.Prefix,
+			From:      p.Peer.PeerID,
+			ASPath:    p.ASPath.String,
+			Origin:    p.Origin,
+			LocalPref: p.LocalPref,
+			MED:       p.Med,
+			Best:      p.Bestpath.Overall,
+		}
+		if len(p.Nexthops) > 0 {
+			r.NextHop = p.Nexthops[0].IP
+		}
+		r.Communities = append(strings.Fields(p.Community.String), strings.Fields(p.LargeCommunity.String)...)
+		routes = append(routes, r)
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,142 @@
+package lg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// gobgp represents GoBGP through the gobgp command (gRPC API) JSON output
+type gobgp struct {
+	bin string
+}
+
+// gobgpPath represents gobgp global rib json output path
+type gobgpPath struct {
+	Nlri struct {
+		Prefix string `json:"prefix"`
+	} `json:"nlri"`
+	Attrs []struct {
+		Type        int            `json:"type"`
+		Value       int            `json:"value"`
+		Nexthop     string         `json:"nexthop"`
+		Metric      int            `json:"metric"`
+		ASPaths     []gobgpSegment `json:"as_paths"`
+		Communities []uint32       `json:"communities"`
+	} `json:"attrs"`
+	Best       bool   `json:"best"`
+	NeighborIP string `json:"neighbor-ip"`
+	SourceID   string `json:"source-id"`
+}
+
+// gobgpSegment represents an AS path segment
+type gobgpSegment struct {
+	Type int      `json:"segment_type"`
+	ASNs []uint32 `json:"asns"`
+}
+
+func (g *gobgp) bgp(ctx context.Context, addr, prefix string) ([]string, error) {
+	routes, err := g.routes(ctx, addr, prefix)
+	return routeLines(routes), err
+}
+
+func (g *gobgp) routes(ctx context.Context, addr, prefix string) ([]BGPRoute, error) {
+	var (
+		bin  = "gobgp"
+		args []string
+	)
+
+	if err := validPrefix(prefix); err != nil {
+		return nil, err
+	}
+	if g.bin != "" {
+		bin = g.bin
+	}
+	if addr != "" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-u", host, "-p", port)
+	}
+	args = append(args, "global", "rib", "-a", afi(prefix), prefix, "-j")
+
+	b, err := output(ctx, bin, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGoBGP(b)
+}
+
+// parseGoBGP parses gobgp global rib json output
+func parseGoBGP(b []byte) ([]BGPRoute, error) {
+	var (
+		dsts   map[string][]gobgpPath
+		routes []BGPRoute
+	)
+
+	if err := json.Unmarshal(b, &dsts); err != nil {
+		return nil, err
+	}
+
+	for prefix, paths := range dsts {
+		for i, p := range paths {
+			r := BGPRoute{
+				Prefix: prefix,
+				From:   p.NeighborIP,
+				Best:   p.Best || (i == 0 && len(paths) == 1),
+			}
+			if r.From == "" {
+				r.From = p.SourceID
+			}
+			for _, a := range p.Attrs {
+				switch a.Type {
+				case 1:
+					r.Origin = [...]string{"IGP", "EGP", "incomplete"}[a.Value%3]
+				case 2:
+					r.ASPath = gobgpASPath(a.ASPaths)
+				case 3, 14:
+					r.NextHop = a.Nexthop
+				case 4:
+					r.MED = a.Metric
+				case 5:
+					r.LocalPref = a.Value
+				case 8:
+					for _, c := range a.Communities {
+						r.Communities = append(r.Communities, fmt.Sprintf("%d:%d", c>>16, c&0xffff))
+					}
+				}
+			}
+			routes = append(routes, r)
+		}
+		// it's a single destination lookup
+		break
+	}
+
+	if len(routes) == 0 {
+		return nil, errors.New("network not in table")
+	}
+
+	return routes, nil
+}
+
+// gobgpASPath returns AS path in text, the AS set is in braces
+func gobgpASPath(segments []gobgpSegment) string {
+	var path []string
+	for _, s := range segments {
+		var asns []string
+		for _, asn := range s.ASNs {
+			asns = append(asns, fmt.Sprintf("%d", asn))
+		}
+		if s.Type == 1 {
+			path = append(path, "{"+strings.Join(asns, ",")+"}")
+		} else {
+			path = append(path, asns...)
+		}
+	}
+	return strings.Join(path, " ")
+}
@@ -6,8 +6,13 @@ import (
 	"testing"
 )
 
+func level3() *lg.Provider {
+	providers, _ := lg.Load(nil)
+	return providers["level3"]
+}
+
 func TestGetDefaultNode(t *testing.T) {
-	var level3 lg.Level3
+	level3 := level3()
 	if level3.GetDefaultNode() != "Los Angeles, CA" {
 		t.Error("Level3 default node expected Los Angeles, CA but", level3.GetDefaultNode)
 	}
@@ -27,7 +32,7 @@ func TestFetchNodes(t *testing.T) {
 				<OPTION value="bear1.xrs2">Belgrade, Serbia</OPTION>
 				</SELECT></td><td>
 		`)
-	var level3 lg.Level3
+	level3 := level3()
 	nodes := level3.FetchNodes()
 	if len(nodes) != 3 {
 		t.Error("expected to have 3 nodes but they are", len(nodes))
@@ -48,7 +53,7 @@ func TestPing(t *testing.T) {
 			`statistics ----<br>1 packets transmitted, 1 packets received, 0% packet loss<br>` +
 			`rtt min/avg/median/max/mdev/stddev = 0.297/0.311/0.307/0.325/0.097/0.01 ms<br></font></pre><br></div></body></html>
 		`)
-	var level3 lg.Level3
+	level3 := level3()
 	level3.Set("127.0.0.1", "ipv4")
 	p, err := level3.Ping()
 	if err != nil {
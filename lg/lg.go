@@ -0,0 +1,687 @@
+// Package lg provides looking glass methods for the looking glasses
+// which are defined at built-in or configuration definitions
+package lg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+)
+
+var errNodeNotSupported = errors.New("current node doesn't support it")
+
+// nodesTimeout is the default timeout of fetching the nodes
+const nodesTimeout = 30 * time.Second
+
+// A Provider represents a looking glass request
+type Provider struct {
+	Host  string
+	Addr  string
+	Len   string
+	IPv   string
+	Node  string
+	Nodes []string
+
+	name    string
+	def     cli.LGProvider
+	nodes   *nodeSource
+	ping    *command
+	trace   *command
+	bgp     *command
+	daemon  daemon
+	ctx     context.Context
+	nodesMu sync.Mutex
+}
+
+// command represents a compiled looking glass command
+type command struct {
+	cli.LGCommand
+	match *regexp.Regexp
+	start *regexp.Regexp
+	nodes *nodeSource
+}
+
+// nodeSource represents a compiled nodes definition w/ cache
+type nodeSource struct {
+	cli.LGNodes
+	match *regexp.Regexp
+	cache map[string]string
+	sync.Mutex
+}
+
+// Load returns the built-in looking glasses along w/ the configured
+// ones, a configured looking glass replaces the built-in w/ same name
+func Load(defs []cli.LGProvider) (map[string]*Provider, error) {
+	var (
+		providers = make(map[string]*Provider)
+		errs      []string
+	)
+
+	for _, def := range append(builtin(), defs...) {
+		p, err := NewProvider(def)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		providers[p.name] = p
+	}
+
+	if len(errs) > 0 {
+		return providers, errors.New(strings.Join(errs, "\n"))
+	}
+
+	return providers, nil
+}
+
+// NewProvider validates a looking glass definition and
+// returns the provider
+func NewProvider(def cli.LGProvider) (*Provider, error) {
+	var err error
+
+	p := &Provider{name: strings.ToLower(def.Name), def: def}
+	if p.name == "" {
+		return nil, errors.New("looking glass name is required")
+	}
+
+	if p.nodes, err = newNodeSource(&def.Nodes); err != nil {
+		return nil, fmt.Errorf("%s nodes: %s", p.name, err)
+	}
+	if p.ping, err = newCommand(def.Ping); err != nil {
+		return nil, fmt.Errorf("%s ping: %s", p.name, err)
+	}
+	if p.trace, err = newCommand(def.Trace); err != nil {
+		return nil, fmt.Errorf("%s trace: %s", p.name, err)
+	}
+	if p.bgp, err = newCommand(def.BGP); err != nil {
+		return nil, fmt.Errorf("%s bgp: %s", p.name, err)
+	}
+
+	switch strings.ToLower(def.Type) {
+	case "", "http":
+	case "bird":
+		p.daemon = &bird{}
+	case "frr":
+		p.daemon = &frr{bin: def.Command}
+	case "gobgp":
+		p.daemon = &gobgp{bin: def.Command}
+	default:
+		return nil, fmt.Errorf("%s: unknown looking glass type %s", p.name, def.Type)
+	}
+
+	return p, nil
+}
+
+func newCommand(def cli.LGCommand) (*command, error) {
+	var (
+		cmd = &command{LGCommand: def}
+		err error
+	)
+
+	if def.Match != "" {
+		if cmd.match, err = regexp.Compile(def.Match); err != nil {
+			return nil, err
+		}
+	}
+	if def.Start != "" {
+		if cmd.start, err = regexp.Compile(def.Start); err != nil {
+			return nil, err
+		}
+	}
+	if def.Nodes != nil {
+		if cmd.nodes, err = newNodeSource(def.Nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	return cmd, nil
+}
+
+func newNodeSource(def *cli.LGNodes) (*nodeSource, error) {
+	var (
+		n   = &nodeSource{LGNodes: *def}
+		err error
+	)
+
+	if def.URL != "" && def.Match == "" {
+		return nil, errors.New("nodes match is required")
+	}
+	if def.Match != "" {
+		if n.match, err = regexp.Compile(def.Match); err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+// Set configures host and ip version
+func (p *Provider) Set(host, version string) {
+	p.Host = host
+	if i := strings.Index(host, "/"); i > 0 {
+		p.Addr = host[:i]
+		p.Len = host[i+1:]
+	} else {
+		p.Addr = host
+		p.Len = "24"
+	}
+	p.IPv = version
+	if p.Node == "" {
+		p.Node = p.def.Default
+	}
+}
+
+// PingNode pings the host through the node w/o changing the
+// current node and host, it's safe for concurrent use
+func (p *Provider) PingNode(ctx context.Context, node, host string) (string, error) {
+	return p.withNode(ctx, node, host).Ping()
+}
+
+// TraceNode traces the host from the node w/o changing the
+// current node and host, it's safe for concurrent use
+func (p *Provider) TraceNode(ctx context.Context, node, host string) chan string {
+	return p.withNode(ctx, node, host).Trace()
+}
+
+// BGPRoutesContext returns the prefix's BGP paths through the current
+// node w/o changing the current host, it's safe for concurrent use
+func (p *Provider) BGPRoutesContext(ctx context.Context, prefix string) ([]BGPRoute, error) {
+	return p.withNode(ctx, p.Node, prefix).BGPRoutes()
+}
+
+// withNode returns a copy of the provider for the node and host
+func (p *Provider) withNode(ctx context.Context, node, host string) *Provider {
+	n := &Provider{
+		Node:   node,
+		name:   p.name,
+		def:    p.def,
+		nodes:  p.nodes,
+		ping:   p.ping,
+		trace:  p.trace,
+		bgp:    p.bgp,
+		daemon: p.daemon,
+		ctx:    ctx,
+	}
+	if strings.Contains(host, ":") {
+		n.Set(host, "ipv6")
+	} else {
+		n.Set(host, "ipv4")
+	}
+	return n
+}
+
+// Timeout returns the looking glass timeout if it's configured
+// otherwise the default timeout
+func (p *Provider) Timeout(d time.Duration) time.Duration {
+	if t, err := time.ParseDuration(p.def.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return d
+}
+
+// context returns the request context
+func (p *Provider) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// GetDefaultNode returns the looking glass default node
+func (p *Provider) GetDefaultNode() string {
+	if p.def.Notice != "" {
+		println(p.def.Notice)
+	}
+	return p.def.Default
+}
+
+// GetNodes returns all the looking glass nodes
+func (p *Provider) GetNodes() []string {
+	return p.GetNodesContext(p.context())
+}
+
+// GetNodesContext returns all the looking glass nodes, the
+// context cancels fetching the nodes
+func (p *Provider) GetNodesContext(ctx context.Context) []string {
+	p.nodesMu.Lock()
+	defer p.nodesMu.Unlock()
+	// Memory cache
+	if len(p.Nodes) > 1 {
+		return p.Nodes
+	}
+	var nodes []string
+	for node := range p.nodes.fetch(ctx, p.name, p.Timeout(nodesTimeout)) {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	p.Nodes = nodes
+	return nodes
+}
+
+// FetchNodes returns all available nodes and their values
+func (p *Provider) FetchNodes() map[string]string {
+	return p.nodeMap(p.nodes)
+}
+
+// nodeMap returns the nodes of the source w/ the request
+// context and the looking glass timeout
+func (p *Provider) nodeMap(n *nodeSource) map[string]string {
+	return n.fetch(p.context(), p.name, p.Timeout(nodesTimeout))
+}
+
+// ChangeNode set new requested node
+func (p *Provider) ChangeNode(node string) bool {
+	p.nodesMu.Lock()
+	defer p.nodesMu.Unlock()
+	// Validate
+	for _, n := range p.Nodes {
+		if node == n {
+			p.Node = node
+			return true
+		}
+	}
+	return false
+}
+
+// Ping tries to connect the looking glass through HTTP
+// or the local command, returns the result
+func (p *Provider) Ping() (string, error) {
+	// Basic validate
+	if p.Node == "NA" || len(p.Host) < 5 {
+		print("Invalid node or host/ip address")
+		return "", errors.New("error")
+	}
+	r, err := p.open(p.ping, p.nodes)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if p.ping.match == nil {
+		return p.ping.result(string(body)), nil
+	}
+	b := p.ping.match.FindStringSubmatch(string(body))
+	if len(b) > 1 {
+		return p.ping.result(b[1]), nil
+	} else if len(b) > 0 {
+		return p.ping.result(b[0]), nil
+	}
+	return "", errors.New("error")
+}
+
+// Trace gets traceroute information from the looking glass
+func (p *Provider) Trace() chan string {
+	return p.stream(p.trace)
+}
+
+// BGP gets bgp information from the looking glass
+// or the route server daemon
+func (p *Provider) BGP() chan string {
+	if p.daemon == nil || p.bgp.URL != "" || p.bgp.Exec != "" {
+		return p.stream(p.bgp)
+	}
+
+	c := make(chan string)
+	go func() {
+		lines, err := p.daemon.bgp(p.context(), p.address(), p.Host)
+		for _, l := range lines {
+			c <- l
+		}
+		if err != nil {
+			c <- err.Error()
+		}
+		close(c)
+	}()
+	return c
+}
+
+// BGPRoutes returns the parsed BGP paths from the looking glass
+// or the route server daemon
+func (p *Provider) BGPRoutes() ([]BGPRoute, error) {
+	var lines []string
+
+	if p.daemon != nil && p.bgp.URL == "" && p.bgp.Exec == "" {
+		return p.daemon.routes(p.context(), p.address(), p.Host)
+	}
+
+	c, err := p.lines(p.bgp)
+	if err != nil {
+		return nil, err
+	}
+	for l := range c {
+		lines = append(lines, l)
+	}
+
+	routes := ParseBGP(lines, p.def.Format)
+	if len(routes) == 0 {
+		return nil, errors.New("no route found")
+	}
+
+	return routes, nil
+}
+
+// stream runs a command and sends the result line by line
+func (p *Provider) stream(cmd *command) chan string {
+	c, err := p.lines(cmd)
+	if err == errNodeNotSupported {
+		println("current node doesn't support it, please select one of the below nodes:")
+		c = make(chan string)
+		go func() {
+			for name := range p.nodeMap(cmd.nodes) {
+				c <- name
+			}
+			close(c)
+		}()
+	} else if err != nil {
+		println(err.Error())
+		c = make(chan string)
+		close(c)
+	}
+	return c
+}
+
+// lines runs a command and returns the result lines
+func (p *Provider) lines(cmd *command) (chan string, error) {
+	c := make(chan string)
+
+	nodes := p.nodes
+	if cmd.nodes != nil {
+		nodes = cmd.nodes
+		if _, ok := p.nodeMap(nodes)[p.Node]; !ok {
+			return nil, errNodeNotSupported
+		}
+	}
+
+	r, err := p.open(cmd, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+
+	go func() {
+		var (
+			parse = cmd.start == nil
+			last  string
+		)
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+	LOOP:
+		for scanner.Scan() {
+			l := scanner.Text()
+			if !parse {
+				parse = cmd.start.MatchString(l)
+				continue
+			}
+			if cmd.match != nil {
+				m := cmd.match.FindStringSubmatch(l)
+				if m == nil {
+					continue
+				}
+				if len(m) > 1 {
+					l = m[1]
+				}
+			}
+			l = cmd.result(l)
+			if cmd.Dedup && l == last {
+				continue
+			}
+			last = l
+			select {
+			case <-sigCh:
+				break LOOP
+			case c <- l:
+			}
+		}
+		signal.Stop(sigCh)
+		close(c)
+	}()
+
+	return c, nil
+}
+
+// open returns the command result reader through
+// the local command or HTTP
+func (p *Provider) open(cmd *command, nodes *nodeSource) (io.ReadCloser, error) {
+	if cmd.Exec == "" {
+		resp, err := p.request(cmd, nodes)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	}
+
+	// the command may run through a remote shell, e.g. ssh
+	if err := validHost(p.Host); err != nil {
+		return nil, err
+	}
+
+	args, err := fields(cmd.Exec)
+	if err != nil {
+		return nil, err
+	}
+	for i := range args {
+		args[i] = p.expand(args[i], p.address())
+	}
+
+	return run(p.context(), args[0], args[1:]...)
+}
+
+// address returns the current node value or the
+// daemon address if there is no node
+func (p *Provider) address() string {
+	if v, ok := p.nodeMap(p.nodes)[p.Node]; ok {
+		return v
+	}
+	return p.def.Address
+}
+
+// request sends the command request to the looking glass
+func (p *Provider) request(cmd *command, nodes *nodeSource) (*http.Response, error) {
+	var (
+		req  *http.Request
+		form = url.Values{}
+		node = p.Node
+		err  error
+	)
+
+	if cmd.URL == "" && p.daemon != nil {
+		// a local run wouldn't be through the route server
+		return nil, fmt.Errorf("%s route server needs the exec command, e.g. \"ssh %s ping -c 5 ${host}\"", p.name, p.name)
+	}
+	if cmd.URL == "" {
+		return nil, fmt.Errorf("%s looking glass doesn't support it", p.name)
+	}
+
+	if v, ok := p.nodeMap(nodes)[p.Node]; ok {
+		node = v
+	}
+
+	for k, v := range cmd.Form {
+		form.Set(k, p.expand(v, node))
+	}
+	u := p.expand(cmd.URL, node)
+
+	switch strings.ToUpper(cmd.Method) {
+	case "GET":
+		if len(form) > 0 {
+			if strings.Contains(u, "?") {
+				u += "&" + form.Encode()
+			} else {
+				u += "?" + form.Encode()
+			}
+		}
+		req, err = http.NewRequest("GET", u, nil)
+	default:
+		req, err = http.NewRequest("POST", u, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(p.context()))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error: %s looking glass is not available", p.name)
+	}
+
+	return resp, nil
+}
+
+// expand replaces the template variables
+func (p *Provider) expand(s, node string) string {
+	return os.Expand(s, func(k string) string {
+		switch k {
+		case "host":
+			return p.Host
+		case "addr":
+			return p.Addr
+		case "len":
+			return p.Len
+		case "ipv":
+			return p.IPv
+		case "af":
+			if p.IPv == "ipv6" {
+				return "6"
+			}
+			return "4"
+		case "node":
+			return node
+		case "name":
+			return p.Node
+		}
+		return ""
+	})
+}
+
+// result applies the command post processing
+func (cmd *command) result(s string) string {
+	if cmd.ASN {
+		s = replaceASNTrace(s)
+	}
+	if cmd.Sanitize {
+		s = sanitize(s)
+	}
+	return s
+}
+
+// fetch returns the nodes, it fetches them through HTTP
+// once if the source has URL, the context and the timeout
+// limit the request
+func (n *nodeSource) fetch(ctx context.Context, name string, timeout time.Duration) map[string]string {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.cache != nil {
+		return n.cache
+	}
+
+	// static nodes are available if there is no source
+	// or the source is unreachable
+	if n.URL == "" {
+		n.cache = n.Static
+		return n.cache
+	}
+
+	var (
+		nodes  = make(map[string]string, 100)
+		client = &http.Client{Timeout: timeout}
+	)
+	req, err := http.NewRequest("GET", n.URL, nil)
+	if err != nil {
+		println("error: " + name + " looking glass nodes source is not valid")
+		return n.Static
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		println("error: " + name + " looking glass unreachable (1)")
+		return n.Static
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		println("error: " + name + " looking glass unreachable (2)" + err.Error())
+		return n.Static
+	}
+	body := string(b)
+	if i := strings.Index(body, n.After); n.After != "" && i > -1 {
+		body = body[i:]
+	}
+	if i := strings.Index(body, n.Before); n.Before != "" && i > -1 {
+		body = body[:i]
+	}
+
+	for _, v := range n.match.FindAllStringSubmatch(body, -1) {
+		var key, value = group(v, n.Name, 1), group(v, n.Value, 0)
+		if value == "" {
+			value = key
+		}
+		nodes[key] = value
+	}
+
+	if len(nodes) == 0 {
+		return n.Static
+	}
+
+	n.cache = nodes
+	return nodes
+}
+
+// group returns the requested submatch or default one
+func group(m []string, i, d int) string {
+	if i == 0 {
+		i = d
+	}
+	if i < 1 || i >= len(m) {
+		return ""
+	}
+	return m[i]
+}
+
+// sanitize removes html tags
+func sanitize(b string) string {
+	re := regexp.MustCompile(`<br>`)
+	b = re.ReplaceAllString(b, "\n")
+	re = regexp.MustCompile(`<[^>]*>`)
+	b = re.ReplaceAllString(b, "")
+	return html.UnescapeString(b)
+}
+
+// [GOOGLE (ARIN)" HREF="http://www.arin.net/cgi-bin/whois.pl?queryinput=15169" TARGET=_lookup>15169</A>]  1.261 ms 72.14.236.69 (72.14.236.69) [AS  <A title="GOOGLE (ARIN)" HREF="http://www.arin.net/cgi-bin/whois.pl?queryinput=15169" TARGET=_lookup>15169</A>]
+// replaceASNTrace
+func replaceASNTrace(l string) string {
+	m, _ := regexp.MatchString(`\[AS\s+`, l)
+	if !m {
+		return l
+	}
+	r := regexp.MustCompile(`(?i)\[AS\s+<A\s+title="([a-z|\d|\s|\(\)_,-]+)"\s+HREF="[a-z|\/|:.-]+\?\w+=\d+"\s+\w+=_lookup>(\d+)</A>]`)
+	asn := r.FindStringSubmatch(l)
+	if len(asn) == 3 {
+		l = r.ReplaceAllString(l, fmt.Sprintf("[%s (%s)]", asn[1], asn[2]))
+	}
+	return l
+}
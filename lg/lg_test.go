@@ -0,0 +1,159 @@
+package lg_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestLoad(t *testing.T) {
+	providers, err := lg.Load([]cli.LGProvider{
+		{Name: "Internal", Default: "core1"},
+		{Name: "telia", Default: "Stockholm"},
+		{Name: "broken", Ping: cli.LGCommand{Match: "(["}},
+	})
+	if err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	for _, name := range []string{"telia", "level3", "cogent", "ntt", "kpn", "internal"} {
+		if _, ok := providers[name]; !ok {
+			t.Error("expected provider", name)
+		}
+	}
+	if _, ok := providers["broken"]; ok {
+		t.Error("unexpected broken provider")
+	}
+	if providers["telia"].GetDefaultNode() != "Stockholm" {
+		t.Error("expected configured telia replaces the built-in")
+	}
+}
+
+func TestProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nodes":
+			fmt.Fprint(w, `<option value="r1">Router 1</option><option value="r2">Router 2</option>`)
+		case "/ping":
+			fmt.Fprintf(w, "<pre>%s %s %s</pre>", r.FormValue("cmd"), r.FormValue("dst"), r.FormValue("router"))
+		case "/bgp":
+			fmt.Fprint(w, "header\nResults\n<b>10.0.0.0/8</b>\n10.0.0.0/8\nvia 192.0.2.1\n")
+		}
+	}))
+	defer ts.Close()
+
+	p, err := lg.NewProvider(cli.LGProvider{
+		Name:    "internal",
+		Default: "Router 1",
+		Nodes:   cli.LGNodes{URL: ts.URL + "/nodes", Match: `<option value="(\w+)">([\w\s]+)<`, Name: 2, Value: 1},
+		Ping: cli.LGCommand{
+			URL:    ts.URL + "/ping",
+			Method: "GET",
+			Form:   map[string]string{"cmd": "ping${af}", "dst": "${addr}", "router": "${node}"},
+			Match:  `<pre>(.*)</pre>`,
+		},
+		BGP: cli.LGCommand{URL: ts.URL + "/bgp", Start: "Results", Sanitize: true, Dedup: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nodes := p.GetNodes(); len(nodes) != 2 || nodes[1] != "Router 2" {
+		t.Error("unexpected nodes", nodes)
+	}
+	if !p.ChangeNode("Router 2") {
+		t.Error("expected node changed")
+	}
+
+	p.Set("192.0.2.0/24", "ipv4")
+	if r, err := p.Ping(); err != nil || r != "ping4 192.0.2.0 r2" {
+		t.Error("unexpected ping result", r, err)
+	}
+
+	var lines []string
+	for l := range p.BGP() {
+		lines = append(lines, l)
+	}
+	if len(lines) != 2 || lines[0] != "10.0.0.0/8" || lines[1] != "via 192.0.2.1" {
+		t.Error("unexpected bgp result", lines)
+	}
+
+	if _, ok := <-p.Trace(); ok {
+		t.Error("expected unsupported trace")
+	}
+}
+
+func TestGetNodesContext(t *testing.T) {
+	hang := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer ts.Close()
+	defer close(hang)
+
+	p, err := lg.NewProvider(cli.LGProvider{
+		Name:    "hung",
+		Timeout: "10s",
+		Nodes:   cli.LGNodes{URL: ts.URL, Match: `(\w+)`, Static: map[string]string{"r1": "r1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	ts0 := time.Now()
+	nodes := p.GetNodesContext(ctx)
+	if time.Since(ts0) > 5*time.Second {
+		t.Error("expected the canceled context stops fetching the nodes")
+	}
+	if len(nodes) != 1 || nodes[0] != "r1" {
+		t.Error("expected the static nodes", nodes)
+	}
+}
+
+func TestBGPRoutesContext(t *testing.T) {
+	var (
+		got = make(map[string]string)
+		mu  sync.Mutex
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got[r.FormValue("prefix")] = r.FormValue("af")
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	p, err := lg.NewProvider(cli.LGProvider{
+		Name:    "internal",
+		Default: "core1",
+		BGP: cli.LGCommand{
+			URL:    ts.URL,
+			Method: "GET",
+			Form:   map[string]string{"prefix": "${host}", "af": "${af}"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Set("192.0.2.1", "ipv4")
+
+	expected := map[string]string{"8.8.8.0/24": "4", "2001:db8::/32": "6"}
+	for prefix := range expected {
+		p.BGPRoutesContext(context.Background(), prefix)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Error("unexpected address families", got)
+	}
+	if p.Host != "192.0.2.1" || p.IPv != "ipv4" {
+		t.Error("expected the current host unchanged", p.Host, p.IPv)
+	}
+}
@@ -0,0 +1,126 @@
+package lg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PingStats represents a looking glass ping statistics
+type PingStats struct {
+	Sent int     `json:"sent"`
+	Recv int     `json:"recv"`
+	Loss float64 `json:"loss"`
+	Min  float64 `json:"min"`
+	Avg  float64 `json:"avg"`
+	Max  float64 `json:"max"`
+}
+
+// PingResult represents a looking glass node ping result
+type PingResult struct {
+	Provider string    `json:"provider"`
+	Node     string    `json:"node"`
+	Stats    PingStats `json:"stats"`
+	Err      error     `json:"-"`
+}
+
+var (
+	pingPktsRgx  = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingLossRgx  = regexp.MustCompile(`([\d.]+)% packet loss`)
+	pingRTTRgx   = regexp.MustCompile(`(?:rtt|round-trip)(?:\s+\(ms\))?\s+([a-z/]+)\s*=\s*([\d./]+)`)
+	pingCiscoRgx = regexp.MustCompile(`Success rate is (\d+) percent \((\d+)/(\d+)\)`)
+)
+
+// ParsePing parses the ping output of Linux, BSD, Juniper
+// and Cisco routers and returns the statistics
+func ParsePing(out string) (PingStats, error) {
+	var (
+		stats PingStats
+		found bool
+	)
+
+	if m := pingPktsRgx.FindStringSubmatch(out); len(m) > 2 {
+		stats.Sent, _ = strconv.Atoi(m[1])
+		stats.Recv, _ = strconv.Atoi(m[2])
+		found = true
+	} else if m := pingCiscoRgx.FindStringSubmatch(out); len(m) > 3 {
+		stats.Recv, _ = strconv.Atoi(m[2])
+		stats.Sent, _ = strconv.Atoi(m[3])
+		found = true
+	}
+
+	if !found {
+		return stats, errors.New("unknown ping output")
+	}
+
+	if m := pingLossRgx.FindStringSubmatch(out); len(m) > 1 {
+		stats.Loss, _ = strconv.ParseFloat(m[1], 64)
+	} else if stats.Sent > 0 {
+		stats.Loss = float64(stats.Sent-stats.Recv) * 100 / float64(stats.Sent)
+	}
+
+	// the order is different e.g. min/avg/median/max/mdev
+	if m := pingRTTRgx.FindStringSubmatch(out); len(m) > 2 {
+		names, values := strings.Split(m[1], "/"), strings.Split(m[2], "/")
+		for i := 0; i < len(names) && i < len(values); i++ {
+			v, _ := strconv.ParseFloat(values[i], 64)
+			switch names[i] {
+			case "min":
+				stats.Min = v
+			case "avg":
+				stats.Avg = v
+			case "max":
+				stats.Max = v
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// PrintPingAll prints out the nodes ping results
+func PrintPingAll(w io.Writer, host string, results []PingResult) {
+	var reachable, failed int
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Provider != results[j].Provider {
+			return results[i].Provider < results[j].Provider
+		}
+		return results[i].Node < results[j].Node
+	})
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Provider", "Node", "Sent", "Recv", "Loss%", "Min", "Avg", "Max"})
+	table.SetAutoWrapText(false)
+
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			table.Append([]string{r.Provider, r.Node, "", "", "", "", "", r.Err.Error()})
+			continue
+		}
+		if r.Stats.Recv > 0 {
+			reachable++
+		}
+		table.Append([]string{
+			r.Provider,
+			r.Node,
+			strconv.Itoa(r.Stats.Sent),
+			strconv.Itoa(r.Stats.Recv),
+			fmt.Sprintf("%.1f", r.Stats.Loss),
+			fmt.Sprintf("%.3f", r.Stats.Min),
+			fmt.Sprintf("%.3f", r.Stats.Avg),
+			fmt.Sprintf("%.3f", r.Stats.Max),
+		})
+	}
+	table.Render()
+
+	fmt.Fprintf(w, "%s: %d nodes, %d reachable, %d unreachable, %d failed\n",
+		host, len(results), reachable, len(results)-reachable-failed, failed)
+}
@@ -0,0 +1,103 @@
+package lg_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestParsePing(t *testing.T) {
+	tests := []struct {
+		out   string
+		stats lg.PingStats
+	}{
+		{
+			"5 packets transmitted, 4 received, 20% packet loss, time 4005ms\nrtt min/avg/max/mdev = 1.100/1.200/1.300/0.100 ms",
+			lg.PingStats{Sent: 5, Recv: 4, Loss: 20, Min: 1.1, Avg: 1.2, Max: 1.3},
+		},
+		{
+			"5 packets transmitted, 5 packets received, 0% packet loss\nround-trip min/avg/max/stddev = 0.5/0.6/0.7/0.1 ms",
+			lg.PingStats{Sent: 5, Recv: 5, Min: 0.5, Avg: 0.6, Max: 0.7},
+		},
+		{
+			"1 packets transmitted, 1 packets received, 0% packet loss\nrtt min/avg/median/max/mdev/stddev = 0.297/0.311/0.307/0.325/0.097/0.01 ms",
+			lg.PingStats{Sent: 1, Recv: 1, Min: 0.297, Avg: 0.311, Max: 0.325},
+		},
+		{
+			"!!!.!\nSuccess rate is 80 percent (4/5), round-trip min/avg/max = 1/2/4 ms",
+			lg.PingStats{Sent: 5, Recv: 4, Loss: 20, Min: 1, Avg: 2, Max: 4},
+		},
+	}
+
+	for _, test := range tests {
+		stats, err := lg.ParsePing(test.out)
+		if err != nil {
+			t.Error(err)
+		}
+		if stats != test.stats {
+			t.Errorf("expected %+v, actual %+v", test.stats, stats)
+		}
+	}
+
+	if _, err := lg.ParsePing("Invalid node or host"); err == nil {
+		t.Error("expected error for unknown output")
+	}
+}
+
+func TestPingNode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("router") == "slow" {
+			time.Sleep(time.Second)
+		}
+		fmt.Fprintf(w, "<pre>%s %s</pre>", r.FormValue("router"), r.FormValue("addr"))
+	}))
+	defer ts.Close()
+
+	p, err := lg.NewProvider(cli.LGProvider{
+		Name:    "internal",
+		Default: "r1",
+		Timeout: "5s",
+		Ping: cli.LGCommand{
+			URL:   ts.URL,
+			Form:  map[string]string{"addr": "${host}", "router": "${node}"},
+			Match: `<pre>(.*)</pre>`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	for _, node := range []string{"r1", "r2", "r3"} {
+		go func(node string) {
+			r, err := p.PingNode(context.Background(), node, "192.0.2.1")
+			if err != nil || r != node+" 192.0.2.1" {
+				t.Error("unexpected result", r, err)
+			}
+			done <- struct{}{}
+		}(node)
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	if p.Node != "" || p.Host != "" {
+		t.Error("expected provider's state doesn't change")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.PingNode(ctx, "slow", "192.0.2.1"); err == nil {
+		t.Error("expected timeout error")
+	}
+
+	if p.Timeout(time.Second) != 5*time.Second {
+		t.Error("expected configured timeout")
+	}
+}
@@ -0,0 +1,109 @@
+package lg
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TraceHop represents a looking glass traceroute hop, the IP
+// is empty if the hop didn't respond
+type TraceHop struct {
+	Num  int     `json:"hop"`
+	Name string  `json:"name,omitempty"`
+	IP   string  `json:"ip"`
+	RTT  float64 `json:"rtt"`
+	ASN  float64 `json:"asn"`
+}
+
+var (
+	traceHopRgx  = regexp.MustCompile(`^\s*(\d{1,3})\s+(.*)$`)
+	traceAddrRgx = regexp.MustCompile(`(\S+)\s+\(([0-9a-fA-F.:]+)\)|([0-9a-fA-F]*[.:][0-9a-fA-F.:]+)`)
+	traceRTTRgx  = regexp.MustCompile(`([\d.]+)\s*(?:ms|msec)\b`)
+	traceASNRgx  = regexp.MustCompile(`(?i)\[AS\s*(\d+)|\((\d+)\)\]`)
+)
+
+// ParseTrace parses the traceroute output of Linux, BSD, Juniper
+// and Cisco routers, the first responded address of each hop
+// represents the hop
+func ParseTrace(lines []string) []TraceHop {
+	var hops []TraceHop
+
+	for _, l := range lines {
+		m := traceHopRgx.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		h := TraceHop{}
+		h.Num, _ = strconv.Atoi(m[1])
+		if len(hops) > 0 && hops[len(hops)-1].Num == h.Num {
+			continue
+		}
+
+		// name (address) or the address only
+		rest := m[2]
+		for _, a := range traceAddrRgx.FindAllStringSubmatch(rest, -1) {
+			name, ip := a[1], a[2]
+			if ip == "" {
+				ip = a[3]
+			}
+			if net.ParseIP(ip) == nil {
+				continue
+			}
+			h.IP = ip
+			if name != ip {
+				h.Name = name
+			}
+			rest = rest[strings.Index(rest, a[0])+len(a[0]):]
+			break
+		}
+
+		if h.IP != "" {
+			if r := traceRTTRgx.FindStringSubmatch(rest); r != nil {
+				h.RTT, _ = strconv.ParseFloat(r[1], 64)
+			}
+			if a := traceASNRgx.FindStringSubmatch(rest); a != nil {
+				h.ASN, _ = strconv.ParseFloat(a[1]+a[2], 64)
+			}
+		}
+
+		hops = append(hops, h)
+	}
+
+	return hops
+}
+
+// MatchNode returns the node's name in the nodes, the name is case
+// insensitive w/o spaces and punctuation, a unique prefix matches too
+func MatchNode(nodes []string, name string) (string, bool) {
+	var (
+		key     = nodeKey(name)
+		matches []string
+	)
+	for _, n := range nodes {
+		k := nodeKey(n)
+		if k == key {
+			return n, true
+		}
+		if strings.HasPrefix(k, key) {
+			matches = append(matches, n)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], true
+	}
+	return "", false
+}
+
+func nodeKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,69 @@
+package lg_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/lg"
+)
+
+func TestParseTrace(t *testing.T) {
+	lines := []string{
+		"traceroute to 192.0.2.10 (192.0.2.10), 30 hops max, 60 byte packets",
+		" 1  gw.example.net (198.51.100.1)  0.512 ms  0.431 ms  0.399 ms",
+		" 2  * * *",
+		" 3  * 203.0.113.1 (203.0.113.1) [AS64500]  5.1 ms  5.3 ms",
+		"    203.0.113.5 (203.0.113.5) [AS64500]  5.9 ms",
+		"  4 ae-1.r01.example.net (192.0.2.1) [AS 64501] 12 msec 11 msec 11 msec",
+		" 5  192.0.2.10  20.25 ms",
+		" 6  xe-0.example.net (192.0.2.20)  30 ms [EXAMPLE (ARIN) (64502)]",
+	}
+	expected := []lg.TraceHop{
+		{Num: 1, Name: "gw.example.net", IP: "198.51.100.1", RTT: 0.512},
+		{Num: 2},
+		{Num: 3, IP: "203.0.113.1", RTT: 5.1, ASN: 64500},
+		{Num: 4, Name: "ae-1.r01.example.net", IP: "192.0.2.1", RTT: 12, ASN: 64501},
+		{Num: 5, IP: "192.0.2.10", RTT: 20.25},
+		{Num: 6, Name: "xe-0.example.net", IP: "192.0.2.20", RTT: 30, ASN: 64502},
+	}
+
+	hops := lg.ParseTrace(lines)
+	if !reflect.DeepEqual(hops, expected) {
+		t.Errorf("expected %+v, actual %+v", expected, hops)
+	}
+}
+
+func TestMatchNode(t *testing.T) {
+	nodes := []string{"Los Angeles, CA", "London, UK", "New York, NY"}
+	for name, node := range map[string]string{
+		"los_angeles": "Los Angeles, CA",
+		"london,uk":   "London, UK",
+		"NEW":         "New York, NY",
+	} {
+		if n, ok := lg.MatchNode(nodes, name); !ok || n != node {
+			t.Error("unexpected node", name, n)
+		}
+	}
+	if _, ok := lg.MatchNode(nodes, "lo"); ok {
+		t.Error("expected ambiguous node")
+	}
+}
+
+func TestTraceNode(t *testing.T) {
+	p, err := lg.NewProvider(cli.LGProvider{
+		Name:    "rs",
+		Default: "r1",
+		Trace:   cli.LGCommand{Exec: "echo trace ${name} ${host} ${ipv}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := <-p.TraceNode(context.Background(), "r2", "2001:db8::1"); l != "trace r2 2001:db8::1 ipv6" {
+		t.Error("unexpected trace result", l)
+	}
+	if p.Node != "" || p.Host != "" {
+		t.Error("unexpected provider change", p.Node, p.Host)
+	}
+}
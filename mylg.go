@@ -3,10 +3,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
 	"runtime"
 	"strings"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/mehrdadrad/mylg/cli"
 	"github.com/mehrdadrad/mylg/disc"
+	"github.com/mehrdadrad/mylg/geo"
 	"github.com/mehrdadrad/mylg/http/ping"
 	"github.com/mehrdadrad/mylg/icmp"
 	"github.com/mehrdadrad/mylg/lg"
@@ -39,14 +42,19 @@ type Provider interface {
 	Set(host, version string)
 	GetDefaultNode() string
 	GetNodes() []string
+	GetNodesContext(ctx context.Context) []string
 	ChangeNode(node string) bool
 	Ping() (string, error)
 	Trace() chan string
 	BGP() chan string
+	BGPRoutesContext(ctx context.Context, prefix string) ([]lg.BGPRoute, error)
+	PingNode(ctx context.Context, node, host string) (string, error)
+	TraceNode(ctx context.Context, node, host string) chan string
+	Timeout(d time.Duration) time.Duration
 }
 
 var (
-	pNames    = providerNames()
+	pNames    []string
 	req       = make(chan string, 1)
 	nxt       = make(chan struct{}, 1)
 	spin      = spinner.New(spinner.CharSets[26], 220*time.Millisecond)
@@ -59,15 +67,11 @@ var (
 	nmsClient nms.Client
 	nsr       *ns.Request
 	c         *cli.Readline
+	cmdErr    error
+	cmdCtx    = context.Background()
 
-	// register looking glass hosts
-	providers = map[string]Provider{
-		"telia":  new(lg.Telia),
-		"level3": new(lg.Level3),
-		"cogent": new(lg.Cogent),
-		"ntt":    new(lg.NTT),
-		"kpn":    new(lg.KPN),
-	}
+	// looking glass hosts
+	providers = map[string]Provider{}
 
 	// map cmd to function
 	cmdFunc = map[string]func(){
@@ -82,6 +86,7 @@ var (
 		"whois":     whoisLookup,  // whois / dns lookup
 		"peering":   peeringDB,    // peering DB
 		"hping":     hping,        // hping
+		"tping":     tping,        // tcp / udp ping
 		"dig":       dig,          // dig
 		"nms":       setNMS,       // network management system
 		"node":      node,         // change node
@@ -103,12 +108,23 @@ var (
 func init() {
 	// load configuration
 	cfg = cli.LoadConfig()
+	// set the whois and geo sources
+	whois.Init(cfg)
+	geo.Init(cfg)
+	// register looking glass hosts
+	registerProviders()
 	// initialize name server
 	nsr = ns.NewRequest()
 	go nsr.Init()
 	// set current provider, prompt
 	cPName = "local"
 	prompt = "local"
+	// batch mode w/o interface
+	if isBatch() {
+		c = cli.InitBatch()
+		local()
+		return
+	}
 	// with interface
 	if len(eArgs) == 1 {
 		// initialize cli
@@ -125,6 +141,14 @@ func init() {
 }
 
 func main() {
+	// run commands from a file or stdin
+	if isBatch() {
+		os.Exit(runBatch())
+	}
+	// scheduled probes w/o interface
+	if isDaemon() {
+		os.Exit(runDaemon())
+	}
 	// command line w/o interface
 	if noIf {
 		cmd := eArgs[1]
@@ -132,7 +156,10 @@ func main() {
 		if f, ok := cmdFunc[cmd]; ok {
 			f()
 		} else {
-			println("Invalid command please try mylg help")
+			fail(errors.New("Invalid command please try mylg help"))
+		}
+		if cmdErr != nil {
+			os.Exit(1)
 		}
 		return
 	}
@@ -150,7 +177,7 @@ LOOP:
 			}
 			subReq := cli.CMDRgx().FindStringSubmatch(request)
 			if len(subReq) == 0 {
-				println("syntax error")
+				fail(errors.New("syntax error"))
 				c.Next()
 				continue
 			}
@@ -160,13 +187,26 @@ LOOP:
 			if f, ok := cmdFunc[cmd]; ok {
 				f()
 			} else {
-				println("Invalid command please try help")
+				fail(errors.New("Invalid command please try help"))
 			}
 			c.Next()
 		}
 	}
 }
 
+// registerProviders registers the built-in and
+// configured looking glasses
+func registerProviders() {
+	lgs, err := lg.Load(cfg.LG)
+	if err != nil {
+		println(err.Error())
+	}
+	for name, p := range lgs {
+		providers[name] = p
+	}
+	pNames = providerNames()
+}
+
 // providerName
 func providerNames() []string {
 	pNames := []string{}
@@ -197,17 +237,23 @@ func node() {
 				c.UpdatePromptN(args, 3)
 				return
 			}
+			// the nodes might not be loaded yet
+			providers[cPName].GetNodes()
+			if providers[cPName].ChangeNode(args) {
+				c.UpdatePromptN(args, 3)
+				return
+			}
 		}
-		println("the specified node doesn't support")
+		fail(errors.New("the specified node doesn't support"))
 	case strings.HasPrefix(prompt, "ns"):
 		if !nsr.ChkNode(args) {
-			println("error: argument is not valid")
+			fail(errors.New("error: argument is not valid"))
 		} else {
 			c.UpdatePromptN(args, 3)
 		}
 	default:
 		if cPName == "local" {
-			println("local doesn't support node")
+			fail(errors.New("local doesn't support node"))
 		}
 
 	}
@@ -216,7 +262,9 @@ func node() {
 // dig gets dig info
 func dig() {
 	if ok := nsr.SetOptions(args, prompt); ok {
-		nsr.Dig()
+		if err := nsr.Dig(); err != nil {
+			cmdErr = err
+		}
 	}
 }
 
@@ -230,7 +278,7 @@ func web() {
 	cmd := exec.Command(openCmd, fmt.Sprintf("http://%s:%d", cfg.Web.Address, cfg.Web.Port))
 	err := cmd.Start()
 	if err != nil {
-		println("error opening default browser")
+		fail(errors.New("error opening default browser"))
 	}
 
 }
@@ -238,11 +286,16 @@ func web() {
 // dump provides decoding packets
 func dump() {
 	p, err := packet.NewPacket(args)
+	if err != nil {
+		fail(err)
+	}
 	if p == nil || err != nil {
 		return
 	}
+	ctx, cancel := interruptContext()
+	defer cancel()
 	println(p.Banner())
-	for l := range p.Open() {
+	for l := range p.Open(ctx) {
 		l.PrintPretty()
 	}
 }
@@ -256,7 +309,7 @@ func connect() {
 	switch {
 	case strings.HasPrefix(prompt, "lg"):
 		if pName, err = validateProvider(args); err != nil {
-			println("provider not available")
+			fail(errors.New("provider not available"))
 			c.Next()
 			return
 		}
@@ -267,11 +320,11 @@ func connect() {
 				c.UpdateCompleter("node", providers[cPName].GetNodes())
 			}()
 		} else {
-			println("it doesn't support")
+			fail(errors.New("it doesn't support"))
 		}
 	case strings.HasPrefix(prompt, "ns"):
 		if !nsr.ChkCountry(args) {
-			println("error: argument is not valid")
+			fail(errors.New("error: argument is not valid"))
 		} else {
 			c.SetPrompt("ns/" + args)
 			c.UpdateCompleter("node", nsr.NodeList())
@@ -280,7 +333,7 @@ func connect() {
 	case strings.HasPrefix(prompt, "nms"):
 		nmsClient, err = nms.NewClient(args, cfg)
 		if err != nil {
-			println("error:", err.Error())
+			fail(fmt.Errorf("error: %s", err))
 		} else if nmsClient.Host == "" {
 			return
 		} else {
@@ -296,22 +349,32 @@ func mode() {
 	} else if args == "emacs" {
 		c.SetEmacs()
 	} else {
-		println("the request mode doesn't support")
+		fail(errors.New("the request mode doesn't support"))
 	}
 }
 
 // trace tries to trace from local and lg
 func trace() {
 	switch {
+	case strings.HasPrefix(prompt, "local") && (args == "diff" || strings.HasPrefix(args, "diff ")):
+		icmp.PrintTraceDiff(strings.TrimSpace(strings.TrimPrefix(args, "diff")))
+	case strings.HasPrefix(prompt, "local") && (args == "topo" || strings.HasPrefix(args, "topo ")):
+		ctx, cancel := interruptContext()
+		icmp.PrintTopology(ctx, strings.TrimSpace(strings.TrimPrefix(args, "topo")), cfg)
+		cancel()
+	case strings.HasPrefix(prompt, "local") && strings.Contains(" "+args, " -rev"):
+		traceRev()
 	case strings.HasPrefix(prompt, "local"):
 		trace, err := icmp.NewTrace(args, cfg)
 		if err != nil {
-			println(err.Error())
+			fail(err)
 		}
 		if trace == nil {
 			break
 		}
-		trace.Print()
+		ctx, cancel := interruptContext()
+		trace.Print(ctx)
+		cancel()
 	case strings.HasPrefix(prompt, "lg"):
 		spin.Prefix = "please wait "
 		spin.Start()
@@ -329,6 +392,58 @@ func trace() {
 	}
 }
 
+// traceRev traces the target from local and back from
+// a looking glass node (trace host -rev provider/node)
+func traceRev() {
+	var (
+		_, flag = cli.Flag(args)
+		rev, _  = flag["rev"].(string)
+		name    = rev
+		node    string
+	)
+	if i := strings.Index(rev, "/"); i > 0 {
+		name, node = rev[:i], rev[i+1:]
+	}
+	p, ok := providers[strings.ToLower(name)]
+	if !ok {
+		fail(fmt.Errorf("looking glass not available, usage: trace host -rev provider/node (%s)",
+			strings.Join(pNames, ", ")))
+		return
+	}
+	if node == "" {
+		node = p.GetDefaultNode()
+	} else {
+		nodes := p.GetNodes()
+		if node, ok = lg.MatchNode(nodes, node); !ok {
+			fail(fmt.Errorf("the node doesn't support, %s nodes: %s", name, strings.Join(nodes, ", ")))
+			return
+		}
+	}
+
+	trace, err := icmp.NewTrace(args, cfg)
+	if err != nil {
+		fail(err)
+	}
+	if trace == nil {
+		return
+	}
+	ctx, cancel := interruptContext()
+	trace.PrintBothWays(ctx, func(ctx context.Context, ip string) ([]icmp.RevHop, error) {
+		var (
+			lines []string
+			hops  []icmp.RevHop
+		)
+		for l := range p.TraceNode(ctx, node, ip) {
+			lines = append(lines, l)
+		}
+		for _, h := range lg.ParseTrace(lines) {
+			hops = append(hops, icmp.RevHop(h))
+		}
+		return hops, nil
+	}, name, node)
+	cancel()
+}
+
 // hping tries to ping a web server by http
 func hping() {
 	// it should work at local mode
@@ -337,14 +452,44 @@ func hping() {
 	}
 	p, err := ping.NewPing(args, cfg)
 	if err != nil {
-		println(err.Error())
+		fail(err)
 	} else {
-		p.Run()
+		ctx, cancel := interruptContext()
+		p.Run(ctx)
+		cancel()
+	}
+}
+
+// tping tries to ping a TCP / UDP port
+func tping() {
+	// it should work at local mode
+	if cPName != "local" {
+		return
+	}
+	p, err := scan.NewPing(args, cfg)
+	if err != nil {
+		fail(err)
+	}
+	if p == nil {
+		return
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if err := p.PrintPretty(p.Run(ctx)); err != nil {
+		cmdErr = err
 	}
 }
 
 // pingQuery runs ping command (local/LG)
 func pingQuery() {
+	if host, flag := cli.Flag(args); flag["all"] != nil {
+		// ping -all host
+		if v, ok := flag["all"].(string); ok && host == "" {
+			host = v
+		}
+		pingAll(host, flag)
+		return
+	}
 	if cPName == "local" {
 		pingLocal()
 	} else {
@@ -360,26 +505,165 @@ func pingLG() {
 	m, err := providers[cPName].Ping()
 	spin.Stop()
 	if err != nil {
-		println(err.Error())
+		fail(err)
 	} else {
 		println(m)
 	}
 }
 
+// pingAll pings the host through all the looking glasses
+// nodes concurrently and prints out the results per node
+func pingAll(host string, flag map[string]interface{}) {
+	// job pings the node, the empty node loads the provider's nodes
+	type job struct {
+		name string
+		p    Provider
+		node string
+	}
+	type nodeList struct {
+		name  string
+		nodes []string
+	}
+	var (
+		workers = cli.SetFlag(flag, "w", 20).(int)
+		only    = cli.SetFlag(flag, "p", "").(string)
+		jobs    = make(chan job)
+		loaded  = make(chan nodeList)
+		queue   []job
+		results []lg.PingResult
+		nodes   = make(map[string][]string)
+		next    = make(map[string]int)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	timeout, err := time.ParseDuration(cli.SetFlag(flag, "t", "30s").(string))
+	if host == "" || err != nil || workers < 1 {
+		fail(errors.New("usage: ping host -all [-w workers] [-t timeout] [-p provider]"))
+		return
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	spin.Prefix = "please wait "
+	spin.Start()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				tCtx, tCancel := context.WithTimeout(ctx, j.p.Timeout(timeout))
+				if j.node == "" {
+					n := j.p.GetNodesContext(tCtx)
+					tCancel()
+					if len(n) == 0 {
+						n = []string{j.p.GetDefaultNode()}
+					}
+					select {
+					case loaded <- nodeList{j.name, n}:
+					case <-ctx.Done():
+					}
+					continue
+				}
+				r := lg.PingResult{Provider: j.name, Node: j.node}
+				out, err := j.p.PingNode(tCtx, j.node, host)
+				tCancel()
+				if err == nil {
+					r.Stats, err = lg.ParsePing(out)
+				}
+				r.Err = err
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// load the nodes
+	for name, p := range providers {
+		if only != "" && name != only {
+			continue
+		}
+		queue = append(queue, job{name: name, p: p})
+	}
+
+	// round robin between the providers once their nodes loaded
+	for loads := len(queue); ; {
+		if len(queue) == 0 {
+			for name, n := range nodes {
+				if next[name] < len(n) {
+					queue = append(queue, job{name, providers[name], n[next[name]]})
+					next[name]++
+				}
+			}
+		}
+		if len(queue) == 0 && loads == 0 {
+			break
+		}
+
+		var (
+			out chan job
+			j   job
+		)
+		if len(queue) > 0 {
+			out, j = jobs, queue[0]
+		}
+
+		select {
+		case out <- j:
+			queue = queue[1:]
+		case l := <-loaded:
+			nodes[l.name] = l.nodes
+			loads--
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	spin.Stop()
+
+	if len(results) == 0 {
+		fail(errors.New("there isn't any looking glass node"))
+		return
+	}
+
+	fmt.Println("")
+	lg.PrintPingAll(os.Stdout, host, results)
+}
+
 // pingLocal tries to ping from local source ip
 func pingLocal() {
+	if icmp.IsMultiPing(args) {
+		pingMulti()
+		return
+	}
 	p, err := icmp.NewPing(args, cfg)
 	if err != nil {
-		println(err.Error())
+		fail(err)
 	}
 	if p == nil {
 		return
 	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if p.IsSweep() {
+		if err := p.PrintSweep(ctx); err != nil {
+			cmdErr = err
+		}
+		return
+	}
 	if !p.IsCIDR() {
-		resp := p.Run()
-		p.PrintPretty(resp)
+		resp := p.Run(ctx)
+		if err := p.PrintPretty(resp); err != nil {
+			cmdErr = err
+		}
 	} else {
-		resp := p.MRun()
+		resp := p.MRun(ctx)
 		p.CIDRHeader()
 		for r := range resp {
 			icmp.CIDRRespPrint(r)
@@ -387,9 +671,25 @@ func pingLocal() {
 	}
 }
 
+// pingMulti pings the hosts list or the hosts file concurrently
+func pingMulti() {
+	m, err := icmp.NewMultiPing(args, cfg)
+	if err != nil {
+		fail(err)
+	}
+	if m == nil {
+		return
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	if err := m.PrintPretty(ctx); err != nil {
+		fail(err)
+	}
+}
+
 func speedTest() {
 	if err := speedtest.Run(); err != nil {
-		println("\n", err.Error())
+		fail(fmt.Errorf("\n%s", err))
 	}
 }
 
@@ -397,19 +697,29 @@ func speedTest() {
 func scanPorts() {
 	scan, err := scan.NewScan(args, cfg)
 	if err != nil {
-		println(err.Error())
+		fail(err)
 	} else {
+		ctx, cancel := interruptContext()
 		spin.Prefix = "please wait "
 		spin.Start()
-		scan.Run()
+		scan.Run(ctx)
 		spin.Stop()
+		cancel()
 	}
 }
 
 // BGP tries to get BGP lookup from a LG
 func BGP() {
+	if prefix, flag := cli.Flag(args); flag["compare"] != nil {
+		// bgp -compare prefix
+		if v, ok := flag["compare"].(string); ok && prefix == "" {
+			prefix = v
+		}
+		bgpCompare(prefix)
+		return
+	}
 	if cPName == "local" {
-		println("no provider selected")
+		fail(errors.New("no provider selected"))
 		return
 	}
 	providers[cPName].Set(args, "ipv4")
@@ -418,6 +728,67 @@ func BGP() {
 	}
 }
 
+// bgpCompare looks up a prefix through all the looking
+// glasses and prints out the best paths side by side
+func bgpCompare(prefix string) {
+	var (
+		mu     sync.Mutex
+		routes = make(map[string][]lg.BGPRoute)
+		errs   = make(map[string]error)
+		done   = make(chan struct{})
+		wg     sync.WaitGroup
+	)
+
+	if prefix == "" {
+		fail(errors.New("prefix is required, e.g. bgp 8.8.8.0/24 -compare"))
+		return
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	spin.Prefix = "please wait "
+	spin.Start()
+
+	for name, p := range providers {
+		wg.Add(1)
+		go func(name string, p Provider) {
+			defer wg.Done()
+			r, err := p.BGPRoutesContext(ctx, prefix)
+			mu.Lock()
+			routes[name], errs[name] = r, err
+			if err == nil {
+				delete(errs, name)
+			}
+			mu.Unlock()
+		}(name, p)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	spin.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for name := range providers {
+		if _, ok := routes[name]; !ok {
+			errs[name] = errors.New("interrupted")
+		}
+	}
+	fmt.Println("")
+	lg.PrintCompare(os.Stdout, routes, errs)
+	if len(errs) == len(providers) {
+		cmdErr = errors.New("no route found")
+	}
+}
+
 // discovery handles disc command
 func discovery() {
 	var (
@@ -445,7 +816,7 @@ func discovery() {
 	time.Sleep(5 * time.Second)
 
 	if err := d.GetARPTable(); err != nil {
-		println(err.Error())
+		fail(err)
 		return
 	}
 	wg.Wait()
@@ -465,8 +836,10 @@ func setNMS() {
 // setConfig
 func setConfig() {
 	if err := cli.SetConfig(args, &cfg); err != nil {
-		println(err.Error())
+		fail(err)
 	}
+	whois.Init(cfg)
+	geo.Init(cfg)
 }
 
 // show command
@@ -490,10 +863,10 @@ func show() {
 		if strings.HasPrefix(prompt, "nms") {
 			err = nmsClient.ShowInterface(subArgs)
 		} else {
-			println("it's available under nms")
+			fail(errors.New("it's available under nms"))
 		}
 		if err != nil {
-			println(err.Error())
+			fail(err)
 		}
 	}
 }
@@ -517,12 +890,16 @@ func setNS() {
 
 // peeringDB gets peer info
 func peeringDB() {
-	peeringdb.Search(args)
+	if err := peeringdb.Search(args); err != nil {
+		cmdErr = err
+	}
 }
 
 // whoisLookup gets ANS/Prefix info
 func whoisLookup() {
-	whois.Lookup(args)
+	if err := whois.Lookup(args); err != nil {
+		cmdErr = err
+	}
 }
 
 // local set prompts to local
@@ -533,6 +910,32 @@ func local() {
 	c.SetPrompt(cPName)
 }
 
+// interruptContext returns a context that it's canceled
+// once the user interrupts the running command (ctrl-c)
+func interruptContext() (context.Context, context.CancelFunc) {
+	var sigCh = make(chan os.Signal, 1)
+
+	ctx, cancel := context.WithCancel(cmdCtx)
+	signal.Notify(sigCh, os.Interrupt)
+
+	go func() {
+		defer signal.Stop(sigCh)
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// fail prints out the error and marks the current command as failed
+func fail(err error) {
+	cmdErr = err
+	println(err.Error())
+}
+
 // printVersion prints version and exits
 func printVersion() {
 	fmt.Printf("myLG v%s\n", version)
@@ -552,6 +955,8 @@ func help() {
               ***** TRY IT WITHOUT ANYTHING TO HAVE INTERFACE *****
         Usage:
               mylg [command] [args...]
+              mylg -f runbook [name=value...]
+              mylg daemon -c probes.yaml
 
               Available commands:
 
@@ -560,17 +965,34 @@ func help() {
               dig                         name server looking up
               whois                       resolve AS number/IP/CIDR to holder (provides by ripe ncc)
               hping                       Ping through HTTP/HTTPS w/ GET/HEAD methods
+              tping                       Ping a TCP port (connect or SYN) or a UDP port (-u)
               scan                        scan tcp ports (you can provide range >scan host minport maxport)
               dump                        prints out a description of the contents of packets on a network interface
               disc                        discover all the devices on a LAN
               peering                     peering information (provides by peeringdb.com)
+              bgp                         compare a prefix through all the looking glasses (-compare)
+              ping -all                   ping through all the looking glasses nodes (-w workers -t timeout -p provider)
+              trace diff                  compare the saved trace runs (trace -save) over time
+              trace topo                  trace multiple targets and export the merged topology (DOT/GraphML/JSON)
               version                     shows mylg version
 
         Example:
               mylg trace freebsd.org -r
               mylg whois 8.8.8.8
               mylg scan 127.0.0.1
+              mylg tping google.com:443
               mylg dig google.com +trace
+              mylg bgp 8.8.8.0/24 -compare
+              mylg ping 8.8.8.8 -all -w 50
+              mylg -f runbook.mylg target=8.8.8.8
+              echo "ping 8.8.8.8 -c 2" | mylg
+
+        Runbook:
+              # comment
+              name=value                  set a variable, use it as $name or ${name}
+              TIMEOUT=30s                 set the default timeout for the next commands
+              timeout 10s [command]       run a command w/ specific timeout
+              exit                        stop the runbook
 		`
 		fmt.Println(h)
 	} else {
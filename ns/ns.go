@@ -158,17 +158,17 @@ func (d *Request) Local() {
 	d.Country = ""
 }
 
-// Dig looks up name server w/ trace feature
-func (d *Request) Dig() {
+// Dig looks up name server w/ trace feature, the error
+// has been printed out already
+func (d *Request) Dig() error {
 	if !d.TraceEnabled {
-		d.RunDig()
-	} else {
-		d.RunDigTrace()
+		return d.RunDig()
 	}
+	return d.RunDigTrace()
 }
 
 // RunDig looks up name server
-func (d *Request) RunDig() {
+func (d *Request) RunDig() error {
 	var (
 		r   *dns.Msg
 		err error
@@ -206,7 +206,7 @@ func (d *Request) RunDig() {
 	}
 
 	if err != nil {
-		return
+		return err
 	}
 
 	// Answer
@@ -236,10 +236,36 @@ func (d *Request) RunDig() {
 			fmt.Println(a)
 		}
 	}
+	return nil
+}
+
+// Query looks up the target w/o printing and returns the answer
+// and the query time, it falls back to tcp if the answer truncated
+func (d *Request) Query(timeout time.Duration) (*dns.Msg, time.Duration, error) {
+	var (
+		c    = &dns.Client{Net: "udp", Timeout: timeout}
+		m    = new(dns.Msg)
+		addr = d.Host
+	)
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(d.Host, "53")
+	}
+
+	m.SetQuestion(dns.Fqdn(d.Target), d.Type)
+	m.RecursionDesired = true
+
+	r, rtt, err := c.Exchange(m, addr)
+	if err == nil && r.Truncated {
+		c.Net = "tcp"
+		r, rtt, err = c.Exchange(m, addr)
+	}
+
+	return r, rtt, err
 }
 
 // RunDigTrace handles dig trace
-func (d *Request) RunDigTrace() {
+func (d *Request) RunDigTrace() error {
 	var (
 		nss  = []string{d.Host}
 		err  error
@@ -275,6 +301,9 @@ func (d *Request) RunDigTrace() {
 				break
 			}
 		}
+		if err != nil {
+			return err
+		}
 
 		nss = nss[:0]
 
@@ -291,6 +320,7 @@ func (d *Request) RunDigTrace() {
 
 		fmt.Printf("from: %s#53 in %d ms\n", host, rtt/1e6)
 	}
+	return nil
 }
 
 // cache provides caching for name servers
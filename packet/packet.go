@@ -2,12 +2,12 @@
 package packet
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"log"
 	"net"
 	"os"
-	"os/signal"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +21,7 @@ import (
 	"github.com/olekukonko/tablewriter"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/whois"
 )
 
 // Packet holds all layers information
@@ -64,6 +65,8 @@ type Options struct {
 	x bool
 	// search at payload
 	s string
+	// show origin AS
+	as bool
 }
 
 type LookUpCache struct {
@@ -106,6 +109,12 @@ func NewPacket(args string) (*Packet, error) {
 		t:  cli.SetFlag(flag, "t", false).(bool),
 		w:  cli.SetFlag(flag, "w", "").(string),
 		s:  cli.SetFlag(flag, "s", "").(string),
+		as: cli.SetFlag(flag, "as", false).(bool),
+	}
+
+	// a request per address isn't practical w/ RIPEstat
+	if options.as && !whois.Offline() {
+		return nil, fmt.Errorf("dump -as needs an offline whois source (set whois source file)")
 	}
 
 	if options.d {
@@ -125,21 +134,17 @@ func NewPacket(args string) (*Packet, error) {
 	}, nil
 }
 
-// Open is a loop over packets
-func (p *Packet) Open() chan *Packet {
+// Open is a loop over packets until the count reached
+// or the context canceled
+func (p *Packet) Open(ctx context.Context) chan *Packet {
 	var (
 		c    = make(chan *Packet, 1)
-		s    = make(chan os.Signal, 1)
 		w    *pcapgo.Writer
 		loop = true
 	)
-	// capture interrupt w/ s channel
-	signal.Notify(s, os.Interrupt)
 
 	go func() {
 		var counter int
-		defer signal.Stop(s)
-		defer close(s)
 		defer close(c)
 
 		// write to pcap if needed
@@ -169,11 +174,15 @@ func (p *Packet) Open() chan *Packet {
 				if options.w != "" {
 					w.WritePacket(packet.Metadata().CaptureInfo, packet.Data())
 				}
-				c <- ParsePacketLayers(packet)
+				select {
+				case c <- ParsePacketLayers(packet):
+				case <-ctx.Done():
+					return
+				}
 				if counter++; counter > options.c-1 {
 					loop = false
 				}
-			case <-s:
+			case <-ctx.Done():
 				loop = false
 			}
 		}
@@ -407,6 +416,11 @@ func czIP(ip net.IP, host []string, attr ...color.Attribute) string {
 			src = ip.String()
 		}
 	}
+	if options.as {
+		if w, err := whois.IPInfo(ip.String()); err == nil {
+			src += fmt.Sprintf("[AS%.0f]", w.ASN)
+		}
+	}
 	return src
 }
 
@@ -538,6 +552,7 @@ func help() {
           -s keyword     Search keyword at payload
           -n             Don't convert host addresses to names
           -nc            Shows dumps without color
+          -as            Shows the origin AS of the addresses (offline whois source)
     Example:
           dump tcp and port 443 -c 1000
           dump !udp
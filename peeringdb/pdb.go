@@ -156,8 +156,9 @@ func printTable(net Net, ixLan [][]string) {
 	table.Render()
 }
 
-// Search find a key through the records
-func Search(key string) {
+// Search find a key through the records, it returns the
+// error that it has printed out
+func Search(key string) error {
 	var (
 		result [][]string
 		ASN    string
@@ -170,7 +171,7 @@ func Search(key string) {
 	// help
 	if _, ok := flag["help"]; ok {
 		help()
-		return
+		return nil
 	}
 
 	if _, ok := cache("validate", "ix", nil); ok {
@@ -187,7 +188,7 @@ func Search(key string) {
 	}
 	if err != nil {
 		println(err.Error())
-		return
+		return err
 	}
 
 	switch {
@@ -199,12 +200,15 @@ func Search(key string) {
 			}
 		}
 	}
-	if len(result) > 0 {
-		n := nets.(map[string]Net)
-		printTable(n[ASN], result)
-	} else {
-		println("no information @ peeringdb")
+	if len(result) == 0 {
+		err = fmt.Errorf("no information @ peeringdb")
+		println(err.Error())
+		return err
 	}
+
+	n := nets.(map[string]Net)
+	printTable(n[ASN], result)
+	return nil
 }
 
 // IsASN checks if the key is number
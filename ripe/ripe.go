@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mehrdadrad/mylg/data"
 	"github.com/olekukonko/tablewriter"
@@ -29,6 +30,10 @@ const (
 	RIPEMyIPURL = "/data/whats-my-ip/data.json"
 )
 
+// client is the RIPEstat HTTP client, the timeout keeps
+// the callers from stalling w/o Internet access
+var client = &http.Client{Timeout: 10 * time.Second}
+
 // Geo represents RIPE NCC Geo format
 type Geo struct {
 	Status string
@@ -79,7 +84,7 @@ func (p *Prefix) GetData() bool {
 		println("error: prefix invalid")
 		return false
 	}
-	resp, err := http.Get(RIPEAPI + RIPEPrefixURL + p.Resource)
+	resp, err := client.Get(RIPEAPI + RIPEPrefixURL + p.Resource)
 	if err != nil {
 		println(err.Error())
 		return false
@@ -99,7 +104,7 @@ func (p *Prefix) GetGeoData() error {
 	if len(p.Resource) < 6 {
 		return fmt.Errorf("prefix invalid")
 	}
-	resp, err := http.Get(RIPEAPI + RIPEGeoURL + p.Resource)
+	resp, err := client.Get(RIPEAPI + RIPEGeoURL + p.Resource)
 	if err != nil {
 		return err
 	}
@@ -162,7 +167,7 @@ func (a *ASN) GetOVData() bool {
 		println("error: AS number invalid")
 		return false
 	}
-	resp, err := http.Get(RIPEAPI + RIPEASNURL + a.Number)
+	resp, err := client.Get(RIPEAPI + RIPEASNURL + a.Number)
 	if err != nil {
 		println(err.Error())
 		return false
@@ -182,7 +187,7 @@ func (a *ASN) GetGeoData() bool {
 		println("error: AS number invalid")
 		return false
 	}
-	resp, err := http.Get(RIPEAPI + RIPEGeoURL + "AS" + a.Number)
+	resp, err := client.Get(RIPEAPI + RIPEGeoURL + "AS" + a.Number)
 	if err != nil {
 		println(err.Error())
 		return false
@@ -304,7 +309,7 @@ func MyIPAddr() (string, error) {
 		}
 		myIP string
 	)
-	resp, err := http.Get(RIPEAPI + RIPEMyIPURL)
+	resp, err := client.Get(RIPEAPI + RIPEMyIPURL)
 	if err != nil {
 		return myIP, err
 	}
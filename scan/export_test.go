@@ -0,0 +1,19 @@
+package scan
+
+import (
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+// SynMatch feeds the SYN matcher w/ the captured segments in order
+// and returns the first response
+func SynMatch(lport, rport int, tcps []*layers.TCP, ts []time.Time) (Response, bool) {
+	m := synMatch{lport: lport, rport: rport}
+	for i := range tcps {
+		if r, ok := m.add(segment{tcps[i], ts[i]}); ok {
+			return r, true
+		}
+	}
+	return Response{}, false
+}
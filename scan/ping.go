@@ -0,0 +1,415 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/icmp"
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+var errTimeout = errors.New("Request timeout")
+
+// Ping represents the TCP / UDP ping (tping) parameters, the TCP
+// probe measures the full connect or the SYN to SYN-ACK time
+type Ping struct {
+	Scan
+	count    int
+	udp      bool
+	syn      bool
+	fmtJSON  bool
+	timeout  time.Duration
+	interval time.Duration
+}
+
+// Response represents a probe's response, a closed port
+// responds w/ TCP RST or ICMP port unreachable
+type Response struct {
+	Sequence int
+	RTT      float64
+	Closed   bool
+	Error    error
+}
+
+// segment represents a captured TCP segment
+type segment struct {
+	tcp *layers.TCP
+	ts  time.Time
+}
+
+// NewPing creates a new TCP / UDP ping object
+func NewPing(args string, cfg cli.Config) (*Ping, error) {
+	var err error
+
+	target, flag := cli.Flag(args)
+
+	// show help
+	if _, ok := flag["help"]; ok || target == "" {
+		pingHelp(cfg)
+		return nil, nil
+	}
+
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, fmt.Errorf("the port is missing, e.g. tping %s:443", target)
+	}
+
+	p := &Ping{
+		count:   cli.SetFlag(flag, "c", cfg.Ping.Count).(int),
+		udp:     cli.SetFlag(flag, "u", false).(bool),
+		syn:     cli.SetFlag(flag, "syn", false).(bool),
+		fmtJSON: cli.SetFlag(flag, "json", false).(bool),
+	}
+	p.target = host
+	p.forceV4 = cli.SetFlag(flag, "4", false).(bool)
+	p.forceV6 = cli.SetFlag(flag, "6", false).(bool)
+
+	if p.rport, err = strconv.Atoi(port); err != nil || p.rport < 1 || p.rport > 65535 {
+		return nil, fmt.Errorf("port %s is not valid", port)
+	}
+	if p.udp && p.syn {
+		return nil, fmt.Errorf("the SYN probe is TCP only")
+	}
+
+	// set timeout
+	timeoutStr := icmp.NormalizeDuration(cli.SetFlag(flag, "t", cfg.Ping.Timeout).(string))
+	if p.timeout, err = time.ParseDuration(timeoutStr); err != nil {
+		return nil, fmt.Errorf("timeout options is not valid")
+	}
+	// set interval
+	intervalStr := icmp.NormalizeDuration(cli.SetFlag(flag, "i", cfg.Ping.Interval).(string))
+	if p.interval, err = time.ParseDuration(intervalStr); err != nil {
+		return nil, fmt.Errorf("interval options is not valid")
+	}
+
+	if err = p.setIP(); err != nil {
+		return nil, err
+	}
+	if p.raddr == nil {
+		return nil, fmt.Errorf("there is not A or AAAA record")
+	}
+
+	return p, nil
+}
+
+// Run probes the port until count reached or the context canceled
+func (p *Ping) Run(ctx context.Context) chan Response {
+	var (
+		r     = make(chan Response, 1)
+		probe = p.connect
+	)
+
+	go func() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer close(r)
+
+		switch {
+		case p.udp:
+			probe = p.udpProbe
+		case p.syn:
+			syn, err := p.synProber(ctx)
+			if err != nil {
+				r <- Response{Error: err}
+				return
+			}
+			probe = syn
+		}
+
+		for n := 0; n < p.count; n++ {
+			resp := probe(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			resp.Sequence = n
+			r <- resp
+			if n == p.count-1 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.interval):
+			}
+		}
+	}()
+
+	return r
+}
+
+// connect measures the TCP connect time
+func (p *Ping) connect(ctx context.Context) Response {
+	var dialer = net.Dialer{Timeout: p.timeout}
+
+	ts := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr())
+	rtt := elapsed(ts)
+	if err != nil {
+		return errResponse(err, rtt)
+	}
+	conn.Close()
+	return Response{RTT: rtt}
+}
+
+// udpProbe measures the time of the UDP reply or the ICMP port
+// unreachable, no response means the port is open or filtered
+func (p *Ping) udpProbe(ctx context.Context) Response {
+	var (
+		buf  = make([]byte, 1500)
+		done = make(chan struct{})
+	)
+
+	conn, err := net.Dial("udp", p.addr())
+	if err != nil {
+		return Response{Error: err}
+	}
+	defer conn.Close()
+
+	// unblock the read once the context canceled
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	ts := time.Now()
+	conn.SetReadDeadline(ts.Add(p.timeout))
+	if _, err = conn.Write([]byte("mylg")); err == nil {
+		_, err = conn.Read(buf)
+	}
+	return errResponse(err, elapsed(ts))
+}
+
+// synProber returns the SYN probe, it sends the TCP SYN through the
+// raw socket w/ a new source port and the capture timestamps both
+// the SYN and the SYN-ACK / RST
+func (p *Ping) synProber(ctx context.Context) (func(context.Context) Response, error) {
+	var (
+		conn net.PacketConn
+		segs = make(chan segment, 100)
+		err  error
+	)
+
+	if err = p.setLocalNet(); err != nil {
+		return nil, fmt.Errorf("source IP address not configured")
+	}
+	p.setProto("tcp")
+
+	if conn, err = net.ListenPacket(p.network+":tcp", p.laddr.String()); err != nil {
+		return nil, err
+	}
+
+	filter := fmt.Sprintf("tcp and ((src host %[1]s and src port %[2]d) or (dst host %[1]s and dst port %[2]d))", p.raddr, p.rport)
+	handle, err := p.openCapture(filter)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go func() {
+		capture(ctx, handle, func(tcp *layers.TCP, ts time.Time) {
+			select {
+			case segs <- segment{tcp, ts}:
+			default:
+			}
+		})
+		handle.Close()
+		conn.Close()
+	}()
+
+	return func(ctx context.Context) Response {
+		p.lport = 32768 + rand.Intn(28232)
+		err, buf := p.packetDataTCP(p.rport)
+		if err != nil {
+			return Response{Error: err}
+		}
+
+		if _, err := conn.WriteTo(buf, &net.IPAddr{IP: p.raddr}); err != nil {
+			return Response{Error: err}
+		}
+
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+
+		m := synMatch{lport: p.lport, rport: p.rport}
+		for {
+			select {
+			case <-ctx.Done():
+				return Response{Error: ctx.Err()}
+			case <-timer.C:
+				return Response{Error: errTimeout}
+			case s := <-segs:
+				if r, ok := m.add(s); ok {
+					return r
+				}
+			}
+		}
+	}, nil
+}
+
+// synMatch matches the probe's captured SYN and its SYN-ACK / RST,
+// the RTT is between the capture timestamps so the user space
+// scheduling doesn't count
+type synMatch struct {
+	lport, rport int
+	syn, reply   *segment
+}
+
+// add returns the response once both the SYN and the reply captured
+func (m *synMatch) add(s segment) (Response, bool) {
+	switch {
+	// the SYN's sequence is 1
+	case int(s.tcp.SrcPort) == m.lport && int(s.tcp.DstPort) == m.rport &&
+		s.tcp.SYN && !s.tcp.ACK && s.tcp.Seq == 1:
+		m.syn = &s
+	case int(s.tcp.DstPort) == m.lport && int(s.tcp.SrcPort) == m.rport &&
+		s.tcp.Ack == 2 && (s.tcp.RST || s.tcp.SYN && s.tcp.ACK):
+		m.reply = &s
+	default:
+		return Response{}, false
+	}
+
+	if m.syn == nil || m.reply == nil {
+		return Response{}, false
+	}
+
+	rtt := float64(m.reply.ts.Sub(m.syn.ts).Nanoseconds()) / 1e6
+	return Response{RTT: rtt, Closed: m.reply.tcp.RST}, true
+}
+
+// PrintPretty prints out the responses and the statistics, it
+// returns error if there isn't any response
+func (p *Ping) PrintPretty(resp chan Response) error {
+	var (
+		pFmt = "%s port %d %s seq=%d time=%.3f ms\n"
+		st   stats.Stats
+		err  error
+	)
+
+	if !p.fmtJSON {
+		fmt.Printf("TPING %s (%s) port %d/%s\n", p.target, p.raddr, p.rport, p.proto())
+	}
+
+	for r := range resp {
+		if r.Error != nil {
+			st.AddLoss()
+			if !p.fmtJSON {
+				fmt.Printf("%s seq=%d\n", r.Error, r.Sequence)
+			}
+			continue
+		}
+		st.Add(r.RTT)
+		if p.fmtJSON {
+			continue
+		}
+		status := "open"
+		if r.Closed {
+			status = "closed"
+		}
+		fmt.Printf(pFmt, p.raddr, p.rport, status, r.Sequence, r.RTT)
+	}
+
+	if st.Sent() == 0 {
+		return nil
+	}
+
+	sum := st.Summary()
+	if sum.Recv == 0 {
+		err = errors.New("100% loss")
+	}
+
+	if p.fmtJSON {
+		b, _ := json.Marshal(struct {
+			Host  string `json:"host"`
+			Addr  string `json:"addr"`
+			Port  int    `json:"port"`
+			Proto string `json:"proto"`
+			stats.Summary
+		}{p.target, p.raddr.String(), p.rport, p.proto(), sum})
+		fmt.Println(string(b))
+		return err
+	}
+
+	fmt.Printf("\n--- %s tping statistics ---\n", p.target)
+	fmt.Printf("%d probes transmitted,  %d responses received, %.0f%% loss\n", sum.Sent, sum.Recv, sum.Loss)
+	if sum.Recv > 0 {
+		fmt.Println(sum)
+	}
+	return err
+}
+
+func (p *Ping) proto() string {
+	switch {
+	case p.udp:
+		return "udp"
+	case p.syn:
+		return "tcp syn"
+	}
+	return "tcp connect"
+}
+
+func (p *Ping) addr() string {
+	return net.JoinHostPort(p.raddr.String(), strconv.Itoa(p.rport))
+}
+
+func elapsed(ts time.Time) float64 {
+	return float64(time.Since(ts).Nanoseconds()) / 1e6
+}
+
+// errResponse returns the response of the probe's error, the
+// connection refused means the port is closed
+func errResponse(err error, rtt float64) Response {
+	if err == nil {
+		return Response{RTT: rtt}
+	}
+	if oe, ok := err.(*net.OpError); ok {
+		if oe.Timeout() {
+			return Response{Error: errTimeout}
+		}
+		if se, ok := oe.Err.(*os.SyscallError); ok && se.Err == syscall.ECONNREFUSED {
+			return Response{RTT: rtt, Closed: true}
+		}
+	}
+	return Response{Error: err}
+}
+
+// pingHelp represents tping guide to user
+func pingHelp(cfg cli.Config) {
+	fmt.Printf(`
+    usage:
+          tping host:port [options]
+    options:
+          -c count       Send 'count' probes (default: %d)
+          -t timeout     Specify a timeout in format "ms", "s", "m" (default: %s)
+          -i interval    Wait interval between sending each probe (default: %s)
+          -u             UDP probe, the reply or the ICMP port unreachable responds
+          -syn           TCP SYN to SYN-ACK time through the raw socket (needs root)
+          -4             Force IPv4
+          -6             Force IPv6
+          -json          Prints out the statistics in json format
+    Note:
+          the TCP probe measures the full connect time by default
+          a closed port responds w/ TCP RST or ICMP port unreachable
+    Example:
+          tping google.com:443
+          tping 8.8.8.8:53 -u -c 10
+          tping [2001:4860:4860::8888]:443 -syn -i 500ms
+	`,
+		cfg.Ping.Count,
+		cfg.Ping.Timeout,
+		cfg.Ping.Interval)
+}
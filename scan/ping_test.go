@@ -0,0 +1,92 @@
+package scan_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/mehrdadrad/mylg/scan"
+)
+
+func TestPing(t *testing.T) {
+	if _, err := scan.NewPing("127.0.0.1", cfg); err == nil {
+		t.Error("NewPing expected error w/o port")
+	}
+	if _, err := scan.NewPing("127.0.0.1:80 -u -syn", cfg); err == nil {
+		t.Error("NewPing expected error w/ UDP SYN probe")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	addr := ln.Addr().String()
+	p, err := scan.NewPing(addr+" -c 2 -i 10ms -t 1s", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for r := range p.Run(context.Background()) {
+		if r.Error != nil || r.Closed || r.Sequence != n {
+			t.Error("unexpected response", r)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Error("expected 2 responses, got", n)
+	}
+
+	// closed port responds w/ RST
+	ln.Close()
+	p, _ = scan.NewPing(addr+" -c 1 -t 1s", cfg)
+	if r := <-p.Run(context.Background()); r.Error != nil || !r.Closed {
+		t.Error("expected closed port", r)
+	}
+}
+
+func TestSynMatch(t *testing.T) {
+	var (
+		t0     = time.Unix(1500000000, 0)
+		syn    = &layers.TCP{SrcPort: 40000, DstPort: 80, SYN: true, Seq: 1}
+		synAck = &layers.TCP{SrcPort: 80, DstPort: 40000, SYN: true, ACK: true, Ack: 2}
+		rst    = &layers.TCP{SrcPort: 80, DstPort: 40000, RST: true, ACK: true, Ack: 2}
+		stale  = &layers.TCP{SrcPort: 80, DstPort: 40001, SYN: true, ACK: true, Ack: 2}
+	)
+
+	for _, tc := range []struct {
+		tcps   []*layers.TCP
+		ms     []int
+		rtt    float64
+		closed bool
+		ok     bool
+	}{
+		{[]*layers.TCP{syn, synAck}, []int{1, 4}, 3, false, true},
+		{[]*layers.TCP{syn, rst}, []int{1, 3}, 2, true, true},
+		{[]*layers.TCP{stale, syn, synAck}, []int{0, 2, 7}, 5, false, true},
+		// the SYN's capture came out after the reply
+		{[]*layers.TCP{synAck, syn}, []int{6, 2}, 4, false, true},
+		{[]*layers.TCP{syn, stale}, []int{1, 2}, 0, false, false},
+		{[]*layers.TCP{synAck, rst}, []int{1, 2}, 0, false, false},
+	} {
+		var ts []time.Time
+		for _, ms := range tc.ms {
+			ts = append(ts, t0.Add(time.Duration(ms)*time.Millisecond))
+		}
+		r, ok := scan.SynMatch(40000, 80, tc.tcps, ts)
+		if ok != tc.ok || r.RTT != tc.rtt || r.Closed != tc.closed {
+			t.Errorf("%v unexpected response %#v %t", tc.ms, r, ok)
+		}
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/google/gopacket/pcap"
 
 	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/whois"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -36,6 +37,7 @@ type Scan struct {
 	forceV4  bool
 	forceV6  bool
 	connScan bool
+	showAS   bool
 	ip       gopacket.NetworkLayer
 }
 
@@ -57,6 +59,7 @@ func NewScan(args string, cfg cli.Config) (Scan, error) {
 	scan.forceV4 = cli.SetFlag(flag, "4", false).(bool)
 	scan.forceV6 = cli.SetFlag(flag, "6", false).(bool)
 	scan.connScan = cli.SetFlag(flag, "c", false).(bool)
+	scan.showAS = cli.SetFlag(flag, "as", false).(bool)
 
 	pRange := cli.SetFlag(flag, "p", cfg.Scan.Port).(string)
 
@@ -128,17 +131,18 @@ func (s *Scan) setIP() error {
 	return nil
 }
 
-// Run tries to scan wide range ports (TCP)
-func (s *Scan) Run() {
+// Run tries to scan wide range ports (TCP) until
+// the scan finished or the context canceled
+func (s *Scan) Run(ctx context.Context) {
 	var (
 		openPorts []int
 		err       error
 	)
 
 	if s.minPort != s.maxPort {
-		fmt.Printf("Scan %s (%s) TCP ports %d-%d\n", s.target, s.raddr, s.minPort, s.maxPort)
+		fmt.Printf("Scan %s (%s)%s TCP ports %d-%d\n", s.target, s.raddr, s.origin(), s.minPort, s.maxPort)
 	} else {
-		fmt.Printf("Scan %s (%s) TCP port %d\n", s.target, s.raddr, s.minPort)
+		fmt.Printf("Scan %s (%s)%s TCP port %d\n", s.target, s.raddr, s.origin(), s.minPort)
 	}
 
 	table := tablewriter.NewWriter(os.Stdout)
@@ -146,9 +150,9 @@ func (s *Scan) Run() {
 
 	tStart := time.Now()
 	if s.connScan {
-		openPorts = s.tcpConnScan()
+		openPorts = s.tcpConnScan(ctx)
 	} else {
-		openPorts, err = s.tcpSYNScan()
+		openPorts, err = s.tcpSYNScan(ctx)
 	}
 
 	if err != nil {
@@ -171,6 +175,21 @@ func (s *Scan) Run() {
 
 }
 
+// origin returns the target's prefix and origin AS through the whois source
+func (s *Scan) origin() string {
+	if !s.showAS {
+		return ""
+	}
+	w, err := whois.IPInfo(s.raddr.String())
+	if err != nil {
+		return ""
+	}
+	if w.Holder != "" {
+		return fmt.Sprintf(" [%s AS%.0f %s]", w.Prefix, w.ASN, w.Holder)
+	}
+	return fmt.Sprintf(" [%s AS%.0f]", w.Prefix, w.ASN)
+}
+
 func (s *Scan) packetDataTCP(rport int) (error, []byte) {
 	tcp := &layers.TCP{
 		SrcPort: layers.TCPPort(s.lport),
@@ -257,10 +276,11 @@ func (s *Scan) setLocalNet() error {
 	return nil
 }
 
-func (s *Scan) tcpSYNScan() ([]int, error) {
+func (s *Scan) tcpSYNScan(ctx context.Context) ([]int, error) {
 	var err error
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	if err = s.setLocalNet(); err != nil {
 		return []int{}, fmt.Errorf("source IP address not configured")
@@ -269,7 +289,7 @@ func (s *Scan) tcpSYNScan() ([]int, error) {
 		println(err.Error())
 	}
 	go func() {
-		if err := s.sendTCPSYN(); err != nil {
+		if err := s.sendTCPSYN(ctx); err != nil {
 			println(err.Error())
 		}
 		cancel()
@@ -280,49 +300,58 @@ func (s *Scan) tcpSYNScan() ([]int, error) {
 }
 
 func (s *Scan) pCapture(ctx context.Context) ([]int, error) {
-	var (
-		tcp       *layers.TCP
-		timeout   = 100 * time.Nanosecond
-		openPorts []int
-		ok        bool
-	)
-	handle, err := pcap.OpenLive(s.ifName, 6*1024, false, timeout)
+	var openPorts []int
+
+	handle, err := s.openCapture("tcp and src host " + s.raddr.String())
 	if err != nil {
 		return nil, err
 	}
 	defer handle.Close()
 
-	filter := "tcp and src host " + s.raddr.String()
+	capture(ctx, handle, func(tcp *layers.TCP, _ time.Time) {
+		if tcp.SYN && tcp.ACK {
+			openPorts = append(openPorts, int(tcp.SrcPort))
+		}
+	})
+
+	openPorts = uniqSlice(openPorts)
+	sort.Ints(openPorts)
+	return openPorts, nil
+}
+
+// openCapture opens the live capture w/ the BPF filter
+func (s *Scan) openCapture(filter string) (*pcap.Handle, error) {
+	handle, err := pcap.OpenLive(s.ifName, 6*1024, false, 100*time.Nanosecond)
+	if err != nil {
+		return nil, err
+	}
 	if err := handle.SetBPFFilter(filter); err != nil {
+		handle.Close()
 		return nil, err
 	}
+	return handle, nil
+}
 
+// capture passes the captured TCP segments w/ their capture
+// timestamps to the fn until the context canceled
+func capture(ctx context.Context, handle *pcap.Handle, fn func(*layers.TCP, time.Time)) {
 	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
-LOOP:
 	for {
 		select {
 		case <-ctx.Done():
-			break LOOP
-		case packet := <-packetSource.Packets():
-			tcpLayer := packet.Layer(layers.LayerTypeTCP)
-			if tcpLayer != nil {
-				if tcp, ok = tcpLayer.(*layers.TCP); !ok {
-					continue
-				}
-				if tcp.SYN && tcp.ACK {
-					p, _ := strconv.Atoi(fmt.Sprintf("%d", tcp.SrcPort))
-					openPorts = append(openPorts, p)
-				}
-				continue
+			return
+		case packet, ok := <-packetSource.Packets():
+			if !ok {
+				return
+			}
+			if tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+				fn(tcp, packet.Metadata().Timestamp)
 			}
 		}
 	}
-	openPorts = uniqSlice(openPorts)
-	sort.Ints(openPorts)
-	return openPorts, nil
 }
 
-func (s *Scan) sendTCPSYN() error {
+func (s *Scan) sendTCPSYN(ctx context.Context) error {
 	var (
 		buf  []byte
 		err  error
@@ -336,6 +365,7 @@ func (s *Scan) sendTCPSYN() error {
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
 	for i := s.minPort; i <= s.maxPort; i++ {
 		if err, buf = s.packetDataTCP(i); err != nil {
 			return err
@@ -343,25 +373,36 @@ func (s *Scan) sendTCPSYN() error {
 		if _, err := conn.WriteTo(buf, &net.IPAddr{IP: s.raddr}); err != nil {
 			println(err.Error())
 		}
-		time.Sleep(5 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(1 * time.Second):
 	}
-	time.Sleep(1 * time.Second)
 	return nil
 }
 
 // tcpConnScan tries to scan a single host
-func (s *Scan) tcpConnScan() []int {
-	var wg sync.WaitGroup
+func (s *Scan) tcpConnScan(ctx context.Context) []int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		dialer = net.Dialer{Timeout: 2 * time.Second}
+	)
 
 	var ports []int
-	for i := s.minPort; i <= s.maxPort; i++ {
+	for i := s.minPort; i <= s.maxPort && ctx.Err() == nil; i++ {
 		wg.Add(1)
 		go func(i int) {
 			for {
 				host := net.JoinHostPort(s.raddr.String(), fmt.Sprintf("%d", i))
-				conn, err := net.DialTimeout("tcp", host, 2*time.Second)
+				conn, err := dialer.DialContext(ctx, "tcp", host)
 				if err != nil {
-					if strings.Contains(err.Error(), "too many open files") {
+					if strings.Contains(err.Error(), "too many open files") && ctx.Err() == nil {
 						// random back-off
 						time.Sleep(time.Duration(10+rand.Int31n(30)) * time.Millisecond)
 						continue
@@ -372,7 +413,9 @@ func (s *Scan) tcpConnScan() []int {
 				conn.Close()
 				break
 			}
+			mu.Lock()
 			ports = append(ports, i)
+			mu.Unlock()
 			wg.Done()
 		}(i)
 	}
@@ -404,6 +447,7 @@ func help(cfg cli.Config) {
           -c                                TCP connect scan (default is TCP SYN scan)
           -4                                Force IPv4
           -6                                Force IPv6
+          -as                               Show the target's prefix and origin AS (whois source)
     example:
           scan 8.8.8.8 -p 53
           scan www.google.com -p 1-500
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/mehrdadrad/mylg/ripe"
+	"github.com/mehrdadrad/mylg/geo"
 )
 
 func getGeo(w http.ResponseWriter, r *http.Request) {
-	var geo = struct {
+	var resp = struct {
 		CitySrc    string
 		CityDst    string
 		CountrySrc string
@@ -25,33 +25,19 @@ func getGeo(w http.ResponseWriter, r *http.Request) {
 	ipDst := r.FormValue("ip")
 
 	// find station public ip address and geo
-	ip, err := ripe.MyIPAddr()
-	// find src, dst geo
-	if err == nil {
-		p := new(ripe.Prefix)
-		p.Set(ip)
-		p.GetGeoData()
-		for _, g := range p.GeoData.Data.Locations {
-			if g.City != "" {
-				geo.CitySrc = g.City
-				geo.CountrySrc = g.Country
-				geo.LatSrc = g.Latitude
-				geo.LonSrc = g.Longitude
-				break
-			}
-		}
-		p.Set(ipDst)
-		p.GetGeoData()
-		for _, g := range p.GeoData.Data.Locations {
-			if g.City != "" {
-				geo.CityDst = g.City
-				geo.CountryDst = g.Country
-				geo.LatDst = g.Latitude
-				geo.LonDst = g.Longitude
-				break
-			}
-		}
-		b, _ := json.Marshal(geo)
-		fmt.Fprintf(w, string(b))
+	if _, src, err := geo.MyLocation(); err == nil {
+		resp.CitySrc = src.City
+		resp.CountrySrc = src.Country
+		resp.LatSrc = src.Latitude
+		resp.LonSrc = src.Longitude
 	}
+	if dst, err := geo.IPLocation(ipDst); err == nil {
+		resp.CityDst = dst.City
+		resp.CountryDst = dst.Country
+		resp.LatDst = dst.Latitude
+		resp.LonDst = dst.Longitude
+	}
+
+	b, _ := json.Marshal(resp)
+	fmt.Fprint(w, string(b))
 }
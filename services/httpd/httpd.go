@@ -2,6 +2,7 @@
 package httpd
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/rakyll/statik/fs"
@@ -12,9 +13,11 @@ import (
 	_ "github.com/mehrdadrad/mylg/services/dashboard/statik"
 )
 
+// TTracker represents a running trace for the web dashboard
 type TTracker struct {
-	ch   chan string
-	host string
+	ch     chan string
+	host   string
+	cancel context.CancelFunc
 }
 
 // Route represents a HTTP route
@@ -54,11 +57,21 @@ func API(w http.ResponseWriter, r *http.Request, cfg cli.Config) {
 		closeTrace(w, r)
 	case "geo":
 		getGeo(w, r)
+	case "probes":
+		getProbes(w, r)
 	}
 }
 
 // Run starts web service
 func Run(cfg cli.Config) {
+	err := http.ListenAndServe(fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port), Handler(cfg))
+	if err != nil {
+		println(err.Error())
+	}
+}
+
+// Handler returns the web service routes
+func Handler(cfg cli.Config) http.Handler {
 	statikFS, _ := fs.New()
 	router := mux.NewRouter().StrictSlash(true)
 	routes := []Route{
@@ -68,6 +81,12 @@ func Run(cfg cli.Config) {
 			"/api/{name}",
 			APIWrapper(API, cfg),
 		},
+		{
+			"Metrics",
+			"GET",
+			"/metrics",
+			metrics,
+		},
 	}
 
 	for _, route := range routes {
@@ -78,8 +97,6 @@ func Run(cfg cli.Config) {
 			Handler(route.HandlerFunc)
 	}
 	router.PathPrefix("/").Handler(http.FileServer(statikFS))
-	err := http.ListenAndServe(fmt.Sprintf("%s:%d", cfg.Web.Address, cfg.Web.Port), router)
-	if err != nil {
-		println(err.Error())
-	}
+
+	return router
 }
@@ -0,0 +1,51 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mehrdadrad/mylg/services/monitor"
+)
+
+// mon is the daemon mode probes monitor
+var mon *monitor.Monitor
+
+// SetMonitor exposes the daemon mode probes statistics
+func SetMonitor(m *monitor.Monitor) {
+	mon = m
+}
+
+// getProbes returns all the probes or the requested probe statistics
+func getProbes(w http.ResponseWriter, r *http.Request) {
+	if mon == nil {
+		fmt.Fprintf(w, `{"err": "%s"}`, "daemon mode is not running")
+		return
+	}
+
+	r.ParseForm()
+	name := r.FormValue("name")
+	if name == "" {
+		json.NewEncoder(w).Encode(mon.Stats())
+		return
+	}
+
+	s, ok := mon.Probe(name)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"err": "%s"}`, "probe not found")
+		return
+	}
+	json.NewEncoder(w).Encode(s)
+}
+
+// metrics exposes the probes statistics in prometheus format
+func metrics(w http.ResponseWriter, r *http.Request) {
+	if mon == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	mon.WriteMetrics(w)
+}
@@ -25,7 +25,7 @@ func ping(w http.ResponseWriter, r *http.Request, cfg *cli.Config) {
 		return
 	}
 
-	resp := p.Run()
+	resp := p.Run(r.Context())
 	rs := <-resp
 	if rs.Error != nil {
 		errStr = rs.Error.Error()
@@ -1,6 +1,7 @@
 package httpd
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -16,26 +17,35 @@ func initTrace(w http.ResponseWriter, r *http.Request, cfg *cli.Config) {
 	args := r.FormValue("a")
 
 	id := rand.Intn(1000)
-	ttracker[id] = TTracker{ch: make(chan string, 1)}
 
 	t, err := icmp.NewTrace(args, *cfg)
 	if err != nil {
 		fmt.Fprintf(w, `{"id": %d, "err": "%s"}`, -1, err.Error())
 		return
 	}
+	if t == nil {
+		fmt.Fprintf(w, `{"id": %d, "err": "%s"}`, -1, "invalid arguments")
+		return
+	}
 
-	go func() {
-		defer func() {
-			recover()
-		}()
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := t.MRun(ctx)
+	if err != nil {
+		cancel()
+		fmt.Fprintf(w, `{"id": %d, "err": "%s"}`, -1, err.Error())
+		return
+	}
 
-		resp, _ := t.MRun()
-		defer close(resp)
+	ch := make(chan string, 1)
+	ttracker[id] = TTracker{ch: ch, cancel: cancel}
 
-		for {
+	go func() {
+		defer close(ch)
+		for r := range resp {
 			select {
-			case r, _ := <-resp:
-				ttracker[id].ch <- r.Marshal()
+			case ch <- r.Marshal():
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -84,7 +94,7 @@ func closeTrace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	close(ttracker[i].ch)
+	ttracker[i].cancel()
 	delete(ttracker, i)
 
 	fmt.Fprintf(w, `{"id": %d, "err": ""}`, i)
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// labelEscaper escapes the prometheus label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the probes' statistics in the prometheus
+// text exposition format
+func (m *Monitor) WriteMetrics(w io.Writer) {
+	var (
+		stats  = m.Stats()
+		labels = make([]string, len(stats))
+	)
+
+	for i, s := range stats {
+		labels[i] = fmt.Sprintf(`name="%s",type="%s",target="%s"`,
+			labelEscaper.Replace(s.Name), s.Type, labelEscaper.Replace(s.Target))
+	}
+
+	gauge := func(name, help string, value func(Stats) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i, s := range stats {
+			fmt.Fprintf(w, "%s{%s} %g\n", name, labels[i], value(s))
+		}
+	}
+
+	gauge("mylg_probe_up", "Whether the last probe run succeeded.", func(s Stats) float64 {
+		if s.Up {
+			return 1
+		}
+		return 0
+	})
+	gauge("mylg_probe_loss_ratio", "Lost packets ratio over the window.", func(s Stats) float64 {
+		return s.Loss / 100
+	})
+
+	fmt.Fprintf(w, "# HELP mylg_probe_rtt_milliseconds Round trip time statistics over the window.\n")
+	fmt.Fprintf(w, "# TYPE mylg_probe_rtt_milliseconds gauge\n")
+	for i, s := range stats {
+		for _, v := range []struct {
+			stat  string
+			value float64
+		}{{"last", s.Last}, {"min", s.Min}, {"avg", s.Avg}, {"max", s.Max}, {"stdev", s.StDev}} {
+			fmt.Fprintf(w, "mylg_probe_rtt_milliseconds{%s,stat=\"%s\"} %g\n", labels[i], v.stat, v.value)
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP mylg_probe_runs_total Probe runs since the daemon started.\n")
+	fmt.Fprintf(w, "# TYPE mylg_probe_runs_total counter\n")
+	for i, s := range stats {
+		fmt.Fprintf(w, "mylg_probe_runs_total{%s} %d\n", labels[i], s.Runs)
+	}
+}
@@ -0,0 +1,333 @@
+// Package monitor runs the scheduled probes at daemon mode
+// and keeps their rolling statistics
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/icmp"
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+// Config represents the daemon probes configuration
+type Config struct {
+	Web    cli.Web `yaml:"web"`
+	Window int     `yaml:"window"`
+	Probes []Probe `yaml:"probes"`
+}
+
+// Probe represents a scheduled ping, hping, trace or dig
+type Probe struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Target   string `yaml:"target"`
+	Interval string `yaml:"interval"`
+	Timeout  string `yaml:"timeout"`
+	Count    int    `yaml:"count"`
+	Args     string `yaml:"args"`
+	Server   string `yaml:"server"`
+	QType    string `yaml:"qtype"`
+}
+
+// Stats represents a probe's rolling statistics
+type Stats struct {
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Target  string       `json:"target"`
+	Up      bool         `json:"up"`
+	Runs    int          `json:"runs"`
+	Sent    int          `json:"sent"`
+	Recv    int          `json:"recv"`
+	Loss    float64      `json:"loss"`
+	Last    float64      `json:"last"`
+	Min     float64      `json:"min"`
+	Avg     float64      `json:"avg"`
+	Max     float64      `json:"max"`
+	StDev   float64      `json:"stdev"`
+	Code    int          `json:"code,omitempty"`
+	Hops    int          `json:"hops,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Updated time.Time    `json:"updated"`
+	Report  *icmp.Report `json:"report,omitempty"`
+}
+
+// Monitor represents the probes scheduler
+type Monitor struct {
+	// Logger logs the probes state changes if it's not nil
+	Logger *log.Logger
+
+	cfg    cli.Config
+	window int
+	probes []*probe
+}
+
+// probe represents a probe's state
+type probe struct {
+	Probe
+
+	interval time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	runs    int
+	results []result
+}
+
+// result represents a probe's run result, the rtts are
+// the replied packets' round trip times in milliseconds
+type result struct {
+	time   time.Time
+	sent   int
+	rtts   []float64
+	code   int
+	hops   int
+	report *icmp.Report
+	err    error
+}
+
+// defaultCount is the packets/requests per run for each type
+var defaultCount = map[string]int{
+	"ping":  5,
+	"hping": 3,
+	"trace": 3,
+	"dig":   1,
+}
+
+// ReadConfig reads the probes configuration from a YAML file
+func ReadConfig(file string) (Config, error) {
+	var c Config
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return c, fmt.Errorf("%s: %s", file, err)
+	}
+
+	return c, nil
+}
+
+// New validates the probes and creates a monitor
+func New(c Config, cfg cli.Config) (*Monitor, error) {
+	var (
+		m     = &Monitor{cfg: cfg, window: c.Window}
+		names = make(map[string]struct{})
+	)
+
+	if len(c.Probes) == 0 {
+		return nil, fmt.Errorf("there is no probe")
+	}
+	if m.window < 1 {
+		m.window = 60
+	}
+
+	for i, pc := range c.Probes {
+		p, err := newProbe(pc)
+		if err != nil {
+			return nil, fmt.Errorf("probe #%d: %s", i+1, err)
+		}
+		if _, ok := names[p.Name]; ok {
+			return nil, fmt.Errorf("probe #%d: duplicate name %s", i+1, p.Name)
+		}
+		names[p.Name] = struct{}{}
+		m.probes = append(m.probes, p)
+	}
+
+	return m, nil
+}
+
+// newProbe sets the probe's defaults and checks the options
+func newProbe(pc Probe) (*probe, error) {
+	var (
+		p   = &probe{Probe: pc}
+		err error
+	)
+
+	p.Type = strings.ToLower(p.Type)
+	if _, ok := checks[p.Type]; !ok {
+		return nil, fmt.Errorf("type %q doesn't support", pc.Type)
+	}
+	if p.Target == "" {
+		return nil, fmt.Errorf("target is empty")
+	}
+	if p.Name == "" {
+		p.Name = p.Type + ":" + p.Target
+	}
+	if p.Count < 1 {
+		p.Count = defaultCount[p.Type]
+	}
+
+	if p.Interval == "" {
+		p.Interval = "1m"
+	}
+	if p.interval, err = time.ParseDuration(p.Interval); err != nil || p.interval <= 0 {
+		return nil, fmt.Errorf("interval %q is not valid", p.Interval)
+	}
+	p.timeout = p.interval
+	if p.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(p.Timeout); err != nil || p.timeout <= 0 {
+			return nil, fmt.Errorf("timeout %q is not valid", p.Timeout)
+		}
+	}
+
+	if p.Type == "dig" {
+		if p.QType == "" {
+			p.QType = "A"
+		}
+		if _, ok := dns.StringToType[strings.ToUpper(p.QType)]; !ok {
+			return nil, fmt.Errorf("qtype %q is not valid", p.QType)
+		}
+		if p.Server == "" {
+			config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+			if err != nil || len(config.Servers) == 0 {
+				return nil, fmt.Errorf("server is empty and there is no local dns server")
+			}
+			p.Server = config.Servers[0]
+		}
+	}
+
+	return p, nil
+}
+
+// Run runs the probes at their intervals until the context canceled
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, p := range m.probes {
+		wg.Add(1)
+		go func(p *probe) {
+			defer wg.Done()
+			m.loop(ctx, p)
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+// loop runs the probe right away and then at each interval
+func (m *Monitor) loop(ctx context.Context, p *probe) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		m.run(ctx, p)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run runs the probe once and adds the result to the window
+func (m *Monitor) run(ctx context.Context, p *probe) {
+	var start = time.Now()
+
+	rctx, cancel := context.WithTimeout(ctx, p.timeout)
+	r := checks[p.Type](rctx, p.Probe, m.cfg)
+	cancel()
+
+	// the daemon is stopping, it's not a failure
+	if ctx.Err() != nil {
+		return
+	}
+
+	r.time = start
+	if len(r.rtts) > 0 {
+		r.err = nil
+	} else if r.err == nil {
+		r.err = fmt.Errorf("no reply")
+	}
+
+	p.mu.Lock()
+	up := p.runs == 0 || p.results[len(p.results)-1].err == nil
+	p.runs++
+	p.results = append(p.results, r)
+	if len(p.results) > m.window {
+		p.results = p.results[len(p.results)-m.window:]
+	}
+	p.mu.Unlock()
+
+	if m.Logger == nil {
+		return
+	}
+	if r.err != nil && up {
+		m.Logger.Printf("%s is down: %s", p.Name, r.err)
+	} else if r.err == nil && !up {
+		m.Logger.Printf("%s is up", p.Name)
+	}
+}
+
+// Stats returns all the probes' statistics
+func (m *Monitor) Stats() []Stats {
+	var s []Stats
+	for _, p := range m.probes {
+		s = append(s, p.stats())
+	}
+	return s
+}
+
+// Probe returns a probe's statistics by its name
+func (m *Monitor) Probe(name string) (Stats, bool) {
+	for _, p := range m.probes {
+		if p.Name == name {
+			return p.stats(), true
+		}
+	}
+	return Stats{}, false
+}
+
+// stats calculates the statistics over the results window
+func (p *probe) stats() Stats {
+	var (
+		s  = Stats{Name: p.Name, Type: p.Type, Target: p.Target}
+		st stats.Stats
+	)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s.Runs = p.runs
+	if len(p.results) == 0 {
+		return s
+	}
+
+	for _, r := range p.results {
+		s.Sent += r.sent
+		s.Recv += len(r.rtts)
+		for _, rtt := range r.rtts {
+			st.Add(rtt)
+		}
+	}
+
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Recv) * 100 / float64(s.Sent)
+	}
+	s.Min, s.Avg, s.Max, s.StDev = st.Min(), st.Mean(), st.Max(), st.StdDev()
+
+	last := p.results[len(p.results)-1]
+	s.Up = last.err == nil
+	s.Updated = last.time
+	s.Code = last.code
+	s.Hops = last.hops
+	s.Report = last.report
+	if len(last.rtts) > 0 {
+		s.Last = last.rtts[len(last.rtts)-1]
+	}
+	if last.err != nil {
+		s.Error = last.err.Error()
+	}
+
+	return s
+}
@@ -0,0 +1,184 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/services/httpd"
+	"github.com/mehrdadrad/mylg/services/monitor"
+)
+
+func TestReadConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "probes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	fmt.Fprint(f, `
+web:
+  port: 9090
+window: 10
+probes:
+  - name: google
+    type: ping
+    target: google.com
+    interval: 30s
+    args: -t 1s
+  - type: dig
+    target: example.com
+    server: 8.8.8.8
+    qtype: aaaa
+`)
+	f.Close()
+
+	c, err := monitor.ReadConfig(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Web.Port != 9090 || c.Window != 10 || len(c.Probes) != 2 || c.Probes[0].Args != "-t 1s" {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	cfg, _ := cli.ReadDefaultConfig()
+	if _, err := monitor.New(c, cfg); err != nil {
+		t.Error(err)
+	}
+
+	for _, p := range []monitor.Probe{
+		{Type: "snmp", Target: "192.0.2.1"},
+		{Type: "ping"},
+		{Type: "ping", Target: "192.0.2.1", Interval: "1x"},
+		{Type: "dig", Target: "example.com", Server: "192.0.2.1", QType: "XYZ"},
+	} {
+		if _, err := monitor.New(monitor.Config{Probes: []monitor.Probe{p}}, cfg); err == nil {
+			t.Errorf("expected error for %+v", p)
+		}
+	}
+
+	dup := monitor.Config{Probes: []monitor.Probe{
+		{Name: "a", Type: "ping", Target: "192.0.2.1"},
+		{Name: "a", Type: "hping", Target: "192.0.2.1"},
+	}}
+	if _, err := monitor.New(dup, cfg); err == nil {
+		t.Error("expected duplicate name error")
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprintln(w, "mylg")
+	}))
+	defer ts.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == "example.com." {
+			rr, _ := dns.NewRR("example.com. 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		} else {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+	ds := &dns.Server{PacketConn: conn, Handler: mux}
+	go ds.ActivateAndServe()
+	defer ds.Shutdown()
+
+	cfg, _ := cli.ReadDefaultConfig()
+	m, err := monitor.New(monitor.Config{Window: 3, Probes: []monitor.Probe{
+		{Name: "web", Type: "hping", Target: ts.URL, Interval: "50ms", Count: 2},
+		{Name: "web-down", Type: "hping", Target: ts.URL + "/down", Interval: "50ms"},
+		{Name: "dns", Type: "dig", Target: "example.com", Server: conn.LocalAddr().String(), Interval: "50ms"},
+		{Name: "dns-nx", Type: "dig", Target: "nx.example.com", Server: conn.LocalAddr().String(), Interval: "50ms"},
+	}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if s, _ := m.Probe("dns-nx"); s.Runs > 3 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	web, _ := m.Probe("web")
+	if !web.Up || web.Code != 200 || web.Runs < 4 || web.Sent != 6 || web.Recv != 6 || web.Loss != 0 || web.Avg <= 0 {
+		t.Errorf("unexpected web stats %+v", web)
+	}
+	down, _ := m.Probe("web-down")
+	if down.Up || down.Code != 503 || down.Loss != 100 || !strings.Contains(down.Error, "503") {
+		t.Errorf("unexpected web-down stats %+v", down)
+	}
+	dnsOK, _ := m.Probe("dns")
+	if !dnsOK.Up || dnsOK.Recv != 3 || dnsOK.Max < dnsOK.Min {
+		t.Errorf("unexpected dns stats %+v", dnsOK)
+	}
+	nx, _ := m.Probe("dns-nx")
+	if nx.Up || nx.Error != "NXDOMAIN" {
+		t.Errorf("unexpected dns-nx stats %+v", nx)
+	}
+	if _, ok := m.Probe("unknown"); ok {
+		t.Error("expected unknown probe not found")
+	}
+
+	var buf bytes.Buffer
+	m.WriteMetrics(&buf)
+	for _, s := range []string{
+		`mylg_probe_up{name="web",type="hping",target="` + ts.URL + `"} 1`,
+		`mylg_probe_loss_ratio{name="web-down",type="hping",target="` + ts.URL + `/down"} 1`,
+		`mylg_probe_rtt_milliseconds{name="dns",type="dig",target="example.com",stat="avg"}`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Error("expected metric", s, "\n", buf.String())
+		}
+	}
+
+	httpd.SetMonitor(m)
+	api := httptest.NewServer(httpd.Handler(cfg))
+	defer api.Close()
+
+	resp, err := http.Get(api.URL + "/api/probes?name=web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var s monitor.Stats
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil || s.Name != "web" || !s.Up {
+		t.Error("unexpected api response", s, err)
+	}
+}
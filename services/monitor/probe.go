@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/mehrdadrad/mylg/cli"
+	hping "github.com/mehrdadrad/mylg/http/ping"
+	"github.com/mehrdadrad/mylg/icmp"
+	"github.com/mehrdadrad/mylg/ns"
+)
+
+// checks maps the probe types to their functions
+var checks = map[string]func(context.Context, Probe, cli.Config) result{
+	"ping":  ping,
+	"hping": httpPing,
+	"trace": trace,
+	"dig":   dig,
+}
+
+// ping sends the probe's count ICMP echo requests
+func ping(ctx context.Context, p Probe, cfg cli.Config) result {
+	var r result
+
+	ip, err := icmp.NewPing(fmt.Sprintf("%s -c %d %s", p.Target, p.Count, p.Args), cfg)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if ip == nil {
+		r.err = fmt.Errorf("target is not valid")
+		return r
+	}
+
+	for resp := range ip.Run(ctx) {
+		if resp.Dup || resp.Late {
+			continue
+		}
+		r.sent++
+		if resp.Error != nil {
+			r.err = resp.Error
+			continue
+		}
+		r.rtts = append(r.rtts, resp.RTT)
+	}
+
+	return r
+}
+
+// httpPing sends the probe's count HTTP requests, the
+// responses w/ status code 400 or greater count as lost
+func httpPing(ctx context.Context, p Probe, cfg cli.Config) result {
+	var r result
+
+	hp, err := hping.NewPing(strings.TrimSpace(p.Target+" "+p.Args), cfg)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	for n := 0; n < p.Count && ctx.Err() == nil; n++ {
+		res, err := hp.Ping()
+		r.sent++
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.code = res.StatusCode
+		if res.StatusCode >= 400 {
+			r.err = fmt.Errorf("status code %d", res.StatusCode)
+			continue
+		}
+		r.rtts = append(r.rtts, res.TotalTime*1000)
+	}
+
+	return r
+}
+
+// trace runs a trace report, the round trip times
+// are the target's replies
+func trace(ctx context.Context, p Probe, cfg cli.Config) result {
+	var r result
+
+	t, err := icmp.NewTrace(fmt.Sprintf("%s -c %d %s", p.Target, p.Count, p.Args), cfg)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if t == nil {
+		r.err = fmt.Errorf("target is not valid")
+		return r
+	}
+
+	rp, err := t.Report(ctx)
+	if rp == nil || len(rp.Hops) == 0 {
+		r.err = err
+		return r
+	}
+
+	r.report = rp
+	r.hops = len(rp.Hops)
+
+	last := rp.Hops[len(rp.Hops)-1]
+	if last.IP != rp.IP {
+		r.sent = p.Count
+		r.err = fmt.Errorf("%s not reached", rp.IP)
+		return r
+	}
+	r.sent = last.Sent
+	r.rtts = last.RTTs()
+
+	return r
+}
+
+// dig queries the probe's server count times, the answers
+// w/ response code other than success count as lost
+func dig(ctx context.Context, p Probe, cfg cli.Config) result {
+	var (
+		r result
+		q = ns.NewRequest()
+	)
+
+	q.Host = p.Server
+	q.Target = p.Target
+	q.Type = dns.StringToType[strings.ToUpper(p.QType)]
+
+	for n := 0; n < p.Count && ctx.Err() == nil; n++ {
+		timeout := 5 * time.Second
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+
+		m, rtt, err := q.Query(timeout)
+		r.sent++
+		if err != nil {
+			r.err = err
+			continue
+		}
+		r.code = m.Rcode
+		if m.Rcode != dns.RcodeSuccess {
+			r.err = fmt.Errorf("%s", dns.RcodeToString[m.Rcode])
+			continue
+		}
+		r.rtts = append(r.rtts, float64(rtt)/float64(time.Millisecond))
+	}
+
+	return r
+}
@@ -0,0 +1,213 @@
+// Package stats provides the round trip time statistics, the mean,
+// standard deviation, RFC 3550 jitter, percentiles and the E-model
+// MOS / R-factor estimate
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Stats holds the round trip times (ms) and the lost packets, the
+// zero value is ready to use
+type Stats struct {
+	rtts   []float64
+	sum    float64
+	min    float64
+	max    float64
+	jitter float64
+	lost   int
+}
+
+// Summary represents the calculated statistics
+type Summary struct {
+	Sent    int     `json:"sent"`
+	Recv    int     `json:"recv"`
+	Loss    float64 `json:"loss"`
+	Min     float64 `json:"min"`
+	Mean    float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	StdDev  float64 `json:"stddev"`
+	Jitter  float64 `json:"jitter"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+	RFactor float64 `json:"rfactor"`
+	MOS     float64 `json:"mos"`
+}
+
+// Add adds a round trip time in milliseconds
+func (s *Stats) Add(rtt float64) {
+	if n := len(s.rtts); n > 0 {
+		// RFC 3550 6.4.1 interarrival jitter, the transit
+		// time difference is the difference of the RTTs
+		d := math.Abs(rtt - s.rtts[n-1])
+		s.jitter += (d - s.jitter) / 16
+	}
+	if len(s.rtts) == 0 || rtt < s.min {
+		s.min = rtt
+	}
+	if rtt > s.max {
+		s.max = rtt
+	}
+	s.sum += rtt
+	s.rtts = append(s.rtts, rtt)
+}
+
+// AddLoss counts a lost packet
+func (s *Stats) AddLoss() {
+	s.lost++
+}
+
+// Reset clears the statistics
+func (s *Stats) Reset() {
+	*s = Stats{}
+}
+
+// Sent returns the number of the received and lost packets
+func (s *Stats) Sent() int {
+	return len(s.rtts) + s.lost
+}
+
+// Recv returns the number of the received packets
+func (s *Stats) Recv() int {
+	return len(s.rtts)
+}
+
+// Last returns the last round trip time
+func (s *Stats) Last() float64 {
+	if len(s.rtts) == 0 {
+		return 0
+	}
+	return s.rtts[len(s.rtts)-1]
+}
+
+// Min returns the minimum round trip time
+func (s *Stats) Min() float64 {
+	return s.min
+}
+
+// Max returns the maximum round trip time
+func (s *Stats) Max() float64 {
+	return s.max
+}
+
+// Mean returns the arithmetic mean of the round trip times
+func (s *Stats) Mean() float64 {
+	if len(s.rtts) == 0 {
+		return 0
+	}
+	return s.sum / float64(len(s.rtts))
+}
+
+// StdDev returns the population standard deviation
+func (s *Stats) StdDev() float64 {
+	if len(s.rtts) == 0 {
+		return 0
+	}
+	var (
+		mean = s.Mean()
+		sum  float64
+	)
+	for _, rtt := range s.rtts {
+		sum += (rtt - mean) * (rtt - mean)
+	}
+	return math.Sqrt(sum / float64(len(s.rtts)))
+}
+
+// Jitter returns the RFC 3550 interarrival jitter
+func (s *Stats) Jitter() float64 {
+	return s.jitter
+}
+
+// Loss returns the packet loss percentage
+func (s *Stats) Loss() float64 {
+	if s.Sent() == 0 {
+		return 0
+	}
+	return float64(s.lost) * 100 / float64(s.Sent())
+}
+
+// Percentile returns the p (0-100) percentile of the round trip times
+func (s *Stats) Percentile(p float64) float64 {
+	return Percentile(s.sorted(), p)
+}
+
+// Summary calculates all the statistics
+func (s *Stats) Summary() Summary {
+	var (
+		sorted = s.sorted()
+		sum    = Summary{
+			Sent:   s.Sent(),
+			Recv:   s.Recv(),
+			Loss:   s.Loss(),
+			Min:    s.min,
+			Mean:   s.Mean(),
+			Max:    s.max,
+			StdDev: s.StdDev(),
+			Jitter: s.jitter,
+			P50:    Percentile(sorted, 50),
+			P95:    Percentile(sorted, 95),
+			P99:    Percentile(sorted, 99),
+		}
+	)
+	if sum.Recv > 0 {
+		sum.RFactor, sum.MOS = MOS(sum.Mean, sum.Jitter, sum.Loss)
+	}
+	return sum
+}
+
+func (s *Stats) sorted() []float64 {
+	sorted := make([]float64, len(s.rtts))
+	copy(sorted, s.rtts)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// Percentile returns the p (0-100) percentile of the sorted values
+// by linear interpolation between the closest ranks
+func Percentile(sorted []float64, p float64) float64 {
+	switch {
+	case len(sorted) == 0:
+		return 0
+	case p <= 0:
+		return sorted[0]
+	case p >= 100:
+		return sorted[len(sorted)-1]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	l := int(rank)
+	if l+1 >= len(sorted) {
+		return sorted[l]
+	}
+	return sorted[l] + (rank-float64(l))*(sorted[l+1]-sorted[l])
+}
+
+// MOS estimates the R-factor and the mean opinion score (1-4.5) by
+// the simplified ITU-T G.107 E-model, the latency is the round trip
+// time and the jitter in ms and the loss in percent
+func MOS(latency, jitter, loss float64) (float64, float64) {
+	var r float64
+
+	// one way effective latency plus the jitter buffer and codec delays
+	eff := latency/2 + jitter*2 + 10
+	if eff < 160 {
+		r = 93.2 - eff/40
+	} else {
+		r = 93.2 - (eff-120)/10
+	}
+	r -= loss * 2.5
+
+	if r < 0 {
+		return 0, 1
+	}
+	return r, 1 + 0.035*r + 0.000007*r*(r-60)*(100-r)
+}
+
+// String returns the summary in the ping statistics format
+func (s Summary) String() string {
+	return fmt.Sprintf("round-trip min/avg/max/stddev = %.3f/%.3f/%.3f/%.3f ms\n"+
+		"jitter %.3f ms, p50/p95/p99 = %.3f/%.3f/%.3f ms, MOS %.2f (R-factor %.1f)",
+		s.Min, s.Mean, s.Max, s.StdDev, s.Jitter, s.P50, s.P95, s.P99, s.MOS, s.RFactor)
+}
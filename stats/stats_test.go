@@ -0,0 +1,62 @@
+package stats_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/stats"
+)
+
+func near(x, y float64) bool {
+	return math.Abs(x-y) < 0.001
+}
+
+func TestStats(t *testing.T) {
+	var s stats.Stats
+
+	for _, rtt := range []float64{10, 20, 30, 40} {
+		s.Add(rtt)
+	}
+	s.AddLoss()
+
+	r := s.Summary()
+	if r.Sent != 5 || r.Recv != 4 || r.Loss != 20 {
+		t.Error("unexpected counters", r.Sent, r.Recv, r.Loss)
+	}
+	// true mean, the pairwise average would be 31.25
+	if r.Min != 10 || r.Max != 40 || r.Mean != 25 {
+		t.Error("unexpected min/avg/max", r.Min, r.Mean, r.Max)
+	}
+	if !near(r.StdDev, 11.180) {
+		t.Error("unexpected stddev", r.StdDev)
+	}
+	// J = J + (|D| - J)/16 for D = 10, 10, 10
+	if !near(r.Jitter, 1.7603) {
+		t.Error("unexpected jitter", r.Jitter)
+	}
+	if r.P50 != 25 || !near(r.P95, 38.5) || !near(r.P99, 39.7) {
+		t.Error("unexpected percentiles", r.P50, r.P95, r.P99)
+	}
+	if r.MOS < 1 || r.MOS > 4.5 || r.RFactor >= 93.2 {
+		t.Error("unexpected MOS", r.MOS, r.RFactor)
+	}
+
+	s.Reset()
+	if r := s.Summary(); r.Sent != 0 || r.MOS != 0 {
+		t.Error("reset expected to clear the statistics")
+	}
+}
+
+func TestMOS(t *testing.T) {
+	r, mos := stats.MOS(20, 1, 0)
+	if r < 90 || mos < 4.3 {
+		t.Error("good path expected high MOS", r, mos)
+	}
+	r, mos = stats.MOS(600, 50, 10)
+	if r > 50 || mos > 2.6 {
+		t.Error("bad path expected low MOS", r, mos)
+	}
+	if _, mos = stats.MOS(20, 1, 100); mos != 1 {
+		t.Error("full loss expected MOS 1", mos)
+	}
+}
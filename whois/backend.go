@@ -0,0 +1,143 @@
+package whois
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/mehrdadrad/mylg/cli"
+	"github.com/mehrdadrad/mylg/ripe"
+)
+
+// Info represents the origin AS and the prefix of an IP address
+type Info struct {
+	Holder    string  `json:"Holder"`
+	ASN       float64 `json:"ASN"`
+	Prefix    string  `json:"Prefix"`
+	PrefixLen int     `json:"PrefixLen"`
+}
+
+// Backend represents an IP address to ASN / prefix source
+type Backend interface {
+	Lookup(ip net.IP) (Info, error)
+}
+
+// RIPE is the RIPEstat backend, it requests each
+// IP address once and caches the responses
+type RIPE struct {
+	cache map[string]Info
+	sync.Mutex
+}
+
+var (
+	source  = "ripe"
+	backend Backend
+	loadErr error
+	bMU     sync.Mutex
+)
+
+// NewRIPE creates the RIPEstat backend
+func NewRIPE() *RIPE {
+	return &RIPE{cache: make(map[string]Info)}
+}
+
+// Lookup requests the IP address's prefix overview from RIPEstat
+func (r *RIPE) Lookup(ip net.IP) (Info, error) {
+	var info Info
+
+	r.Lock()
+	info, ok := r.cache[ip.String()]
+	r.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	p := new(ripe.Prefix)
+	p.Set(ip.String())
+	p.GetData()
+	data, ok := p.Data["data"].(map[string]interface{})
+	if !ok {
+		return Info{}, fmt.Errorf("data not available")
+	}
+	asns, _ := data["asns"].([]interface{})
+	for _, h := range asns {
+		info.Holder, _ = h.(map[string]interface{})["holder"].(string)
+		info.ASN, _ = h.(map[string]interface{})["asn"].(float64)
+	}
+	if prefix, ok := data["resource"].(string); ok {
+		if _, ipNet, err := net.ParseCIDR(prefix); err == nil {
+			info.Prefix = ipNet.String()
+			info.PrefixLen, _ = ipNet.Mask.Size()
+		}
+	}
+
+	r.Lock()
+	r.cache[ip.String()] = info
+	r.Unlock()
+
+	return info, nil
+}
+
+// Init sets the whois source, it's ripe (RIPEstat) or an ip2asn,
+// pfx2as or MRT RIB dump file which loads once it's needed
+func Init(cfg cli.Config) {
+	bMU.Lock()
+	source = cfg.Whois.Source
+	backend, loadErr = nil, nil
+	bMU.Unlock()
+}
+
+// SetBackend replaces the backend
+func SetBackend(b Backend) {
+	bMU.Lock()
+	backend, loadErr = b, nil
+	bMU.Unlock()
+}
+
+// Offline returns true if the backend doesn't need network
+func Offline() bool {
+	bMU.Lock()
+	defer bMU.Unlock()
+	if backend != nil {
+		_, ok := backend.(*Table)
+		return ok
+	}
+	return source != "" && source != "ripe"
+}
+
+// IPInfo returns the IP address's origin AS and prefix through the backend
+func IPInfo(ip string) (Info, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Info{}, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	b, err := current()
+	if err != nil {
+		return Info{}, err
+	}
+
+	return b.Lookup(addr)
+}
+
+// current returns the backend, it loads the offline table at first
+func current() (Backend, error) {
+	bMU.Lock()
+	defer bMU.Unlock()
+
+	if backend != nil || loadErr != nil {
+		return backend, loadErr
+	}
+
+	if source == "" || source == "ripe" {
+		backend = NewRIPE()
+	} else {
+		backend, loadErr = LoadTable(source)
+		if loadErr != nil {
+			// the nil *Table isn't a nil backend
+			backend = nil
+		}
+	}
+
+	return backend, loadErr
+}
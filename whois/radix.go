@@ -0,0 +1,124 @@
+package whois
+
+import "net"
+
+// radix is a path compressed binary tree of the IPv6 and
+// IPv4-mapped prefixes w/ the longest prefix match lookup
+type radix struct {
+	root *node
+	size int
+}
+
+type node struct {
+	key   [16]byte
+	bits  int
+	value int32
+	child [2]*node
+}
+
+// insert adds or replaces the prefix's value
+func (r *radix) insert(key [16]byte, bits int, value int32) {
+	key = maskKey(key, bits)
+	n := &r.root
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &node{key: key, bits: bits, value: value}
+			r.size++
+			return
+		}
+
+		c := commonBits(cur.key, key, minInt(cur.bits, bits))
+		if c == cur.bits && c == bits {
+			if cur.value < 0 {
+				r.size++
+			}
+			cur.value = value
+			return
+		}
+		if c == cur.bits {
+			n = &cur.child[keyBit(key, c)]
+			continue
+		}
+
+		// split the node at the common bits
+		s := &node{key: maskKey(key, c), bits: c, value: -1}
+		s.child[keyBit(cur.key, c)] = cur
+		if c == bits {
+			s.value = value
+		} else {
+			s.child[keyBit(key, c)] = &node{key: key, bits: bits, value: value}
+		}
+		*n = s
+		r.size++
+		return
+	}
+}
+
+// lookup returns the longest matched prefix's value and length
+func (r *radix) lookup(key [16]byte) (int32, int, bool) {
+	var (
+		value int32 = -1
+		bits  int
+	)
+
+	for n := r.root; n != nil; {
+		if commonBits(n.key, key, n.bits) < n.bits {
+			break
+		}
+		if n.value >= 0 {
+			value, bits = n.value, n.bits
+		}
+		if n.bits == 128 {
+			break
+		}
+		n = n.child[keyBit(key, n.bits)]
+	}
+
+	return value, bits, value >= 0
+}
+
+// ipKey returns the IPv6 or the IPv4-mapped IPv6 address
+func ipKey(ip net.IP) [16]byte {
+	var k [16]byte
+	copy(k[:], ip.To16())
+	return k
+}
+
+func keyBit(k [16]byte, i int) int {
+	return int(k[i>>3]>>(7-uint(i&7))) & 1
+}
+
+// commonBits returns the number of the same leading bits up to max
+func commonBits(a, b [16]byte, max int) int {
+	for i := 0; i < max; i += 8 {
+		if x := a[i>>3] ^ b[i>>3]; x != 0 {
+			for j := i; j < i+8 && j < max; j++ {
+				if keyBit(a, j) != keyBit(b, j) {
+					return j
+				}
+			}
+		}
+	}
+	return max
+}
+
+// maskKey clears the bits after the prefix length
+func maskKey(k [16]byte, bits int) [16]byte {
+	for i := bits; i < 128; i++ {
+		if i&7 == 0 {
+			k[i>>3] = 0
+			i += 7
+			continue
+		}
+		k[i>>3] &^= 1 << (7 - uint(i&7))
+	}
+	return k
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,375 @@
+package whois
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MRT types and subtypes (RFC 6396)
+const (
+	mrtTableDumpV2     = 13
+	mrtRIBIPv4Unicast  = 2
+	mrtRIBIPv6Unicast  = 4
+	bgpAttrASPath      = 2
+	bgpASSet           = 1
+	bgpASSequence      = 2
+	bgpAttrFlagExtLen  = 0x10
+	mrtCommonHeaderLen = 12
+)
+
+// Table is the offline backend, it keeps the prefixes
+// and their origin AS in a radix tree
+type Table struct {
+	tree    radix
+	origins []Info
+	asns    map[float64]int32
+}
+
+// NewTable creates an empty offline table
+func NewTable() *Table {
+	return &Table{asns: make(map[float64]int32)}
+}
+
+// LoadTable loads an ip2asn TSV, a pfx2as or an MRT
+// RIB dump (TABLE_DUMP_V2) file, gzip and bzip2 supported
+func LoadTable(file string) (*Table, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := NewTable()
+	if err := t.Read(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if t.Len() == 0 {
+		return nil, fmt.Errorf("%s: there is not any prefix", file)
+	}
+
+	return t, nil
+}
+
+// Read detects the data format and adds its prefixes to the table
+func (t *Table) Read(r io.Reader) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	magic, _ := br.Peek(mrtCommonHeaderLen)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		return t.Read(gz)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return t.Read(bzip2.NewReader(br))
+	case len(magic) == mrtCommonHeaderLen && binary.BigEndian.Uint16(magic[4:6]) == mrtTableDumpV2:
+		return t.readMRT(br)
+	}
+
+	return t.readText(br)
+}
+
+// Len returns the number of the prefixes
+func (t *Table) Len() int {
+	return t.tree.size
+}
+
+// Add adds the prefix w/ its origin AS
+func (t *Table) Add(ipNet *net.IPNet, asn float64, holder string) {
+	var bits, _ = ipNet.Mask.Size()
+
+	if ipNet.IP.To4() != nil {
+		bits += 96
+	}
+	t.tree.insert(ipKey(ipNet.IP), bits, t.origin(asn, holder))
+}
+
+// Lookup returns the IP address's longest matched prefix and origin AS
+func (t *Table) Lookup(ip net.IP) (Info, error) {
+	idx, bits, ok := t.tree.lookup(ipKey(ip))
+	if !ok {
+		return Info{}, fmt.Errorf("%s not found", ip)
+	}
+
+	info := t.origins[idx]
+	if ip.To4() != nil {
+		// an IPv6 prefix covers the IPv4-mapped addresses
+		if bits < 96 {
+			return Info{}, fmt.Errorf("%s not found", ip)
+		}
+		bits -= 96
+		ip = ip.To4()
+	}
+	ipNet := &net.IPNet{IP: ip.Mask(net.CIDRMask(bits, len(ip)*8)), Mask: net.CIDRMask(bits, len(ip)*8)}
+	info.Prefix = ipNet.String()
+	info.PrefixLen = bits
+
+	return info, nil
+}
+
+// origin returns the origin AS index, the holders are
+// from the first record of the AS
+func (t *Table) origin(asn float64, holder string) int32 {
+	if idx, ok := t.asns[asn]; ok {
+		if t.origins[idx].Holder == "" {
+			t.origins[idx].Holder = holder
+		}
+		return idx
+	}
+	t.origins = append(t.origins, Info{ASN: asn, Holder: holder})
+	t.asns[asn] = int32(len(t.origins) - 1)
+	return t.asns[asn]
+}
+
+// readText reads the ip2asn TSV (range_start range_end AS_number
+// country_code AS_description) or the CAIDA pfx2as (prefix length
+// AS_number) format, the first AS of multi origin and AS set taken
+func (t *Table) readText(r io.Reader) error {
+	var (
+		s    = bufio.NewScanner(r)
+		line int
+	)
+
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line++
+		l := strings.TrimSpace(s.Text())
+		if l == "" || l[0] == '#' {
+			continue
+		}
+
+		f := strings.Split(l, "\t")
+		if len(f) < 3 {
+			f = strings.Fields(l)
+		}
+		if len(f) < 3 {
+			return fmt.Errorf("line %d: invalid record", line)
+		}
+
+		asn, err := parseASN(f[2])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		// not routed
+		if asn == 0 {
+			continue
+		}
+
+		if end := net.ParseIP(f[1]); end != nil {
+			start := net.ParseIP(f[0])
+			if start == nil {
+				return fmt.Errorf("line %d: invalid IP address %s", line, f[0])
+			}
+			var holder string
+			if len(f) > 4 {
+				holder = f[4]
+			}
+			for _, ipNet := range rangeToCIDRs(start, end) {
+				t.Add(ipNet, asn, holder)
+			}
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(f[0] + "/" + f[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		t.Add(ipNet, asn, "")
+	}
+
+	return s.Err()
+}
+
+// readMRT reads the RIB entries of a TABLE_DUMP_V2 dump, the
+// origin AS is the last AS of the first entry's AS path
+func (t *Table) readMRT(r io.Reader) error {
+	var hdr [mrtCommonHeaderLen]byte
+
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		body := make([]byte, binary.BigEndian.Uint32(hdr[8:12]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+
+		if binary.BigEndian.Uint16(hdr[4:6]) != mrtTableDumpV2 {
+			continue
+		}
+		switch binary.BigEndian.Uint16(hdr[6:8]) {
+		case mrtRIBIPv4Unicast:
+			t.addRIB(body, net.IPv4len)
+		case mrtRIBIPv6Unicast:
+			t.addRIB(body, net.IPv6len)
+		}
+	}
+}
+
+// addRIB adds the RIB_IPV4_UNICAST or RIB_IPV6_UNICAST record's prefix
+func (t *Table) addRIB(b []byte, ipLen int) {
+	// sequence number
+	if len(b) < 5 {
+		return
+	}
+	bits := int(b[4])
+	pLen := (bits + 7) / 8
+	if bits > ipLen*8 || len(b) < 5+pLen+2 {
+		return
+	}
+
+	ip := make(net.IP, ipLen)
+	copy(ip, b[5:5+pLen])
+	ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, ipLen*8)}
+
+	b = b[5+pLen:]
+	count := int(binary.BigEndian.Uint16(b[0:2]))
+	b = b[2:]
+	for n := 0; n < count && len(b) >= 8; n++ {
+		aLen := int(binary.BigEndian.Uint16(b[6:8]))
+		if len(b) < 8+aLen {
+			return
+		}
+		if asn := originAS(b[8 : 8+aLen]); asn != 0 {
+			t.Add(ipNet, asn, "")
+			return
+		}
+		b = b[8+aLen:]
+	}
+}
+
+// originAS returns the origin AS from the BGP path attributes
+// w/ the four-octet AS numbers
+func originAS(b []byte) float64 {
+	var asn uint32
+
+	for len(b) >= 3 {
+		flags, typ := b[0], b[1]
+		hl, l := 3, int(b[2])
+		if flags&bgpAttrFlagExtLen != 0 {
+			if len(b) < 4 {
+				return 0
+			}
+			hl, l = 4, int(binary.BigEndian.Uint16(b[2:4]))
+		}
+		if len(b) < hl+l {
+			return 0
+		}
+		if typ == bgpAttrASPath {
+			for seg := b[hl : hl+l]; len(seg) >= 2; {
+				n := int(seg[1])
+				if len(seg) < 2+n*4 || n == 0 {
+					break
+				}
+				switch seg[0] {
+				case bgpASSequence:
+					asn = binary.BigEndian.Uint32(seg[2+(n-1)*4:])
+				case bgpASSet:
+					asn = binary.BigEndian.Uint32(seg[2:])
+				}
+				seg = seg[2+n*4:]
+			}
+			return float64(asn)
+		}
+		b = b[hl+l:]
+	}
+
+	return 0
+}
+
+// parseASN parses the AS number, the first one of the multi
+// origin (_) or the AS set (,) and w/ or w/o AS prefix
+func parseASN(s string) (float64, error) {
+	s = strings.TrimPrefix(strings.ToUpper(s), "AS")
+	if i := strings.IndexAny(s, "_,"); i > 0 {
+		s = s[:i]
+	}
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid AS number %s", s)
+	}
+	return float64(asn), nil
+}
+
+// rangeToCIDRs returns the smallest list of the prefixes
+// that cover the IP addresses range
+func rangeToCIDRs(start, end net.IP) []*net.IPNet {
+	var (
+		nets  []*net.IPNet
+		s, e  = ipKey(start), ipKey(end)
+		v4    = start.To4() != nil && end.To4() != nil
+		first = 0
+	)
+
+	if v4 {
+		first = 96
+	}
+
+	for bytes.Compare(s[:], e[:]) <= 0 {
+		// the largest block that starts at s and ends before e
+		host := minInt(trailingZeros(s), 128-first)
+		for host > 0 {
+			if last := setHostBits(s, host); bytes.Compare(last[:], e[:]) <= 0 {
+				break
+			}
+			host--
+		}
+
+		ip := net.IP(append([]byte(nil), s[:]...))
+		if v4 {
+			ip = ip.To4()
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128-first-host, 128-first)})
+
+		s = nextKey(setHostBits(s, host))
+		if s == ([16]byte{}) || (v4 && s[11] != 0xff) {
+			// overflowed the address space
+			break
+		}
+	}
+
+	return nets
+}
+
+// trailingZeros returns the number of the trailing zero bits
+func trailingZeros(k [16]byte) int {
+	for i := 127; i >= 0; i-- {
+		if keyBit(k, i) == 1 {
+			return 127 - i
+		}
+	}
+	return 128
+}
+
+// setHostBits sets the last host bits of the key
+func setHostBits(k [16]byte, host int) [16]byte {
+	for i := 127; i > 127-host; i-- {
+		k[i>>3] |= 1 << (7 - uint(i&7))
+	}
+	return k
+}
+
+// nextKey returns the next IP address
+func nextKey(k [16]byte) [16]byte {
+	for i := 15; i >= 0; i-- {
+		k[i]++
+		if k[i] != 0 {
+			break
+		}
+	}
+	return k
+}
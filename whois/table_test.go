@@ -0,0 +1,118 @@
+package whois_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/mehrdadrad/mylg/whois"
+)
+
+func lookup(t *testing.T, tb *whois.Table, ip string) whois.Info {
+	w, err := tb.Lookup(net.ParseIP(ip))
+	if err != nil {
+		t.Error("unexpected error", ip, err)
+	}
+	return w
+}
+
+func TestTableIP2ASN(t *testing.T) {
+	data := "1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+		"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n" +
+		"1.0.4.0\t1.0.6.255\t38803\tAU\tWPL-AS-AP Wirefreebroadband Pty Ltd\n" +
+		"2001:db8::\t2001:db8:ffff:ffff:ffff:ffff:ffff:ffff\t64500\tZZ\tEXAMPLE\n"
+
+	tb := whois.NewTable()
+	if err := tb.Read(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	// 1.0.4.0-1.0.6.255 => 1.0.4.0/23, 1.0.6.0/24
+	if tb.Len() != 4 {
+		t.Error("expected 4 prefixes but got", tb.Len())
+	}
+
+	if w := lookup(t, tb, "1.0.0.1"); w.ASN != 13335 || w.Prefix != "1.0.0.0/24" || w.Holder != "CLOUDFLARENET" {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "1.0.5.10"); w.ASN != 38803 || w.Prefix != "1.0.4.0/23" || w.PrefixLen != 23 {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "1.0.6.10"); w.Prefix != "1.0.6.0/24" {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "2001:db8::1"); w.ASN != 64500 || w.Prefix != "2001:db8::/32" {
+		t.Error("unexpected info", w)
+	}
+	if _, err := tb.Lookup(net.ParseIP("1.0.2.1")); err == nil {
+		t.Error("expected not found error")
+	}
+}
+
+func TestTablePfx2AS(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		gz  = gzip.NewWriter(&buf)
+	)
+	gz.Write([]byte("8.0.0.0\t9\t3356\n8.8.8.0\t24\t15169\n8.8.4.0\t24\t15169_36040\n"))
+	gz.Close()
+
+	tb := whois.NewTable()
+	if err := tb.Read(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if w := lookup(t, tb, "8.8.8.8"); w.ASN != 15169 || w.Prefix != "8.8.8.0/24" {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "8.8.4.4"); w.ASN != 15169 {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "8.8.9.1"); w.ASN != 3356 || w.Prefix != "8.0.0.0/9" {
+		t.Error("unexpected info", w)
+	}
+
+	if err := whois.NewTable().Read(strings.NewReader("8.0.0.0\t9\tASX\n")); err == nil {
+		t.Error("expected invalid AS number error")
+	}
+}
+
+func TestTableMRT(t *testing.T) {
+	var buf bytes.Buffer
+
+	record := func(subtype uint16, body []byte) {
+		hdr := make([]byte, 12)
+		binary.BigEndian.PutUint16(hdr[4:6], 13)
+		binary.BigEndian.PutUint16(hdr[6:8], subtype)
+		binary.BigEndian.PutUint32(hdr[8:12], uint32(len(body)))
+		buf.Write(append(hdr, body...))
+	}
+	rib := func(prefix []byte, bits byte, path ...uint32) []byte {
+		seg := []byte{2, byte(len(path))}
+		for _, asn := range path {
+			seg = append(seg, byte(asn>>24), byte(asn>>16), byte(asn>>8), byte(asn))
+		}
+		// ORIGIN and AS_PATH attributes
+		attrs := append([]byte{0x40, 1, 1, 0, 0x40, 2, byte(len(seg))}, seg...)
+		b := append([]byte{0, 0, 0, 1, bits}, prefix...)
+		b = append(b, 0, 1, 0, 0, 0, 0, 0, 0, 0, byte(len(attrs)))
+		return append(b, attrs...)
+	}
+
+	// peer index table
+	record(1, []byte{192, 0, 2, 1, 0, 0, 0, 0})
+	record(2, rib([]byte{203, 0, 113}, 24, 64500, 3356, 4200000001))
+	record(4, rib([]byte{0x20, 0x01, 0x0d, 0xb8}, 32, 64500, 64511))
+
+	tb := whois.NewTable()
+	if err := tb.Read(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if w := lookup(t, tb, "203.0.113.10"); w.ASN != 4200000001 || w.Prefix != "203.0.113.0/24" {
+		t.Error("unexpected info", w)
+	}
+	if w := lookup(t, tb, "2001:db8::10"); w.ASN != 64511 {
+		t.Error("unexpected info", w)
+	}
+}
@@ -3,6 +3,13 @@
 package whois
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+
 	"github.com/mehrdadrad/mylg/ripe"
 )
 
@@ -18,19 +25,50 @@ var (
 )
 
 // Lookup tries to get whois information
-// ASN and prefix/ip information
-func Lookup(args string) {
-	if ripe.IsASN(args) {
-		w["asn"].Set(args)
-		w["asn"].GetData()
-		w["asn"].PrettyPrint()
-	} else if ripe.IsIP(args) || ripe.IsPrefix(args) {
-		w["prefix"].Set(args)
-		w["prefix"].GetData()
-		w["prefix"].PrettyPrint()
-	} else {
+// ASN and prefix/ip information, the error
+// has been printed out already
+func Lookup(args string) error {
+	var key string
+
+	switch {
+	case ripe.IsASN(args):
+		key = "asn"
+	case (ripe.IsIP(args) || ripe.IsPrefix(args)) && Offline():
+		return printOffline(args)
+	case ripe.IsIP(args) || ripe.IsPrefix(args):
+		key = "prefix"
+	default:
 		help()
+		return nil
 	}
+
+	w[key].Set(args)
+	if !w[key].GetData() {
+		return errors.New("whois lookup failed")
+	}
+	w[key].PrettyPrint()
+	return nil
+}
+
+// printOffline prints the IP address / prefix's origin AS from the
+// offline table, the prefix is looked up by its network address
+func printOffline(args string) error {
+	ip := net.ParseIP(args)
+	if _, ipNet, err := net.ParseCIDR(args); err == nil {
+		ip = ipNet.IP
+	}
+
+	info, err := IPInfo(ip.String())
+	if err != nil {
+		println(err.Error())
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Prefix", "ASN", "Holder"})
+	table.Append([]string{info.Prefix, fmt.Sprintf("%.0f", info.ASN), info.Holder})
+	table.Render()
+	return nil
 }
 
 // help represents whois help